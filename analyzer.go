@@ -211,6 +211,10 @@ func (analyzer *ScaAnalyzer) Run() {
 		logger.Fatal(err.Error())
 	}
 	if result != nil {
+		AttachDependencyPaths(result)
+		ApplyVex(result, LoadVexStatements())
+		result.LicenseViolations = NewLicensePolicy().Evaluate(*result)
+		result.Remediations = NewRemediationPlans(*result)
 		analyzer.handler.HandleSCA(analyzer.sourceManager, *result)
 	} else {
 		logger.Error("SCA result nil")
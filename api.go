@@ -1,7 +1,10 @@
 package analyzer
 
 import (
+	"errors"
+
 	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/califio/code-secure-analyzer/transport"
 	"github.com/go-resty/resty/v2"
 	"strings"
 )
@@ -27,6 +30,9 @@ type UploadDependencyRequest struct {
 	Packages            []Package           `json:"packages,omitempty"`
 	PackageDependencies []PackageDependency `json:"packageDependencies,omitempty"`
 	Vulnerabilities     []Vulnerability     `json:"vulnerabilities,omitempty"`
+	// SuppressedVulnerabilities are declared not affecting or fixed by VEX, they are reported but do not block
+	SuppressedVulnerabilities []Vulnerability   `json:"suppressedVulnerabilities,omitempty"`
+	Remediations              []RemediationPlan `json:"remediations,omitempty"`
 }
 
 type UploadDependencyResponse struct {
@@ -63,64 +69,153 @@ type CiScanInfo struct {
 }
 
 type Client struct {
-	baseURL    string
-	apiKey     string
-	httpClient *resty.Client
-	UserAgent  string
+	baseURL      string
+	credentials  CredentialProvider
+	httpClient   *resty.Client
+	retry        RetryPolicy
+	chunkSize    int
+	capabilities *ServerCapabilities
+	uploads      map[string]*uploadSession
+	UserAgent    string
 }
 
+// NewClient creates a client of the Code Secure API. The request timeout is read from CODE_SECURE_TIMEOUT, the
+// retry policy from CODE_SECURE_MAX_RETRIES, CODE_SECURE_RETRY_BACKOFF and CODE_SECURE_RETRY_MAX_BACKOFF and the
+// tls and proxy settings as described by transport.FromEnv. Invalid tls and proxy settings are logged and the client
+// keeps the default transport, NewClientWithCredentials reports them
 func NewClient(baseUrl string, apiKey string) *Client {
+	client, err := newClient(baseUrl, StaticToken(apiKey))
+	if err != nil {
+		logger.Error(err.Error())
+	}
+	return client
+}
+
+// NewClientWithCredentials creates a client which authenticates the requests with the credentials of provider. It
+// fails when the tls and proxy settings of the environment are invalid
+func NewClientWithCredentials(baseUrl string, provider CredentialProvider) (*Client, error) {
+	client, err := newClient(baseUrl, provider)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// newClient returns the client with the default transport and the error of the transport settings
+func newClient(baseUrl string, provider CredentialProvider) (*Client, error) {
 	client := &Client{
-		apiKey:     apiKey,
-		httpClient: resty.New(),
+		httpClient: resty.New().SetTimeout(envDuration("CODE_SECURE_TIMEOUT", defaultClientTimeout)),
 		baseURL:    strings.TrimSuffix(baseUrl, "/"),
+		retry:      retryPolicyFromEnv(),
+		chunkSize:  uploadChunkSize(),
+		uploads:    make(map[string]*uploadSession),
 	}
-	return client
+	transportErr := client.SetTransportConfig(transport.FromEnv())
+	client.SetCredentialProvider(provider)
+	client.httpClient.OnBeforeRequest(func(_ *resty.Client, request *resty.Request) error {
+		header, value, err := client.credentials.Credential()
+		if err != nil {
+			return err
+		}
+		request.SetHeader(header, value)
+		return nil
+	})
+	return client, transportErr
 }
 
-func (client *Client) TestConnection() bool {
-	res, err := client.Request().Get(client.baseURL + "/api/ci/ping")
+// SetCredentialProvider replaces the credentials of the requests
+func (client *Client) SetCredentialProvider(provider CredentialProvider) {
+	if user, ok := provider.(httpClientUser); ok {
+		user.useHTTPClient(client.httpClient.GetClient())
+	}
+	client.credentials = provider
+}
+
+// SetTransportConfig applies the CA bundle, client certificate, proxy and minimum tls version of config
+func (client *Client) SetTransportConfig(config transport.Config) error {
+	httpTransport, err := config.NewTransport()
 	if err != nil {
-		logger.Error(err.Error())
-		return false
+		return err
 	}
-	if res.StatusCode() == 403 || res.StatusCode() == 401 {
+	client.httpClient.SetTransport(httpTransport)
+	return nil
+}
+
+func (client *Client) TestConnection() bool {
+	res, err := client.call("ping", callIdempotent, func() (*resty.Response, error) {
+		return client.Request().Get(client.baseURL + "/api/ci/ping")
+	})
+	if res != nil && (res.StatusCode() == 403 || res.StatusCode() == 401) {
 		logger.Error("Token is invalid")
 		return false
 	}
+	if err != nil {
+		logger.Error(err.Error())
+		return false
+	}
 	return res.StatusCode() == 200
 }
 
+// InitScan creates the scan, it is only retried when the server did not receive the request
 func (client *Client) InitScan(request CiScanRequest) (*CiScanInfo, error) {
 	var scanInfo CiScanInfo
-	_, err := client.Request().
-		SetBody(request).
-		SetResult(&scanInfo).
-		Post(client.baseURL + "/api/ci/scan")
+	_, err := client.call("init scan", callCreate, func() (*resty.Response, error) {
+		return client.Request().
+			SetBody(request).
+			SetResult(&scanInfo).
+			Post(client.baseURL + "/api/ci/scan")
+	})
 	if err != nil {
 		return nil, err
 	}
+	if scanInfo.ScanId == "" {
+		return nil, errors.New("init scan: the server returned no scan id")
+	}
 	return &scanInfo, nil
 }
 
+// UploadFinding replaces the findings of the scan, so the upload is safe to retry. Findings above the chunk size
+// (CODE_SECURE_UPLOAD_CHUNK_SIZE) are sent in chunks when the server supports it
 func (client *Client) UploadFinding(request UploadFindingRequest) (*UploadFindingResponse, error) {
+	if len(request.Findings) > client.chunkSize && client.Capabilities().ChunkedUpload {
+		chunkSize := client.chunkSize
+		if maxChunkSize := client.Capabilities().MaxChunkSize; maxChunkSize > 0 && maxChunkSize < chunkSize {
+			chunkSize = maxChunkSize
+		}
+		response, err := client.uploadFindingChunks(request, chunkSize)
+		if !errors.Is(err, errChunkedUploadUnsupported) {
+			return response, err
+		}
+		logger.Warn("the server does not support chunked upload, upload the findings in a single request")
+	}
 	var response UploadFindingResponse
-	_, err := client.Request().
-		SetBody(request).
-		SetResult(&response).
-		Post(client.baseURL + "/api/ci/finding")
+	_, err := client.call("upload finding", callIdempotent, func() (*resty.Response, error) {
+		httpRequest, err := client.jsonRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		return httpRequest.
+			SetResult(&response).
+			Post(client.baseURL + "/api/ci/finding")
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &response, nil
 }
 
+// UploadDependency replaces the dependencies of the scan, so the upload is safe to retry
 func (client *Client) UploadDependency(request UploadDependencyRequest) (*UploadDependencyResponse, error) {
 	var response UploadDependencyResponse
-	_, err := client.Request().
-		SetBody(request).
-		SetResult(&response).
-		Post(client.baseURL + "/api/ci/dependency")
+	_, err := client.call("upload dependency", callIdempotent, func() (*resty.Response, error) {
+		httpRequest, err := client.jsonRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		return httpRequest.
+			SetResult(&response).
+			Post(client.baseURL + "/api/ci/dependency")
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -128,12 +223,14 @@ func (client *Client) UploadDependency(request UploadDependencyRequest) (*Upload
 }
 
 func (client *Client) UpdateScan(scanId string, request UpdateCIScanRequest) error {
-	_, err := client.Request().
-		SetBody(request).
-		Put(client.baseURL + "/api/ci/scan/" + scanId)
+	_, err := client.call("update scan", callIdempotent, func() (*resty.Response, error) {
+		return client.Request().
+			SetBody(request).
+			Put(client.baseURL + "/api/ci/scan/" + scanId)
+	})
 	return err
 }
 
 func (client *Client) Request() *resty.Request {
-	return client.httpClient.R().SetHeader("CI-TOKEN", client.apiKey)
+	return client.httpClient.R()
 }
@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// requestIDHeaders are the response headers which may carry the id of the request in the server logs
+var requestIDHeaders = []string{"X-Request-Id", "X-Correlation-Id", "Request-Id", "X-Trace-Id"}
+
+// APIError is a non 2xx response of the Code Secure API
+type APIError struct {
+	Operation  string
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	// Hint tells how to fix the credentials rejected by a 401 or 403
+	Hint string
+}
+
+func (err *APIError) Error() string {
+	message := fmt.Sprintf("%s: %d %s", err.Operation, err.StatusCode, http.StatusText(err.StatusCode))
+	if err.Code != "" {
+		message += " (" + err.Code + ")"
+	}
+	if err.Message != "" {
+		message += ": " + err.Message
+	}
+	if err.RequestID != "" {
+		message += " [request id: " + err.RequestID + "]"
+	}
+	if err.Hint != "" {
+		message += ", " + err.Hint
+	}
+	return message
+}
+
+// IsAPIError reports whether err is an APIError with one of the status codes, any status when none is given
+func IsAPIError(err error, statusCodes ...int) bool {
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		return false
+	}
+	if len(statusCodes) == 0 {
+		return true
+	}
+	for _, statusCode := range statusCodes {
+		if apiError.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// apiErrorPayload covers the error bodies of the server: {"code", "message"} and the problem details of RFC 7807
+// with the validation errors of ASP.NET
+type apiErrorPayload struct {
+	Code    string              `json:"code"`
+	Error   string              `json:"error"`
+	Message string              `json:"message"`
+	Title   string              `json:"title"`
+	Detail  string              `json:"detail"`
+	TraceID string              `json:"traceId"`
+	Errors  map[string][]string `json:"errors"`
+}
+
+func responseError(operation string, res *resty.Response) error {
+	apiError := &APIError{Operation: operation, StatusCode: res.StatusCode()}
+	for _, header := range requestIDHeaders {
+		if value := res.Header().Get(header); value != "" {
+			apiError.RequestID = value
+			break
+		}
+	}
+	body := strings.TrimSpace(string(res.Body()))
+	var payload apiErrorPayload
+	if err := json.Unmarshal(res.Body(), &payload); err != nil {
+		// a proxy answers with a html or text page, keep the beginning of it
+		if !strings.HasPrefix(body, "<") {
+			apiError.Message = truncate(body, 200)
+		}
+		return apiError
+	}
+	apiError.Code = payload.Code
+	if apiError.Code == "" && payload.Message != "" {
+		apiError.Code = payload.Error
+	}
+	apiError.Message = firstNonEmpty(payload.Message, payload.Detail, payload.Title, payload.Error)
+	if len(payload.Errors) > 0 {
+		fields := make([]string, 0, len(payload.Errors))
+		for field := range payload.Errors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		var details []string
+		for _, field := range fields {
+			details = append(details, field+": "+strings.Join(payload.Errors[field], " "))
+		}
+		apiError.Message = strings.TrimPrefix(apiError.Message+" ("+strings.Join(details, "; ")+")", " ")
+	}
+	if apiError.RequestID == "" {
+		apiError.RequestID = payload.TraceID
+	}
+	return apiError
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,236 @@
+package analyzer
+
+import (
+	"iter"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/go-resty/resty/v2"
+)
+
+const defaultPageSize = 100
+
+type FindingStatus string
+
+const (
+	FindingStatusOpen         FindingStatus = "Open"
+	FindingStatusConfirmed    FindingStatus = "Confirmed"
+	FindingStatusAcceptedRisk FindingStatus = "AcceptedRisk"
+	FindingStatusIncorrect    FindingStatus = "Incorrect"
+	FindingStatusFixed        FindingStatus = "Fixed"
+)
+
+// ProjectRef identifies a project of Code Secure by its source control and repository id, as sent by InitScan
+type ProjectRef struct {
+	Source string
+	RepoId string
+}
+
+func NewProjectRef(sourceManager git.GitEnv) ProjectRef {
+	return ProjectRef{Source: sourceManager.Provider(), RepoId: sourceManager.ProjectID()}
+}
+
+func (project ProjectRef) values() url.Values {
+	values := url.Values{}
+	values.Set("source", project.Source)
+	values.Set("repoId", project.RepoId)
+	return values
+}
+
+// Page is a page of a list endpoint, Page starts at 1
+type Page[T any] struct {
+	Items     []T `json:"items"`
+	Page      int `json:"page"`
+	Size      int `json:"size"`
+	Count     int `json:"count"`
+	PageCount int `json:"pageCount"`
+}
+
+// RemoteFinding is a finding triaged on Code Secure
+type RemoteFinding struct {
+	SastFinding
+	Status     FindingStatus `json:"status,omitempty"`
+	Scanner    string        `json:"scanner,omitempty"`
+	FindingUrl string        `json:"findingUrl,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+}
+
+// FindingQuery filters the findings of a project, an empty filter matches every value
+type FindingQuery struct {
+	Project  ProjectRef
+	Branch   string
+	Scanner  string
+	Status   []FindingStatus
+	Severity []Severity
+	PageSize int
+}
+
+func (query FindingQuery) values() url.Values {
+	values := query.Project.values()
+	if query.Branch != "" {
+		values.Set("branch", query.Branch)
+	}
+	if query.Scanner != "" {
+		values.Set("scanner", query.Scanner)
+	}
+	for _, status := range query.Status {
+		values.Add("status", string(status))
+	}
+	for _, severity := range query.Severity {
+		values.Add("severity", string(severity))
+	}
+	return values
+}
+
+// RemoteScan is a previous scan of a project
+type RemoteScan struct {
+	ScanId       string      `json:"scanId"`
+	ScanUrl      string      `json:"scanUrl,omitempty"`
+	Scanner      string      `json:"scanner,omitempty"`
+	Type         ScannerType `json:"type,omitempty"`
+	Status       ScanStatus  `json:"status,omitempty"`
+	GitAction    GitAction   `json:"gitAction,omitempty"`
+	CommitBranch string      `json:"commitBranch,omitempty"`
+	CommitHash   string      `json:"commitHash,omitempty"`
+	StartedAt    time.Time   `json:"startedAt"`
+	CompletedAt  *time.Time  `json:"completedAt,omitempty"`
+}
+
+type ScanQuery struct {
+	Project  ProjectRef
+	Branch   string
+	Scanner  string
+	PageSize int
+}
+
+// ScannerPolicy is the blocking rule of a type of scanner
+type ScannerPolicy struct {
+	BlockSeverities []Severity `json:"blockSeverities,omitempty"`
+	// NewFindingsOnly only blocks on the findings introduced by the scan
+	NewFindingsOnly bool `json:"newFindingsOnly,omitempty"`
+}
+
+// Blocks reports whether a finding of severity blocks the pipeline
+func (policy ScannerPolicy) Blocks(severity Severity) bool {
+	for _, blockSeverity := range policy.BlockSeverities {
+		if blockSeverity == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// SecurityPolicy is the security configuration of a project on Code Secure
+type SecurityPolicy struct {
+	Sast     ScannerPolicy  `json:"sast"`
+	Sca      ScannerPolicy  `json:"sca"`
+	Licenses *LicensePolicy `json:"licenses,omitempty"`
+}
+
+// Findings iterates over the findings matching query, page by page. The iteration stops after an error
+func (client *Client) Findings(query FindingQuery) iter.Seq2[RemoteFinding, error] {
+	return paginate[RemoteFinding](client, "list findings", "/api/ci/findings", query.values(), query.PageSize)
+}
+
+// FindingPage returns a page of the findings matching query
+func (client *Client) FindingPage(query FindingQuery, page int) (*Page[RemoteFinding], error) {
+	return fetchPage[RemoteFinding](client, "list findings", "/api/ci/findings", query.values(), page, query.PageSize)
+}
+
+func (client *Client) GetFinding(id string) (*RemoteFinding, error) {
+	var finding RemoteFinding
+	_, err := client.call("get finding", callIdempotent, func() (*resty.Response, error) {
+		return client.Request().
+			SetResult(&finding).
+			Get(client.baseURL + "/api/ci/findings/" + url.PathEscape(id))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &finding, nil
+}
+
+// Scans iterates over the previous scans of a project from the most recent one
+func (client *Client) Scans(query ScanQuery) iter.Seq2[RemoteScan, error] {
+	values := query.Project.values()
+	if query.Branch != "" {
+		values.Set("branch", query.Branch)
+	}
+	if query.Scanner != "" {
+		values.Set("scanner", query.Scanner)
+	}
+	return paginate[RemoteScan](client, "list scans", "/api/ci/scans", values, query.PageSize)
+}
+
+func (client *Client) GetSecurityPolicy(project ProjectRef) (*SecurityPolicy, error) {
+	var policy SecurityPolicy
+	_, err := client.call("get security policy", callIdempotent, func() (*resty.Response, error) {
+		return client.Request().
+			SetQueryParamsFromValues(project.values()).
+			SetResult(&policy).
+			Get(client.baseURL + "/api/ci/policy")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Collect reads every item of an iterator, e.g. Collect(client.Findings(query))
+func Collect[T any](items iter.Seq2[T, error]) ([]T, error) {
+	var result []T
+	for item, err := range items {
+		if err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func fetchPage[T any](client *Client, name, path string, query url.Values, page, pageSize int) (*Page[T], error) {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	values := url.Values{}
+	for key, value := range query {
+		values[key] = value
+	}
+	values.Set("page", strconv.Itoa(page))
+	values.Set("size", strconv.Itoa(pageSize))
+	var result Page[T]
+	_, err := client.call(name, callIdempotent, func() (*resty.Response, error) {
+		return client.Request().
+			SetQueryParamsFromValues(values).
+			SetResult(&result).
+			Get(client.baseURL + path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func paginate[T any](client *Client, name, path string, query url.Values, pageSize int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page := 1; ; page++ {
+			result, err := fetchPage[T](client, name, path, query, page, pageSize)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range result.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(result.Items) == 0 || page >= result.PageCount {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/go-resty/resty/v2"
+)
+
+const defaultClientTimeout = 2 * time.Minute
+
+// RetryPolicy configures the retries of the calls to the Code Secure API. The wait between two attempts grows
+// exponentially from InitialBackoff up to MaxBackoff with a random jitter, unless the server asks for a wait with
+// Retry-After which is honored up to MaxRetryAfter
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxRetryAfter  time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		MaxRetryAfter:  2 * time.Minute,
+	}
+}
+
+// retryPolicyFromEnv reads CODE_SECURE_MAX_RETRIES, CODE_SECURE_RETRY_BACKOFF and CODE_SECURE_RETRY_MAX_BACKOFF
+func retryPolicyFromEnv() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	if value := os.Getenv("CODE_SECURE_MAX_RETRIES"); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			logger.Warn("invalid CODE_SECURE_MAX_RETRIES: " + value)
+		} else {
+			policy.MaxRetries = retries
+		}
+	}
+	policy.InitialBackoff = envDuration("CODE_SECURE_RETRY_BACKOFF", policy.InitialBackoff)
+	policy.MaxBackoff = envDuration("CODE_SECURE_RETRY_MAX_BACKOFF", policy.MaxBackoff)
+	return policy
+}
+
+// envDuration reads a duration such as "30s" or a number of seconds
+func envDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		logger.Warn(fmt.Sprintf("invalid %s: %s", name, value))
+		return defaultValue
+	}
+	return duration
+}
+
+type callKind int
+
+const (
+	// callIdempotent can be sent again whatever happened to the previous attempt
+	callIdempotent callKind = iota
+	// callCreate creates a resource, it is only sent again when the server surely did not process the previous attempt
+	callCreate
+)
+
+// call sends a request built by send until it succeeds, fails with an error which is not retryable for the kind of
+// call, or the retries are exhausted. The response is returned with the error when the server answered
+func (client *Client) call(name string, kind callKind, send func() (*resty.Response, error)) (*resty.Response, error) {
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		res, err := send()
+		if err == nil && res.IsSuccess() {
+			return res, nil
+		}
+		// the server rejected the request before processing it, an expired credential is renewed once
+		if err == nil && res.StatusCode() == http.StatusUnauthorized && !refreshed && client.credentials.Invalidate() {
+			refreshed = true
+			attempt--
+			continue
+		}
+		if err == nil {
+			err = withCredentialHint(responseError(name, res), client.credentials)
+		} else {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		if attempt >= client.retry.MaxRetries || !isRetryable(kind, res, err) {
+			return res, err
+		}
+		wait := client.retryDelay(attempt, res)
+		logger.Warn(fmt.Sprintf("%s, retry in %s (%d/%d)", err.Error(), wait.Round(time.Millisecond), attempt+1, client.retry.MaxRetries))
+		time.Sleep(wait)
+	}
+}
+
+// isRetryable classifies a failed attempt. 429 and 503 are answered before the request is processed so every call can
+// be retried, gateway errors and broken connections may happen after the server processed the request
+func isRetryable(kind callKind, res *resty.Response, err error) bool {
+	if res == nil || res.RawResponse == nil {
+		// the token exchange answered before the request was sent
+		var apiError *APIError
+		if errors.As(err, &apiError) {
+			return apiError.StatusCode == http.StatusTooManyRequests || apiError.StatusCode == http.StatusServiceUnavailable
+		}
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return kind == callIdempotent
+	}
+	switch res.StatusCode() {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusRequestTimeout, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return kind == callIdempotent
+	}
+	return false
+}
+
+func (client *Client) retryDelay(attempt int, res *resty.Response) time.Duration {
+	if res != nil && res.RawResponse != nil {
+		if wait, ok := retryAfter(res.Header().Get("Retry-After")); ok {
+			return min(wait, client.retry.MaxRetryAfter)
+		}
+	}
+	backoff := client.retry.InitialBackoff << attempt
+	if backoff <= 0 || backoff > client.retry.MaxBackoff {
+		backoff = client.retry.MaxBackoff
+	}
+	// equal jitter keeps at least half of the backoff and spreads the clients retrying at the same time
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryAfter parses the delay in seconds or the http date of a Retry-After header
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// SetRetryPolicy replaces the retry policy read from the environment
+func (client *Client) SetRetryPolicy(policy RetryPolicy) {
+	client.retry = policy
+}
+
+// SetTimeout sets the timeout of every request, 0 disables the timeout
+func (client *Client) SetTimeout(timeout time.Duration) {
+	client.httpClient.SetTimeout(timeout)
+}
@@ -0,0 +1,187 @@
+package analyzer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultUploadChunkSize = 1000
+	// gzipMinSize is the size of the smallest json body worth compressing
+	gzipMinSize = 16 * 1024
+)
+
+var errChunkedUploadUnsupported = errors.New("chunked upload is not supported")
+
+// ServerCapabilities are the optional features advertised by the server at /api/ci/capabilities
+type ServerCapabilities struct {
+	ChunkedUpload bool `json:"chunkedUpload"`
+	Gzip          bool `json:"gzip"`
+	MaxChunkSize  int  `json:"maxChunkSize"`
+}
+
+type beginUploadRequest struct {
+	Strategy     ScanStrategy  `json:"strategy,omitempty"`
+	ChangedFiles []ChangedFile `json:"changedFiles,omitempty"`
+	Chunks       int           `json:"chunks"`
+}
+
+type uploadChunkRequest struct {
+	Findings []SastFinding `json:"findings"`
+}
+
+// uploadSession tracks the chunks acknowledged by the server so that a failed upload resumes with the missing chunks
+type uploadSession struct {
+	digest string
+	acked  map[int]bool
+}
+
+// uploadChunkSize reads the number of findings per chunk from CODE_SECURE_UPLOAD_CHUNK_SIZE
+func uploadChunkSize() int {
+	value := os.Getenv("CODE_SECURE_UPLOAD_CHUNK_SIZE")
+	if value == "" {
+		return defaultUploadChunkSize
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		logger.Warn("invalid CODE_SECURE_UPLOAD_CHUNK_SIZE: " + value)
+		return defaultUploadChunkSize
+	}
+	return size
+}
+
+// Capabilities returns the features advertised by the server, none when the server does not support the endpoint
+func (client *Client) Capabilities() ServerCapabilities {
+	if client.capabilities != nil {
+		return *client.capabilities
+	}
+	var capabilities ServerCapabilities
+	_, err := client.call("capabilities", callIdempotent, func() (*resty.Response, error) {
+		return client.Request().
+			SetResult(&capabilities).
+			Get(client.baseURL + "/api/ci/capabilities")
+	})
+	if err != nil {
+		if !IsAPIError(err, http.StatusNotFound) {
+			logger.Warn(err.Error())
+		}
+		capabilities = ServerCapabilities{}
+	}
+	client.capabilities = &capabilities
+	return capabilities
+}
+
+// jsonRequest sets the json body of a request, compressed with gzip when it is large and the server supports it
+func (client *Client) jsonRequest(body any) (*resty.Request, error) {
+	request := client.Request().SetHeader("Content-Type", "application/json")
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gzipMinSize || !client.Capabilities().Gzip {
+		return request.SetBody(data), nil
+	}
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err = writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return request.
+		SetHeader("Content-Encoding", "gzip").
+		SetBody(buffer.Bytes()), nil
+}
+
+// uploadFindingChunks sends the findings with the begin/append/commit protocol of the scan. A session that failed on a
+// chunk is resumed by the next upload of the same findings
+func (client *Client) uploadFindingChunks(request UploadFindingRequest, chunkSize int) (*UploadFindingResponse, error) {
+	var chunks [][]SastFinding
+	for start := 0; start < len(request.Findings); start += chunkSize {
+		chunks = append(chunks, request.Findings[start:min(start+chunkSize, len(request.Findings))])
+	}
+	digest, err := uploadDigest(request)
+	if err != nil {
+		return nil, err
+	}
+	scanURL := client.baseURL + "/api/ci/scan/" + request.ScanId + "/upload"
+	session := client.uploads[request.ScanId]
+	if session == nil || session.digest != digest {
+		_, err = client.call("begin upload", callIdempotent, func() (*resty.Response, error) {
+			httpRequest, err := client.jsonRequest(beginUploadRequest{
+				Strategy:     request.Strategy,
+				ChangedFiles: request.ChangedFiles,
+				Chunks:       len(chunks),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return httpRequest.Post(scanURL)
+		})
+		if IsAPIError(err, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented) {
+			return nil, errChunkedUploadUnsupported
+		}
+		if err != nil {
+			return nil, err
+		}
+		session = &uploadSession{digest: digest, acked: make(map[int]bool)}
+		client.uploads[request.ScanId] = session
+	} else {
+		logger.Info(fmt.Sprintf("resume upload of scan %s: %d/%d chunks already sent", request.ScanId, len(session.acked), len(chunks)))
+	}
+	for index, chunk := range chunks {
+		if session.acked[index] {
+			continue
+		}
+		name := fmt.Sprintf("upload chunk %d/%d", index+1, len(chunks))
+		_, err = client.call(name, callIdempotent, func() (*resty.Response, error) {
+			httpRequest, err := client.jsonRequest(uploadChunkRequest{Findings: chunk})
+			if err != nil {
+				return nil, err
+			}
+			return httpRequest.Put(scanURL + "/chunks/" + strconv.Itoa(index))
+		})
+		if err != nil {
+			return nil, err
+		}
+		session.acked[index] = true
+	}
+	var response UploadFindingResponse
+	// the server may have processed a commit whose response was lost, a new session is begun rather than committing twice
+	_, err = client.call("commit upload", callCreate, func() (*resty.Response, error) {
+		return client.Request().
+			SetResult(&response).
+			Post(scanURL + "/commit")
+	})
+	delete(client.uploads, request.ScanId)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// hasPendingUpload reports a chunked upload of the scan which failed on a chunk and can be resumed
+func (client *Client) hasPendingUpload(scanId string) bool {
+	return client.uploads[scanId] != nil
+}
+
+func uploadDigest(request UploadFindingRequest) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
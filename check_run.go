@@ -0,0 +1,176 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// checkRun mirrors a scan into a commit check of the source manager. A nil checkRun is a no-op
+type checkRun struct {
+	reporter git.CheckRunReporter
+	findings int
+}
+
+func newCheckRun(sourceManager git.GitEnv, scannerName string, detailsURL string) *checkRun {
+	if os.Getenv("GITHUB_CHECK_RUN") != "true" {
+		return nil
+	}
+	reporter, ok := sourceManager.(git.CheckRunReporter)
+	if !ok {
+		logger.Warn(sourceManager.Provider() + " does not support check run")
+		return nil
+	}
+	err := reporter.StartCheckRun("code-secure/"+scannerName, detailsURL)
+	if err != nil {
+		logger.Error(err.Error())
+		return nil
+	}
+	return &checkRun{reporter: reporter}
+}
+
+func (run *checkRun) annotateSastFindings(findings []SastFinding) {
+	if run == nil {
+		return
+	}
+	var annotations []git.CheckAnnotation
+	for _, finding := range findings {
+		if finding.Location == nil || finding.Location.Path == "" {
+			continue
+		}
+		message := finding.Description
+		if message == "" {
+			message = finding.Name
+		}
+		annotations = append(annotations, git.CheckAnnotation{
+			Path:      finding.Location.Path,
+			StartLine: finding.Location.StartLine,
+			EndLine:   finding.Location.EndLine,
+			Level:     annotationLevel(finding.Severity),
+			Title:     finding.Name,
+			Message:   message,
+			Details:   finding.Recommendation,
+		})
+	}
+	run.annotate(annotations)
+}
+
+func (run *checkRun) annotateVulnerabilities(result ScaResult) {
+	if run == nil {
+		return
+	}
+	locations := make(map[string]string)
+	for _, pkg := range result.Packages {
+		if pkg.Location != nil {
+			locations[pkg.PkgId] = *pkg.Location
+		}
+	}
+	var annotations []git.CheckAnnotation
+	for _, vulnerability := range result.Vulnerabilities {
+		location, ok := locations[vulnerability.PkgId]
+		if !ok {
+			continue
+		}
+		message := vulnerability.Description
+		if message == "" {
+			message = vulnerability.Name
+		}
+		details := ""
+		if vulnerability.FixedVersion != "" {
+			details = "Fixed version: " + vulnerability.FixedVersion
+		}
+		level := annotationLevel(vulnerability.Severity)
+		if vulnerability.Vex != nil {
+			details = strings.TrimPrefix(details+"\nVEX: "+vexDescription(*vulnerability.Vex), "\n")
+			if vulnerability.IsSuppressed() {
+				level = git.AnnotationNotice
+			}
+		}
+		annotations = append(annotations, git.CheckAnnotation{
+			Path:    location,
+			Level:   level,
+			Title:   fmt.Sprintf("%s in %s", vulnerability.Name, vulnerability.PkgName),
+			Message: message,
+			Details: details,
+		})
+	}
+	run.annotate(annotations)
+}
+
+func (run *checkRun) annotateLicenseViolations(result ScaResult) {
+	if run == nil {
+		return
+	}
+	var annotations []git.CheckAnnotation
+	for _, violation := range result.LicenseViolations {
+		pkg := violation.Package
+		if pkg.Location == nil {
+			continue
+		}
+		level := git.AnnotationWarning
+		if violation.Action == LicenseActionDeny {
+			level = git.AnnotationFailure
+		}
+		var paths []string
+		for _, path := range violation.Paths {
+			paths = append(paths, strings.Join(path, " > "))
+		}
+		annotations = append(annotations, git.CheckAnnotation{
+			Path:    *pkg.Location,
+			Level:   level,
+			Title:   fmt.Sprintf("License %s of %s", violation.License, packageName(pkg)),
+			Message: fmt.Sprintf("%s@%s is licensed under %s (%s)", packageName(pkg), pkg.Version, violation.License, violation.Action),
+			Details: strings.Join(paths, "\n"),
+		})
+	}
+	run.annotate(annotations)
+}
+
+func (run *checkRun) annotate(annotations []git.CheckAnnotation) {
+	if len(annotations) == 0 {
+		return
+	}
+	run.findings += len(annotations)
+	err := run.reporter.AddCheckRunAnnotations(annotations)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func (run *checkRun) complete(isBlock bool) {
+	if run == nil {
+		return
+	}
+	summary := fmt.Sprintf("There are %d findings", run.findings)
+	if isBlock {
+		summary += ". Blocked due security config"
+	}
+	err := run.reporter.CompleteCheckRun(!isBlock, summary)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func (run *checkRun) fail(err error) {
+	if run == nil {
+		return
+	}
+	completeErr := run.reporter.CompleteCheckRun(false, "Scan error: "+err.Error())
+	if completeErr != nil {
+		logger.Error(completeErr.Error())
+	}
+}
+
+func annotationLevel(severity Severity) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return git.AnnotationFailure
+	case SeverityMedium:
+		return git.AnnotationWarning
+	default:
+		return git.AnnotationNotice
+	}
+}
@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"os"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// uploadSarif publishes sast findings to the code scanning of the source manager when GITHUB_SARIF_UPLOAD is enabled
+func uploadSarif(sourceManager git.GitEnv, scannerName string, strategy ScanStrategy, findings []SastFinding) {
+	if os.Getenv("GITHUB_SARIF_UPLOAD") != "true" || sourceManager == nil {
+		return
+	}
+	uploader, ok := sourceManager.(git.SarifUploader)
+	if !ok {
+		logger.Warn(sourceManager.Provider() + " does not support sarif upload")
+		return
+	}
+	log := NewSarifLog(scannerName, findings)
+	// code scanning treats each analysis as complete, a partial scan would close alerts of unchanged files. The partial
+	// scan of a pull request goes to its own category, a partial scan of a branch is not uploaded
+	if strategy != AllFiles {
+		if sourceManager.MergeRequestID() == "" {
+			logger.Warn("skip sarif upload because scan strategy is " + strategy.String())
+			return
+		}
+		for i := range log.Runs {
+			log.Runs[i].AutomationDetails = &SarifAutomationDetails{ID: scannerName + "/pull-request/"}
+		}
+	}
+	data, err := log.JSON()
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	err = uploader.UploadSarif(data, scannerName)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
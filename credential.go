@@ -0,0 +1,243 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// tokenRefreshMargin renews an exchanged token before it expires during a request
+	tokenRefreshMargin = 30 * time.Second
+	defaultTokenTTL    = 5 * time.Minute
+	tokenExchangeGrant = "urn:ietf:params:oauth:grant-type:token-exchange"
+	jwtTokenType       = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// CredentialProvider supplies the credential sent with every request of the Client
+type CredentialProvider interface {
+	// Credential returns the header and its value
+	Credential() (header string, value string, err error)
+	// Invalidate drops a credential rejected by the server, it returns false when there is no other credential to try
+	Invalidate() bool
+}
+
+// credentialHinter is implemented by the providers which tell how to fix a credential rejected by the server
+type credentialHinter interface {
+	credentialHint() string
+}
+
+// withCredentialHint adds the hint of provider to an APIError which rejects the credential
+func withCredentialHint(err error, provider CredentialProvider) error {
+	var apiError *APIError
+	if !errors.As(err, &apiError) || (apiError.StatusCode != http.StatusUnauthorized && apiError.StatusCode != http.StatusForbidden) {
+		return err
+	}
+	if hinter, ok := provider.(credentialHinter); ok {
+		apiError.Hint = hinter.credentialHint()
+	}
+	return err
+}
+
+// httpClientUser is implemented by the providers which call the server, they share the transport of the Client
+type httpClientUser interface {
+	useHTTPClient(client *http.Client)
+}
+
+type staticToken string
+
+// StaticToken sends a long-lived token in the CI-TOKEN header
+func StaticToken(token string) CredentialProvider {
+	return staticToken(token)
+}
+
+func (token staticToken) Credential() (string, string, error) {
+	return "CI-TOKEN", string(token), nil
+}
+
+func (token staticToken) Invalidate() bool {
+	return false
+}
+
+func (token staticToken) credentialHint() string {
+	return "check CODE_SECURE_TOKEN"
+}
+
+// IDTokenSource returns an OIDC ID token of the CI job for the audience
+type IDTokenSource func(client *http.Client, audience string) (string, error)
+
+// OIDCCredentials exchanges the OIDC ID token of the CI job for a short-lived token of Code Secure (RFC 8693) and
+// renews it before it expires
+type OIDCCredentials struct {
+	tokenURL   string
+	audience   string
+	source     IDTokenSource
+	httpClient *resty.Client
+	mu         sync.Mutex
+	token      string
+	expiresAt  time.Time
+}
+
+type tokenExchangeResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// NewOIDCCredentials exchanges the ID tokens of source at {baseUrl}/api/ci/auth/token
+func NewOIDCCredentials(baseUrl string, audience string, source IDTokenSource) *OIDCCredentials {
+	return &OIDCCredentials{
+		tokenURL:   strings.TrimSuffix(baseUrl, "/") + "/api/ci/auth/token",
+		audience:   audience,
+		source:     source,
+		httpClient: resty.New().SetTimeout(defaultClientTimeout),
+	}
+}
+
+func (credentials *OIDCCredentials) useHTTPClient(client *http.Client) {
+	credentials.httpClient = resty.NewWithClient(client)
+}
+
+func (credentials *OIDCCredentials) Credential() (string, string, error) {
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	if credentials.token == "" || time.Now().Add(tokenRefreshMargin).After(credentials.expiresAt) {
+		if err := credentials.exchange(); err != nil {
+			return "", "", err
+		}
+	}
+	return "Authorization", "Bearer " + credentials.token, nil
+}
+
+func (credentials *OIDCCredentials) Invalidate() bool {
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	credentials.token = ""
+	return true
+}
+
+func (credentials *OIDCCredentials) credentialHint() string {
+	return fmt.Sprintf("check that Code Secure trusts the id token of the job for the audience %q (CODE_SECURE_OIDC_AUDIENCE)", credentials.audience)
+}
+
+func (credentials *OIDCCredentials) exchange() error {
+	idToken, err := credentials.source(credentials.httpClient.GetClient(), credentials.audience)
+	if err != nil {
+		return fmt.Errorf("oidc id token: %w", err)
+	}
+	var response tokenExchangeResponse
+	res, err := credentials.httpClient.R().
+		SetFormData(map[string]string{
+			"grant_type":         tokenExchangeGrant,
+			"subject_token":      idToken,
+			"subject_token_type": jwtTokenType,
+			"audience":           credentials.audience,
+		}).
+		SetResult(&response).
+		Post(credentials.tokenURL)
+	if err != nil {
+		return fmt.Errorf("token exchange: %w", err)
+	}
+	if !res.IsSuccess() {
+		return withCredentialHint(responseError("token exchange", res), credentials)
+	}
+	if response.AccessToken == "" {
+		return errors.New("token exchange: the server did not return a token")
+	}
+	ttl := time.Duration(response.ExpiresIn) * time.Second
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	credentials.token = response.AccessToken
+	credentials.expiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+// GitLabIDToken reads the id token declared with the id_tokens keyword as CODE_SECURE_ID_TOKEN, or the deprecated
+// CI_JOB_JWT_V2. The audience is set by the job definition
+func GitLabIDToken(_ *http.Client, _ string) (string, error) {
+	if token := firstNonEmpty(os.Getenv("CODE_SECURE_ID_TOKEN"), os.Getenv("CI_JOB_JWT_V2")); token != "" {
+		return token, nil
+	}
+	return "", errors.New("declare CODE_SECURE_ID_TOKEN with the id_tokens keyword of the job")
+}
+
+// GitHubIDToken requests an id token from GitHub Actions, the job needs the id-token: write permission
+func GitHubIDToken(client *http.Client, audience string) (string, error) {
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", errors.New("add the id-token: write permission to the job")
+	}
+	tokenURL, err := url.Parse(requestURL)
+	if err != nil {
+		return "", err
+	}
+	if audience != "" {
+		query := tokenURL.Query()
+		query.Set("audience", audience)
+		tokenURL.RawQuery = query.Encode()
+	}
+	var response struct {
+		Value string `json:"value"`
+	}
+	res, err := resty.NewWithClient(client).R().
+		SetAuthToken(requestToken).
+		SetResult(&response).
+		Get(tokenURL.String())
+	if err != nil {
+		return "", err
+	}
+	if !res.IsSuccess() {
+		return "", responseError("github id token", res)
+	}
+	return response.Value, nil
+}
+
+// idTokenSourceFromEnv detects the id token of the CI, nil when there is none
+func idTokenSourceFromEnv() IDTokenSource {
+	if os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") != "" {
+		return GitHubIDToken
+	}
+	if os.Getenv("CODE_SECURE_ID_TOKEN") != "" || os.Getenv("CI_JOB_JWT_V2") != "" {
+		return GitLabIDToken
+	}
+	return nil
+}
+
+// credentialsFromEnv selects the credential with CODE_SECURE_AUTH: token (CODE_SECURE_TOKEN) or oidc. By default the
+// token is used when it is set, otherwise the OIDC id token of the CI. It returns nil when no credential is configured
+func credentialsFromEnv(server string) (CredentialProvider, error) {
+	token := os.Getenv("CODE_SECURE_TOKEN")
+	source := idTokenSourceFromEnv()
+	switch mode := strings.ToLower(os.Getenv("CODE_SECURE_AUTH")); mode {
+	case "":
+		if token == "" && source == nil {
+			return nil, nil
+		}
+	case "token":
+		if token == "" {
+			return nil, errors.New("CODE_SECURE_TOKEN is required by token authentication")
+		}
+		source = nil
+	case "oidc":
+		if source == nil {
+			return nil, errors.New("there is no OIDC id token, declare CODE_SECURE_ID_TOKEN on GitLab or add the id-token: write permission on GitHub")
+		}
+		token = ""
+	default:
+		return nil, errors.New("invalid CODE_SECURE_AUTH: " + mode)
+	}
+	if token != "" {
+		return StaticToken(token), nil
+	}
+	audience := firstNonEmpty(os.Getenv("CODE_SECURE_OIDC_AUDIENCE"), strings.TrimSuffix(server, "/"))
+	return NewOIDCCredentials(server, audience, source), nil
+}
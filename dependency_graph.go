@@ -0,0 +1,226 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// maxDependencyPaths bounds the paths listed per package, a package used everywhere has a combinatorial number of paths
+const maxDependencyPaths = 10
+
+// DependencyGraph is the graph of the package dependencies of a sca result. Root packages are the packages no other
+// package depends on: the direct dependencies of the project, or the project itself when the scanner reports it
+type DependencyGraph struct {
+	packages map[string]Package
+	order    []string
+	children map[string][]string
+	parents  map[string][]string
+}
+
+func NewDependencyGraph(result ScaResult) *DependencyGraph {
+	graph := &DependencyGraph{
+		packages: make(map[string]Package),
+		children: make(map[string][]string),
+		parents:  make(map[string][]string),
+	}
+	for _, pkg := range result.Packages {
+		graph.addNode(pkg.PkgId)
+		graph.packages[pkg.PkgId] = pkg
+	}
+	edges := make(map[string]bool)
+	for _, dependency := range result.PackageDependencies {
+		graph.addNode(dependency.PkgId)
+		for _, child := range dependency.Dependencies {
+			if edges[dependency.PkgId+" "+child] {
+				continue
+			}
+			edges[dependency.PkgId+" "+child] = true
+			graph.addNode(child)
+			graph.children[dependency.PkgId] = append(graph.children[dependency.PkgId], child)
+			graph.parents[child] = append(graph.parents[child], dependency.PkgId)
+		}
+	}
+	return graph
+}
+
+func (graph *DependencyGraph) addNode(pkgId string) {
+	if _, ok := graph.packages[pkgId]; ok {
+		return
+	}
+	graph.packages[pkgId] = Package{PkgId: pkgId}
+	graph.order = append(graph.order, pkgId)
+}
+
+// Roots returns the packages without parent in the order of the sca result
+func (graph *DependencyGraph) Roots() []string {
+	var roots []string
+	for _, pkgId := range graph.order {
+		if len(graph.parents[pkgId]) == 0 {
+			roots = append(roots, pkgId)
+		}
+	}
+	return roots
+}
+
+// Dependencies returns the packages a package depends on
+func (graph *DependencyGraph) Dependencies(pkgId string) []string {
+	return graph.children[pkgId]
+}
+
+// Paths returns up to limit chains of package ids from a root package down to a package, limit <= 0 returns every
+// path. A root package has no path
+func (graph *DependencyGraph) Paths(pkgId string, limit int) [][]string {
+	var paths [][]string
+	visited := make(map[string]bool)
+	var walk func(current string, path []string)
+	walk = func(current string, path []string) {
+		if limit > 0 && len(paths) >= limit {
+			return
+		}
+		path = append([]string{current}, path...)
+		if len(graph.parents[current]) == 0 {
+			if len(path) > 1 {
+				paths = append(paths, path)
+			}
+			return
+		}
+		visited[current] = true
+		for _, parent := range graph.parents[current] {
+			if !visited[parent] {
+				walk(parent, path)
+			}
+		}
+		delete(visited, current)
+	}
+	walk(pkgId, nil)
+	return paths
+}
+
+// ShortestPath returns the shortest chain of package ids from a root package down to a package, nil when the package is
+// a root or is only reachable from a cycle
+func (graph *DependencyGraph) ShortestPath(pkgId string) []string {
+	next := map[string]string{pkgId: ""}
+	queue := []string{pkgId}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if len(graph.parents[current]) == 0 {
+			if current == pkgId {
+				return nil
+			}
+			var path []string
+			for id := current; id != ""; id = next[id] {
+				path = append(path, id)
+			}
+			return path
+		}
+		for _, parent := range graph.parents[current] {
+			if _, ok := next[parent]; !ok {
+				next[parent] = current
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return nil
+}
+
+// DirectDependencies returns the root packages which pull a package in, these are the packages to upgrade
+func (graph *DependencyGraph) DirectDependencies(pkgId string) []string {
+	var direct []string
+	visited := map[string]bool{pkgId: true}
+	queue := []string{pkgId}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if len(graph.parents[current]) == 0 && current != pkgId {
+			direct = append(direct, current)
+		}
+		for _, parent := range graph.parents[current] {
+			if !visited[parent] {
+				visited[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return direct
+}
+
+// Cycles returns the dependency cycles of the graph, every cycle starts and ends with the same package id
+func (graph *DependencyGraph) Cycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycles [][]string
+	var visit func(pkgId string)
+	visit = func(pkgId string) {
+		state[pkgId] = visiting
+		stack = append(stack, pkgId)
+		for _, child := range graph.children[pkgId] {
+			switch state[child] {
+			case unvisited:
+				visit(child)
+			case visiting:
+				for index := len(stack) - 1; index >= 0; index-- {
+					if stack[index] == child {
+						cycle := append([]string{}, stack[index:]...)
+						cycles = append(cycles, append(cycle, child))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[pkgId] = done
+	}
+	for _, pkgId := range graph.order {
+		if state[pkgId] == unvisited {
+			visit(pkgId)
+		}
+	}
+	return cycles
+}
+
+// Name returns name@version of a package, or the package id when the package is unknown
+func (graph *DependencyGraph) Name(pkgId string) string {
+	pkg := graph.packages[pkgId]
+	if pkg.Name == "" {
+		return pkgId
+	}
+	return packageName(pkg) + "@" + pkg.Version
+}
+
+// PathNames returns the paths of a package with the names of the packages
+func (graph *DependencyGraph) PathNames(pkgId string) [][]string {
+	var names [][]string
+	for _, path := range graph.Paths(pkgId, maxDependencyPaths) {
+		var pathNames []string
+		for _, id := range path {
+			pathNames = append(pathNames, graph.Name(id))
+		}
+		names = append(names, pathNames)
+	}
+	return names
+}
+
+// AttachDependencyPaths sets the dependency paths and the direct dependencies of the vulnerabilities of a sca result
+func AttachDependencyPaths(result *ScaResult) {
+	graph := NewDependencyGraph(*result)
+	for _, cycle := range graph.Cycles() {
+		var names []string
+		for _, id := range cycle {
+			names = append(names, graph.Name(id))
+		}
+		logger.Info(fmt.Sprintf("dependency cycle: %s", strings.Join(names, " > ")))
+	}
+	for index := range result.Vulnerabilities {
+		vulnerability := &result.Vulnerabilities[index]
+		vulnerability.DependencyPaths = graph.Paths(vulnerability.PkgId, maxDependencyPaths)
+		vulnerability.DirectDependencies = graph.DirectDependencies(vulnerability.PkgId)
+	}
+}
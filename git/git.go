@@ -34,3 +34,50 @@ type GitEnv interface {
 	IsActive() bool
 	CreateMRDiscussion(option MRDiscussionOption) error
 }
+
+const (
+	AnnotationFailure = "failure"
+	AnnotationWarning = "warning"
+	AnnotationNotice  = "notice"
+)
+
+type CheckAnnotation struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Level     string
+	Title     string
+	Message   string
+	Details   string
+}
+
+// CheckRunReporter is implemented by source managers that can publish scan results as a commit check
+type CheckRunReporter interface {
+	StartCheckRun(name string, detailsURL string) error
+	AddCheckRunAnnotations(annotations []CheckAnnotation) error
+	CompleteCheckRun(success bool, summary string) error
+}
+
+// SarifUploader is implemented by source managers that can ingest a SARIF log into their code scanning
+type SarifUploader interface {
+	UploadSarif(sarif []byte, toolName string) error
+}
+
+const (
+	CommitStatusRunning = "running"
+	CommitStatusSuccess = "success"
+	CommitStatusFailed  = "failed"
+)
+
+type CommitStatusOption struct {
+	Name        string
+	State       string
+	TargetURL   string
+	Description string
+}
+
+// SecurityGate is implemented by source managers that can enforce the block decision independent of the job exit code
+type SecurityGate interface {
+	SetCommitStatus(option CommitStatusOption) error
+	SetMergeRequestBlocked(blocked bool, reason string) error
+}
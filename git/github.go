@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/califio/code-secure-analyzer/transport"
 	"github.com/google/go-github/v74/github"
 	"golang.org/x/oauth2"
 )
@@ -19,16 +21,31 @@ type GitHubEnv struct {
 	client       *github.Client
 	ctx          context.Context
 	eventPayload eventPayload
+	checkRun     *checkRunState
 }
 
 func NewGitHub() (*GitHubEnv, error) {
 	accessToken := os.Getenv("GITHUB_TOKEN")
-	ctx := context.Background()
+	httpClient, err := transport.FromEnv().NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	// oauth2 sends the requests with the http client of the context
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: accessToken},
 	)
 	tc := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(tc)
+	// GitHub Enterprise Server exposes the REST API under a different base url
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL != "" && apiURL != "https://api.github.com" {
+		baseURL, err := url.Parse(strings.TrimSuffix(apiURL, "/") + "/")
+		if err != nil {
+			return nil, errors.New("invalid GITHUB_API_URL: " + err.Error())
+		}
+		client.BaseURL = baseURL
+	}
 
 	return &GitHubEnv{
 		accessToken: accessToken,
@@ -58,12 +75,10 @@ func (g *GitHubEnv) CreateMRDiscussion(option MRDiscussionOption) error {
 	if err != nil {
 		return errors.New("pull request id should be a number")
 	}
-	ownerRepo := os.Getenv("GITHUB_REPOSITORY")
-	parts := strings.Split(ownerRepo, "/")
-	if len(parts) != 2 {
-		return errors.New("invalid GITHUB_REPOSITORY format")
+	owner, repo, err := ownerAndRepo()
+	if err != nil {
+		return err
 	}
-	owner, repo := parts[0], parts[1]
 	comment := github.DraftReviewComment{
 		Path: github.Ptr(option.Path),
 		Body: github.Ptr(option.Body),
@@ -197,6 +212,14 @@ func (g *GitHubEnv) JobURL() string {
 	return fmt.Sprintf("%s/%s/actions/runs/%s", os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"))
 }
 
+func ownerAndRepo() (string, string, error) {
+	parts := strings.Split(os.Getenv("GITHUB_REPOSITORY"), "/")
+	if len(parts) != 2 {
+		return "", "", errors.New("invalid GITHUB_REPOSITORY format")
+	}
+	return parts[0], parts[1], nil
+}
+
 func getEventPayload() eventPayload {
 	eventPath := os.Getenv("GITHUB_EVENT_PATH")
 	_, err := os.Stat(eventPath)
@@ -0,0 +1,129 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/google/go-github/v74/github"
+)
+
+// maxAnnotationsPerRequest is the limit of annotations GitHub accepts in a single check run update
+const maxAnnotationsPerRequest = 50
+
+type checkRunState struct {
+	id          int64
+	name        string
+	annotations int
+}
+
+func (g *GitHubEnv) StartCheckRun(name string, detailsURL string) error {
+	owner, repo, err := ownerAndRepo()
+	if err != nil {
+		return err
+	}
+	opts := github.CreateCheckRunOptions{
+		Name:      name,
+		HeadSHA:   g.checkRunHeadSha(),
+		Status:    github.Ptr("in_progress"),
+		StartedAt: &github.Timestamp{Time: time.Now()},
+	}
+	if detailsURL != "" {
+		opts.DetailsURL = github.Ptr(detailsURL)
+	}
+	checkRun, _, err := g.client.Checks.CreateCheckRun(g.ctx, owner, repo, opts)
+	if err != nil {
+		logger.Error("Create check run failed")
+		return err
+	}
+	g.checkRun = &checkRunState{id: checkRun.GetID(), name: name}
+	logger.Info("Created check run: " + name)
+	return nil
+}
+
+func (g *GitHubEnv) AddCheckRunAnnotations(annotations []CheckAnnotation) error {
+	if g.checkRun == nil {
+		return errors.New("check run is not started")
+	}
+	owner, repo, err := ownerAndRepo()
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(annotations); start += maxAnnotationsPerRequest {
+		end := min(start+maxAnnotationsPerRequest, len(annotations))
+		var batch []*github.CheckRunAnnotation
+		for _, annotation := range annotations[start:end] {
+			batch = append(batch, toGitHubAnnotation(annotation))
+		}
+		g.checkRun.annotations += len(batch)
+		_, _, err = g.client.Checks.UpdateCheckRun(g.ctx, owner, repo, g.checkRun.id, github.UpdateCheckRunOptions{
+			Name: g.checkRun.name,
+			Output: &github.CheckRunOutput{
+				Title:       github.Ptr(g.checkRun.name),
+				Summary:     github.Ptr(fmt.Sprintf("%d findings", g.checkRun.annotations)),
+				Annotations: batch,
+			},
+		})
+		if err != nil {
+			logger.Error("Add annotations to check run failed")
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GitHubEnv) CompleteCheckRun(success bool, summary string) error {
+	if g.checkRun == nil {
+		return errors.New("check run is not started")
+	}
+	owner, repo, err := ownerAndRepo()
+	if err != nil {
+		return err
+	}
+	conclusion := "success"
+	if !success {
+		conclusion = "failure"
+	}
+	_, _, err = g.client.Checks.UpdateCheckRun(g.ctx, owner, repo, g.checkRun.id, github.UpdateCheckRunOptions{
+		Name:        g.checkRun.name,
+		Status:      github.Ptr("completed"),
+		Conclusion:  github.Ptr(conclusion),
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Output: &github.CheckRunOutput{
+			Title:   github.Ptr(g.checkRun.name),
+			Summary: github.Ptr(summary),
+		},
+	})
+	if err != nil {
+		logger.Error("Complete check run failed")
+		return err
+	}
+	logger.Info(fmt.Sprintf("Completed check run: %s (%s)", g.checkRun.name, conclusion))
+	return nil
+}
+
+// checkRunHeadSha returns the head commit of the pull request instead of the merge commit GitHub checks out
+func (g *GitHubEnv) checkRunHeadSha() string {
+	if g.eventPayload.PullRequest != nil && g.eventPayload.PullRequest.Head.Sha != "" {
+		return g.eventPayload.PullRequest.Head.Sha
+	}
+	return g.CommitSha()
+}
+
+func toGitHubAnnotation(annotation CheckAnnotation) *github.CheckRunAnnotation {
+	startLine := max(annotation.StartLine, 1)
+	endLine := max(annotation.EndLine, startLine)
+	result := &github.CheckRunAnnotation{
+		Path:            github.Ptr(annotation.Path),
+		StartLine:       github.Ptr(startLine),
+		EndLine:         github.Ptr(endLine),
+		AnnotationLevel: github.Ptr(annotation.Level),
+		Message:         github.Ptr(annotation.Message),
+		Title:           github.Ptr(annotation.Title),
+	}
+	if annotation.Details != "" {
+		result.RawDetails = github.Ptr(annotation.Details)
+	}
+	return result
+}
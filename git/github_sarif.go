@@ -0,0 +1,112 @@
+package git
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/google/go-github/v74/github"
+)
+
+const (
+	sarifPollInterval = 3 * time.Second
+	sarifPollTimeout  = 2 * time.Minute
+)
+
+type sarifProcessingStatus struct {
+	ProcessingStatus string   `json:"processing_status"`
+	Errors           []string `json:"errors"`
+}
+
+func (g *GitHubEnv) UploadSarif(sarif []byte, toolName string) error {
+	owner, repo, err := ownerAndRepo()
+	if err != nil {
+		return err
+	}
+	encoded, err := encodeSarif(sarif)
+	if err != nil {
+		return err
+	}
+	ref := g.sarifRef()
+	if ref == "" {
+		return errors.New("cannot upload sarif without git ref")
+	}
+	analysis := &github.SarifAnalysis{
+		CommitSHA: github.Ptr(g.CommitSha()),
+		Ref:       github.Ptr(ref),
+		Sarif:     github.Ptr(encoded),
+		ToolName:  github.Ptr(toolName),
+		StartedAt: &github.Timestamp{Time: time.Now()},
+	}
+	if workspace := os.Getenv("GITHUB_WORKSPACE"); workspace != "" {
+		analysis.CheckoutURI = github.Ptr("file://" + workspace)
+	}
+	sarifID, _, err := g.client.CodeScanning.UploadSarif(g.ctx, owner, repo, analysis)
+	if err != nil {
+		logger.Error("Upload sarif to code scanning failed")
+		return err
+	}
+	logger.Info(fmt.Sprintf("Uploaded sarif (%s) for %s", sarifID.GetID(), ref))
+	return g.waitSarifProcessing(owner, repo, sarifID.GetID())
+}
+
+func (g *GitHubEnv) waitSarifProcessing(owner, repo, sarifID string) error {
+	deadline := time.Now().Add(sarifPollTimeout)
+	for {
+		req, err := g.client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/code-scanning/sarifs/%s", owner, repo, sarifID), nil)
+		if err != nil {
+			return err
+		}
+		var status sarifProcessingStatus
+		_, err = g.client.Do(g.ctx, req, &status)
+		if err != nil {
+			return err
+		}
+		switch status.ProcessingStatus {
+		case "complete":
+			logger.Info("Code scanning processed sarif " + sarifID)
+			return nil
+		case "failed":
+			return errors.New("code scanning failed to process sarif: " + strings.Join(status.Errors, "; "))
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timeout waiting for code scanning to process sarif " + sarifID)
+		}
+		time.Sleep(sarifPollInterval)
+	}
+}
+
+// sarifRef is the ref the analysis belongs to. Pull requests are analyzed on their merge ref
+func (g *GitHubEnv) sarifRef() string {
+	if g.MergeRequestID() != "" {
+		return fmt.Sprintf("refs/pull/%s/merge", g.MergeRequestID())
+	}
+	if ref := os.Getenv("GITHUB_REF"); ref != "" {
+		return ref
+	}
+	if tag := g.CommitTag(); tag != "" {
+		return "refs/tags/" + tag
+	}
+	if branch := g.CommitBranch(); branch != "" {
+		return "refs/heads/" + branch
+	}
+	return ""
+}
+
+func encodeSarif(sarif []byte) (string, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(sarif); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
@@ -4,29 +4,68 @@ import (
 	"errors"
 	"fmt"
 	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/califio/code-secure-analyzer/transport"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 	"os"
 	"strconv"
 )
 
+const (
+	MergeRequestGateApproval = "approval"
+	MergeRequestGateLabel    = "label"
+)
+
 type GitLabEnv struct {
 	accessToken string
 	serverUrl   string
 	client      *gitlab.Client
+	mrGate      string
+	mrGateLabel string
+	mrApprovals int
+	// mrApprovers and mrGroups are the user ids or usernames and the group ids or paths allowed to approve the rule
+	mrApprovers []string
+	mrGroups    []string
 }
 
 func NewGitLab() (*GitLabEnv, error) {
 	accessToken := os.Getenv("GITLAB_TOKEN")
 	serverUrl := os.Getenv("CI_SERVER_URL")
+	httpClient, err := transport.FromEnv().NewHTTPClient()
+	if err != nil {
+		return nil, err
+	}
 	// Initialize the GitLab client
-	client, err := gitlab.NewClient(accessToken, gitlab.WithBaseURL(serverUrl))
+	client, err := gitlab.NewClient(accessToken, gitlab.WithBaseURL(serverUrl), gitlab.WithHTTPClient(httpClient))
 	if err != nil {
 		return nil, err
 	}
+	mrGateLabel := os.Getenv("GITLAB_MR_GATE_LABEL")
+	if mrGateLabel == "" {
+		mrGateLabel = "security::blocked"
+	}
+	mrApprovals := 1
+	if value := os.Getenv("GITLAB_MR_GATE_APPROVALS"); value != "" {
+		if approvals, err := strconv.Atoi(value); err == nil && approvals > 0 {
+			mrApprovals = approvals
+		} else {
+			logger.Warn("GITLAB_MR_GATE_APPROVALS should be a positive number, require 1 approval")
+		}
+	}
+	mrGate := os.Getenv("GITLAB_MR_GATE")
+	mrApprovers := splitList(os.Getenv("GITLAB_MR_GATE_APPROVERS"))
+	mrGroups := splitList(os.Getenv("GITLAB_MR_GATE_GROUPS"))
+	if mrGate == MergeRequestGateApproval && len(mrApprovers) == 0 && len(mrGroups) == 0 {
+		logger.Warn("GITLAB_MR_GATE_APPROVERS or GITLAB_MR_GATE_GROUPS should be set, an approval rule without approvers may not block merging")
+	}
 	return &GitLabEnv{
 		accessToken: accessToken,
 		serverUrl:   serverUrl,
 		client:      client,
+		mrGate:      mrGate,
+		mrGateLabel: mrGateLabel,
+		mrApprovals: mrApprovals,
+		mrApprovers: mrApprovers,
+		mrGroups:    mrGroups,
 	}, nil
 }
 
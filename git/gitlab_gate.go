@@ -0,0 +1,181 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/logger"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+const approvalRuleName = "Code Secure"
+
+func (g GitLabEnv) SetCommitStatus(option CommitStatusOption) error {
+	opt := &gitlab.SetCommitStatusOptions{
+		State: gitlab.BuildStateValue(option.State),
+		Name:  gitlab.Ptr(option.Name),
+	}
+	if ref := os.Getenv("CI_COMMIT_REF_NAME"); ref != "" {
+		opt.Ref = gitlab.Ptr(ref)
+	}
+	if pipelineID, err := strconv.Atoi(os.Getenv("CI_PIPELINE_ID")); err == nil {
+		opt.PipelineID = gitlab.Ptr(pipelineID)
+	}
+	if option.TargetURL != "" {
+		opt.TargetURL = gitlab.Ptr(option.TargetURL)
+	}
+	if option.Description != "" {
+		opt.Description = gitlab.Ptr(option.Description)
+	}
+	_, _, err := g.client.Commits.SetCommitStatus(g.ProjectID(), g.CommitSha(), opt)
+	if err != nil {
+		logger.Error("Set commit status failed")
+		return err
+	}
+	logger.Info(fmt.Sprintf("Set commit status %s: %s", option.Name, option.State))
+	return nil
+}
+
+// SetMergeRequestBlocked requires an extra approval or applies a label on the merge request depending on GITLAB_MR_GATE
+func (g GitLabEnv) SetMergeRequestBlocked(blocked bool, reason string) error {
+	if g.mrGate == "" || g.MergeRequestID() == "" {
+		return nil
+	}
+	mergeRequestID, err := strconv.Atoi(g.MergeRequestID())
+	if err != nil {
+		return errors.New("merge request id should be a number")
+	}
+	switch g.mrGate {
+	case MergeRequestGateApproval:
+		return g.toggleApprovalRule(mergeRequestID, blocked)
+	case MergeRequestGateLabel:
+		return g.toggleLabel(mergeRequestID, blocked, reason)
+	default:
+		return errors.New("unknown GITLAB_MR_GATE: " + g.mrGate)
+	}
+}
+
+func (g GitLabEnv) toggleApprovalRule(mergeRequestID int, blocked bool) error {
+	projectID := g.ProjectID()
+	rules, _, err := g.client.MergeRequestApprovals.GetApprovalRules(projectID, mergeRequestID)
+	if err != nil {
+		return err
+	}
+	var rule *gitlab.MergeRequestApprovalRule
+	for _, item := range rules {
+		if item.Name == approvalRuleName {
+			rule = item
+			break
+		}
+	}
+	if !blocked {
+		if rule == nil {
+			return nil
+		}
+		_, err = g.client.MergeRequestApprovals.DeleteApprovalRule(projectID, mergeRequestID, rule.ID)
+		if err == nil {
+			logger.Info("Removed security approval from merge request")
+		}
+		return err
+	}
+	userIDs, err := g.approverIDs()
+	if err != nil {
+		return err
+	}
+	groupIDs, err := g.groupIDs()
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		_, _, err = g.client.MergeRequestApprovals.CreateApprovalRule(projectID, mergeRequestID, &gitlab.CreateMergeRequestApprovalRuleOptions{
+			Name:              gitlab.Ptr(approvalRuleName),
+			ApprovalsRequired: gitlab.Ptr(g.mrApprovals),
+			UserIDs:           userIDs,
+			GroupIDs:          groupIDs,
+		})
+		if err == nil {
+			logger.Info("Required security approval on merge request")
+		}
+		return err
+	}
+	_, _, err = g.client.MergeRequestApprovals.UpdateApprovalRule(projectID, mergeRequestID, rule.ID, &gitlab.UpdateMergeRequestApprovalRuleOptions{
+		ApprovalsRequired: gitlab.Ptr(g.mrApprovals),
+		UserIDs:           userIDs,
+		GroupIDs:          groupIDs,
+	})
+	return err
+}
+
+// approverIDs resolves the usernames of GITLAB_MR_GATE_APPROVERS, numbers are user ids
+func (g GitLabEnv) approverIDs() (*[]int, error) {
+	if len(g.mrApprovers) == 0 {
+		return nil, nil
+	}
+	var ids []int
+	for _, approver := range g.mrApprovers {
+		if id, err := strconv.Atoi(approver); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		users, _, err := g.client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(approver)})
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 {
+			return nil, errors.New("unknown approver in GITLAB_MR_GATE_APPROVERS: " + approver)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return &ids, nil
+}
+
+// groupIDs resolves the paths of GITLAB_MR_GATE_GROUPS, numbers are group ids
+func (g GitLabEnv) groupIDs() (*[]int, error) {
+	if len(g.mrGroups) == 0 {
+		return nil, nil
+	}
+	var ids []int
+	for _, group := range g.mrGroups {
+		if id, err := strconv.Atoi(group); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		result, _, err := g.client.Groups.GetGroup(group, nil)
+		if err != nil {
+			return nil, fmt.Errorf("group %s of GITLAB_MR_GATE_GROUPS: %w", group, err)
+		}
+		ids = append(ids, result.ID)
+	}
+	return &ids, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (g GitLabEnv) toggleLabel(mergeRequestID int, blocked bool, reason string) error {
+	opt := &gitlab.UpdateMergeRequestOptions{}
+	labels := gitlab.LabelOptions{g.mrGateLabel}
+	if blocked {
+		opt.AddLabels = &labels
+	} else {
+		opt.RemoveLabels = &labels
+	}
+	_, _, err := g.client.MergeRequests.UpdateMergeRequest(g.ProjectID(), mergeRequestID, opt)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		logger.Info(fmt.Sprintf("Applied label %s on merge request: %s", g.mrGateLabel, reason))
+	}
+	return nil
+}
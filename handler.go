@@ -1,11 +1,13 @@
 package analyzer
 
 import (
+	"errors"
 	"fmt"
 	"github.com/califio/code-secure-analyzer/git"
 	"github.com/califio/code-secure-analyzer/logger"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"os"
+	"strings"
 )
 
 type HandleSastFindingPros struct {
@@ -13,6 +15,13 @@ type HandleSastFindingPros struct {
 	Strategy      ScanStrategy
 	ChangedFiles  []ChangedFile
 	SourceManager git.GitEnv
+	// FindingResult is the triage of Code Secure, set when a remote handler runs earlier in a MultiHandler
+	FindingResult *UploadFindingResponse
+}
+
+// FindingResultProvider is implemented by handlers that triage findings against previous scans
+type FindingResultProvider interface {
+	FindingResult() *UploadFindingResponse
 }
 
 type Handler interface {
@@ -23,19 +32,110 @@ type Handler interface {
 	HandleSCA(sourceManager git.GitEnv, result ScaResult)
 }
 
+// HandlerFactory creates a handler of the handler chain configured by CODE_SECURE_HANDLERS
+type HandlerFactory func() (Handler, error)
+
+var handlerFactories = map[string]HandlerFactory{
+	"remote": func() (Handler, error) {
+		remoteServer := os.Getenv("CODE_SECURE_URL")
+		credentials, err := credentialsFromEnv(remoteServer)
+		if err != nil {
+			return nil, err
+		}
+		if credentials == nil || remoteServer == "" {
+			return nil, errors.New("CODE_SECURE_URL and CODE_SECURE_TOKEN (or an OIDC id token) are required by remote handler")
+		}
+		return newRemoteOrOfflineHandler(remoteServer, credentials)
+	},
+	"local": func() (Handler, error) {
+		return NewLocalHandler(), nil
+	},
+	"gitlab-report": func() (Handler, error) {
+		return NewGitLabReportHandler(nil), nil
+	},
+	"sarif": func() (Handler, error) {
+		return NewSarifHandler(), nil
+	},
+	"junit": func() (Handler, error) {
+		return NewJUnitHandler()
+	},
+	"html": func() (Handler, error) {
+		return NewHTMLHandler(), nil
+	},
+	"markdown": func() (Handler, error) {
+		return NewMarkdownHandler(), nil
+	},
+	"codeclimate": func() (Handler, error) {
+		return NewCodeClimateHandler(), nil
+	},
+	"cyclonedx": func() (Handler, error) {
+		return NewCycloneDXHandler()
+	},
+	"spdx": func() (Handler, error) {
+		return NewSpdxHandler()
+	},
+}
+
+// RegisterHandlerFactory makes a custom handler available to CODE_SECURE_HANDLERS
+func RegisterHandlerFactory(name string, factory HandlerFactory) {
+	handlerFactories[name] = factory
+}
+
+// NewHandlerChain builds a MultiHandler from a comma separated list of handler names (e.g. "remote,sarif,local")
+func NewHandlerChain(names string) (*MultiHandler, error) {
+	multiHandler := NewMultiHandler()
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		factory, ok := handlerFactories[name]
+		if !ok {
+			return nil, errors.New("unknown handler: " + name)
+		}
+		handler, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("%s handler: %w", name, err)
+		}
+		multiHandler.Add(name, handler)
+	}
+	if len(multiHandler.handlers) == 0 {
+		return nil, errors.New("there is no handler in handler chain")
+	}
+	return multiHandler, nil
+}
+
 func GetHandler() Handler {
+	if names := os.Getenv("CODE_SECURE_HANDLERS"); names != "" {
+		handler, err := NewHandlerChain(names)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return handler
+	}
+	var handler Handler
 	// only init handler if there are no handler
-	apiKey := os.Getenv("CODE_SECURE_TOKEN")
 	remoteServer := os.Getenv("CODE_SECURE_URL")
-	if apiKey != "" && remoteServer != "" {
-		handler, err := NewRemoteHandler(remoteServer, apiKey)
+	credentials, err := credentialsFromEnv(remoteServer)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	if credentials != nil && remoteServer != "" {
+		remoteHandler, err := newRemoteOrOfflineHandler(remoteServer, credentials)
 		if err != nil {
 			logger.Error(err.Error())
 			os.Exit(1)
 		}
-		return handler
+		handler = remoteHandler
+	} else {
+		handler = NewLocalHandler()
 	}
-	return NewLocalHandler()
+	if os.Getenv("GITLAB_SECURITY_REPORT") == "true" {
+		return NewMultiHandler(handler, NewGitLabReportHandler(nil))
+	}
+	return handler
 }
 
 func printFindings(findings []SastFinding) {
@@ -50,23 +150,136 @@ func printFindings(findings []SastFinding) {
 	tbl.Render()
 }
 
+func printVulnerabilities(result ScaResult) {
+	graph := NewDependencyGraph(result)
+	tbl := table.NewWriter()
+	tbl.SetOutputMirror(os.Stdout)
+	tbl.SetStyle(table.StyleLight)
+	tbl.Style().Options.SeparateRows = true
+	tbl.AppendHeader(table.Row{"ID", "Advisory", "Severity", "Package", "Fixed Version", "Introduced By", "VEX"})
+	for index, vulnerability := range result.Vulnerabilities {
+		direct := vulnerability.DirectDependencies
+		if direct == nil {
+			direct = graph.DirectDependencies(vulnerability.PkgId)
+		}
+		introducedBy := "direct dependency"
+		if len(direct) > 0 {
+			var names []string
+			for _, pkgId := range direct {
+				names = append(names, graph.Name(pkgId))
+			}
+			introducedBy = strings.Join(names, "\n")
+		}
+		vex := ""
+		if vulnerability.Vex != nil {
+			vex = strings.TrimSuffix(string(vulnerability.Vex.Status)+"\n"+vulnerability.Vex.Justification, "\n")
+		}
+		tbl.AppendRow(table.Row{index + 1, vulnerability.Identity, vulnerability.Severity, graph.Name(vulnerability.PkgId), vulnerability.FixedVersion, introducedBy, vex})
+	}
+	tbl.Render()
+}
+
+func printRemediationPlans(plans []RemediationPlan) {
+	tbl := table.NewWriter()
+	tbl.SetOutputMirror(os.Stdout)
+	tbl.SetStyle(table.StyleLight)
+	tbl.Style().Options.SeparateRows = true
+	tbl.AppendHeader(table.Row{"Location", "Package", "Current Version", "Upgrade To", "Fixes", "Introduced By"})
+	for _, plan := range plans {
+		for _, remediation := range plan.Remediations {
+			target := remediation.TargetVersion
+			switch {
+			case target == "":
+				target = "no fix available"
+			case remediation.Breaking:
+				target += " (breaking)"
+			}
+			fixes := strings.Join(remediation.Fixes, "\n")
+			if len(remediation.Unfixed) > 0 {
+				fixes = strings.TrimPrefix(fixes+"\nunfixed: "+strings.Join(remediation.Unfixed, ", "), "\n")
+			}
+			tbl.AppendRow(table.Row{plan.Location, remediation.Package, remediation.CurrentVersion, target, fixes, strings.Join(remediation.DirectDependencies, "\n")})
+		}
+	}
+	tbl.Render()
+}
+
+func printLicenseViolations(violations []LicenseViolation) {
+	tbl := table.NewWriter()
+	tbl.SetOutputMirror(os.Stdout)
+	tbl.SetStyle(table.StyleLight)
+	tbl.Style().Options.SeparateRows = true
+	tbl.AppendHeader(table.Row{"ID", "Package", "License", "Action", "Introduced By"})
+	for index, violation := range violations {
+		var paths []string
+		for _, path := range violation.Paths {
+			paths = append(paths, strings.Join(path, " > "))
+		}
+		pkg := violation.Package
+		tbl.AppendRow(table.Row{index + 1, packageName(pkg) + "@" + pkg.Version, violation.License, violation.Action, strings.Join(paths, "\n")})
+	}
+	tbl.Render()
+}
+
 // default handler
 
 // local handler
 
-type LocalHandler struct{}
+type LocalHandler struct {
+	reporter    *sourceReporter
+	deferReport bool
+	policy      *Policy
+	isBlock     bool
+	deferExit   bool
+}
 
 func NewLocalHandler() *LocalHandler {
 	return &LocalHandler{}
 }
 
 func (handler *LocalHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	policy, err := policyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	handler.start(sourceManager, scannerName, policy)
 	return &CiScanInfo{}, nil
 }
+
+func (handler *LocalHandler) start(sourceManager git.GitEnv, scannerName string, policy *Policy) {
+	handler.policy = policy
+	if !handler.deferReport && sourceManager != nil {
+		handler.reporter = newSourceReporter(sourceManager, scannerName, sourceManager.JobURL())
+	}
+}
 func (handler *LocalHandler) OnCompleted() {
+	handler.reporter.complete(handler.isBlock)
 	logger.Info("scan completed")
+	if handler.isBlock && !handler.deferExit {
+		logger.Info("block due local policy")
+		os.Exit(1)
+	}
+}
+
+func (handler *LocalHandler) IsBlock() bool {
+	return handler.isBlock
+}
+
+func (handler *LocalHandler) DeferExit() {
+	handler.deferExit = true
 }
+
+// Required fails a handler chain when the local policy cannot be loaded
+func (handler *LocalHandler) Required() bool {
+	return true
+}
+
+func (handler *LocalHandler) deferSourceReport() {
+	handler.deferReport = true
+}
+
 func (handler *LocalHandler) OnError(err error) {
+	handler.reporter.fail(err)
 	logger.Error(err.Error())
 }
 
@@ -74,14 +287,35 @@ func (handler *LocalHandler) HandleSastFindings(input HandleSastFindingPros) {
 	if input.SourceManager == nil {
 		logger.Warn("there is no source manager (GitLab, GitHub, vv)")
 	}
+	handler.reporter.handleSastFindings(input)
 	if len(input.Result.Findings) > 0 {
 		logger.Warn(fmt.Sprintf("there are %d new findings", len(input.Result.Findings)))
 		printFindings(input.Result.Findings)
 	} else {
 		logger.Info("there are no new findings")
 	}
+	decision := handler.policy.EvaluateSast(input.Result.Findings, input.ChangedFiles, newFindingFilter(input.FindingResult))
+	reportPolicyDecision(decision)
+	handler.isBlock = handler.policy.IsBlock(false, decision)
 }
 
 func (handler *LocalHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
-	//todo: write something here
+	handler.reporter.handleSCA(result)
+	if len(result.Vulnerabilities) > 0 {
+		logger.Warn(fmt.Sprintf("there are %d vulnerabilities", len(result.Vulnerabilities)))
+		printVulnerabilities(result)
+		if len(result.Remediations) > 0 {
+			logger.Info("remediation plan")
+			printRemediationPlans(result.Remediations)
+		}
+	} else {
+		logger.Info("there are no vulnerabilities")
+	}
+	if len(result.LicenseViolations) > 0 {
+		logger.Warn(fmt.Sprintf("there are %d license violations", len(result.LicenseViolations)))
+		printLicenseViolations(result.LicenseViolations)
+	}
+	decision := handler.policy.EvaluateSca(result)
+	reportPolicyDecision(decision)
+	handler.isBlock = hasDeniedLicense(result.LicenseViolations) || handler.policy.IsBlock(false, decision)
 }
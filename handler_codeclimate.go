@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+type CodeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Severity    string              `json:"severity"`
+	Fingerprint string              `json:"fingerprint"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+type codeClimateLocation struct {
+	Path  string           `json:"path"`
+	Lines codeClimateLines `json:"lines"`
+}
+
+type codeClimateLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+// CodeClimateHandler writes findings in the CodeClimate format used by the GitLab code quality widget
+type CodeClimateHandler struct {
+	output string
+}
+
+func NewCodeClimateHandler() *CodeClimateHandler {
+	output := os.Getenv("CODECLIMATE_OUTPUT")
+	if output == "" {
+		output = "gl-code-quality-report.json"
+	}
+	return &CodeClimateHandler{output: output}
+}
+
+func (handler *CodeClimateHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	return &CiScanInfo{}, nil
+}
+
+func (handler *CodeClimateHandler) OnCompleted() {}
+
+func (handler *CodeClimateHandler) OnError(err error) {}
+
+func (handler *CodeClimateHandler) HandleSastFindings(input HandleSastFindingPros) {
+	handler.write(NewCodeClimateSastIssues(input.Result.Findings))
+}
+
+func (handler *CodeClimateHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	handler.write(NewCodeClimateDependencyIssues(result))
+}
+
+func (handler *CodeClimateHandler) write(issues []CodeClimateIssue) {
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("Save code climate report to: " + handler.output)
+	err = os.WriteFile(handler.output, data, 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func NewCodeClimateSastIssues(findings []SastFinding) []CodeClimateIssue {
+	issues := []CodeClimateIssue{}
+	for index, finding := range findings {
+		issue := CodeClimateIssue{
+			Type:        "issue",
+			CheckName:   findingRule(finding),
+			Description: finding.Name,
+			Categories:  []string{"Security"},
+			Severity:    codeClimateSeverity(finding.Severity),
+		}
+		fingerprint := finding.Identity
+		if finding.Location != nil {
+			issue.Location = codeClimateLocation{
+				Path: finding.Location.Path,
+				Lines: codeClimateLines{
+					Begin: max(finding.Location.StartLine, 1),
+					End:   finding.Location.EndLine,
+				},
+			}
+			if fingerprint == "" {
+				fingerprint = strings.Join([]string{issue.CheckName, finding.Location.Path, finding.Location.Snippet}, "|")
+			}
+		}
+		// without identity nor location, the description and the index keep distinct findings apart
+		if fingerprint == "" {
+			fingerprint = strings.Join([]string{issue.CheckName, finding.Name, finding.Description, strconv.Itoa(index)}, "|")
+		}
+		issue.Fingerprint = codeClimateFingerprint(fingerprint)
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+func NewCodeClimateDependencyIssues(result ScaResult) []CodeClimateIssue {
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	issues := []CodeClimateIssue{}
+	for _, vulnerability := range result.Vulnerabilities {
+		pkg, ok := packages[vulnerability.PkgId]
+		if !ok {
+			pkg = Package{PkgId: vulnerability.PkgId, Name: vulnerability.PkgName}
+		}
+		description := fmt.Sprintf("%s in %s@%s", vulnerability.Identity, packageName(pkg), pkg.Version)
+		if vulnerability.FixedVersion != "" {
+			description += ", fixed in " + vulnerability.FixedVersion
+		}
+		issue := CodeClimateIssue{
+			Type:        "issue",
+			CheckName:   vulnerability.Identity,
+			Description: description,
+			Categories:  []string{"Security"},
+			Severity:    codeClimateSeverity(vulnerability.Severity),
+			Fingerprint: codeClimateFingerprint(strings.Join([]string{vulnerability.Identity, packageName(pkg), pkg.Version}, "|")),
+			Location:    codeClimateLocation{Lines: codeClimateLines{Begin: 1}},
+		}
+		if pkg.Location != nil {
+			issue.Location.Path = *pkg.Location
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+func codeClimateSeverity(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "blocker"
+	case SeverityHigh:
+		return "critical"
+	case SeverityMedium:
+		return "major"
+	case SeverityLow:
+		return "minor"
+	default:
+		return "info"
+	}
+}
+
+func codeClimateFingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,362 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+const (
+	gitLabReportSchemaVersion = "15.0.7"
+	gitLabReportTimeFormat    = "2006-01-02T15:04:05"
+	AnalyzerName              = "code-secure-analyzer"
+)
+
+type GitLabReport struct {
+	Version         string                `json:"version"`
+	Scan            gitLabScan            `json:"scan"`
+	Vulnerabilities []GitLabVulnerability `json:"vulnerabilities"`
+}
+
+type gitLabScan struct {
+	Analyzer  gitLabScanTool `json:"analyzer"`
+	Scanner   gitLabScanTool `json:"scanner"`
+	Type      string         `json:"type"`
+	StartTime string         `json:"start_time"`
+	EndTime   string         `json:"end_time"`
+	Status    string         `json:"status"`
+}
+
+type gitLabScanTool struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Vendor  gitLabVendor `json:"vendor"`
+}
+
+type gitLabVendor struct {
+	Name string `json:"name"`
+}
+
+type GitLabVulnerability struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Severity    string             `json:"severity"`
+	Solution    string             `json:"solution,omitempty"`
+	Identifiers []gitLabIdentifier `json:"identifiers"`
+	Links       []gitLabLink       `json:"links,omitempty"`
+	Location    gitLabLocation     `json:"location"`
+}
+
+type gitLabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	URL   string `json:"url,omitempty"`
+}
+
+type gitLabLink struct {
+	URL string `json:"url"`
+}
+
+type gitLabLocation struct {
+	File       string            `json:"file"`
+	StartLine  int               `json:"start_line,omitempty"`
+	EndLine    int               `json:"end_line,omitempty"`
+	Commit     *gitLabCommit     `json:"commit,omitempty"`
+	Dependency *gitLabDependency `json:"dependency,omitempty"`
+}
+
+type gitLabCommit struct {
+	Sha string `json:"sha"`
+}
+
+type gitLabDependency struct {
+	Package struct {
+		Name string `json:"name"`
+	} `json:"package"`
+	Version string `json:"version"`
+}
+
+// GitLabReportHandler writes GitLab security report artifacts and forwards every event to the next handler
+type GitLabReportHandler struct {
+	next        Handler
+	outputDir   string
+	scannerName string
+	scannerType ScannerType
+	startTime   time.Time
+}
+
+func NewGitLabReportHandler(next Handler) *GitLabReportHandler {
+	outputDir := os.Getenv("GITLAB_REPORT_DIR")
+	if outputDir == "" {
+		outputDir = "."
+	}
+	return &GitLabReportHandler{next: next, outputDir: outputDir}
+}
+
+func (handler *GitLabReportHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	handler.scannerName = scannerName
+	handler.scannerType = scannerType
+	handler.startTime = time.Now()
+	if handler.next != nil {
+		return handler.next.OnStart(sourceManager, scannerName, scannerType)
+	}
+	return &CiScanInfo{}, nil
+}
+
+func (handler *GitLabReportHandler) OnCompleted() {
+	if handler.next != nil {
+		handler.next.OnCompleted()
+	}
+}
+
+func (handler *GitLabReportHandler) OnError(err error) {
+	if handler.next != nil {
+		handler.next.OnError(err)
+	}
+}
+
+func (handler *GitLabReportHandler) HandleSastFindings(input HandleSastFindingPros) {
+	reportType := "sast"
+	commitSha := ""
+	if handler.scannerType == ScannerTypeSecretDetection {
+		reportType = "secret_detection"
+		if input.SourceManager != nil {
+			commitSha = input.SourceManager.CommitSha()
+		}
+	}
+	report := handler.newReport(reportType)
+	report.Vulnerabilities = NewGitLabSastVulnerabilities(input.Result.Findings, commitSha)
+	handler.write(report)
+	if handler.next != nil {
+		handler.next.HandleSastFindings(input)
+	}
+}
+
+func (handler *GitLabReportHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	report := handler.newReport("dependency_scanning")
+	report.Vulnerabilities = NewGitLabDependencyVulnerabilities(result)
+	handler.write(report)
+	if handler.next != nil {
+		handler.next.HandleSCA(sourceManager, result)
+	}
+}
+
+func (handler *GitLabReportHandler) newReport(reportType string) GitLabReport {
+	return GitLabReport{
+		Version: gitLabReportSchemaVersion,
+		Scan: gitLabScan{
+			Analyzer: gitLabScanTool{
+				ID:      AnalyzerName,
+				Name:    "Code Secure Analyzer",
+				Version: analyzerVersion(),
+				Vendor:  gitLabVendor{Name: "Code Secure"},
+			},
+			Scanner: gitLabScanTool{
+				ID:      handler.scannerName,
+				Name:    handler.scannerName,
+				Version: analyzerVersion(),
+				Vendor:  gitLabVendor{Name: "Code Secure"},
+			},
+			Type:      reportType,
+			StartTime: handler.startTime.UTC().Format(gitLabReportTimeFormat),
+			EndTime:   time.Now().UTC().Format(gitLabReportTimeFormat),
+			Status:    "success",
+		},
+	}
+}
+
+func (handler *GitLabReportHandler) write(report GitLabReport) {
+	output := filepath.Join(handler.outputDir, fmt.Sprintf("gl-%s-report.json", strings.ReplaceAll(report.Scan.Type, "_", "-")))
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("Save GitLab security report to: " + output)
+	err = os.WriteFile(output, data, 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func NewGitLabSastVulnerabilities(findings []SastFinding, commitSha string) []GitLabVulnerability {
+	vulnerabilities := []GitLabVulnerability{}
+	for _, finding := range findings {
+		vulnerability := GitLabVulnerability{
+			Name:        truncate(finding.Name, 255),
+			Description: finding.Description,
+			Severity:    gitLabSeverity(finding.Severity),
+			Solution:    truncate(finding.Recommendation, 7000),
+			Identifiers: []gitLabIdentifier{},
+		}
+		ruleID := finding.RuleID
+		if ruleID == "" {
+			ruleID = finding.Identity
+		}
+		if ruleID == "" {
+			ruleID = finding.Name
+		}
+		// GitLab requires at least one identifier
+		vulnerability.Identifiers = append(vulnerability.Identifiers, gitLabIdentifier{
+			Type:  "code_secure_rule_id",
+			Name:  ruleID,
+			Value: ruleID,
+		})
+		if finding.Metadata != nil {
+			vulnerability.Identifiers = append(vulnerability.Identifiers, cweIdentifiers(finding.Metadata.Cwes)...)
+			vulnerability.Links = gitLabLinks(finding.Metadata.References)
+		}
+		if finding.Location != nil {
+			vulnerability.Location = gitLabLocation{
+				File:      finding.Location.Path,
+				StartLine: finding.Location.StartLine,
+				EndLine:   max(finding.Location.EndLine, finding.Location.StartLine),
+			}
+		}
+		if commitSha != "" {
+			vulnerability.Location.Commit = &gitLabCommit{Sha: commitSha}
+		}
+		vulnerability.ID = gitLabVulnerabilityID(ruleID, finding.Identity, vulnerability.Location.File, fmt.Sprint(vulnerability.Location.StartLine))
+		vulnerabilities = append(vulnerabilities, vulnerability)
+	}
+	return vulnerabilities
+}
+
+func NewGitLabDependencyVulnerabilities(result ScaResult) []GitLabVulnerability {
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	vulnerabilities := []GitLabVulnerability{}
+	for _, vuln := range result.Vulnerabilities {
+		pkg, ok := packages[vuln.PkgId]
+		if !ok {
+			pkg = Package{PkgId: vuln.PkgId, Name: vuln.PkgName}
+		}
+		vulnerability := GitLabVulnerability{
+			Name:        truncate(vuln.Name, 255),
+			Description: vuln.Description,
+			Severity:    gitLabSeverity(vuln.Severity),
+			Identifiers: []gitLabIdentifier{advisoryIdentifier(vuln.Identity)},
+		}
+		if vuln.FixedVersion != "" {
+			vulnerability.Solution = fmt.Sprintf("Upgrade %s to version %s or above", packageName(pkg), vuln.FixedVersion)
+		}
+		if vuln.Metadata != nil {
+			vulnerability.Identifiers = append(vulnerability.Identifiers, cweIdentifiers(vuln.Metadata.Cwes)...)
+			vulnerability.Links = gitLabLinks(vuln.Metadata.References)
+		}
+		// GitLab attaches dependency vulnerabilities to the manifest file
+		if pkg.Location == nil || *pkg.Location == "" {
+			logger.Warn(fmt.Sprintf("skip %s in GitLab report because %s has no location", vuln.Identity, packageName(pkg)))
+			continue
+		}
+		dependency := &gitLabDependency{Version: pkg.Version}
+		dependency.Package.Name = packageName(pkg)
+		vulnerability.Location = gitLabLocation{File: *pkg.Location, Dependency: dependency}
+		vulnerability.ID = gitLabVulnerabilityID(vuln.Identity, vulnerability.Location.File, dependency.Package.Name, pkg.Version)
+		vulnerabilities = append(vulnerabilities, vulnerability)
+	}
+	return vulnerabilities
+}
+
+// packageName joins group and name the way the ecosystem displays them (maven group:artifact, npm @scope/name)
+func packageName(pkg Package) string {
+	if pkg.Group == "" {
+		if pkg.Name == "" {
+			return pkg.PkgId
+		}
+		return pkg.Name
+	}
+	if strings.HasPrefix(pkg.Group, "@") {
+		return pkg.Group + "/" + pkg.Name
+	}
+	return pkg.Group + ":" + pkg.Name
+}
+
+func advisoryIdentifier(identity string) gitLabIdentifier {
+	upper := strings.ToUpper(identity)
+	switch {
+	case strings.HasPrefix(upper, "CVE-"):
+		return gitLabIdentifier{Type: "cve", Name: identity, Value: identity, URL: "https://nvd.nist.gov/vuln/detail/" + identity}
+	case strings.HasPrefix(upper, "GHSA-"):
+		return gitLabIdentifier{Type: "ghsa", Name: identity, Value: identity, URL: "https://github.com/advisories/" + identity}
+	default:
+		return gitLabIdentifier{Type: "code_secure_advisory", Name: identity, Value: identity}
+	}
+}
+
+func cweIdentifiers(cwes []string) []gitLabIdentifier {
+	var identifiers []gitLabIdentifier
+	for _, cwe := range cwes {
+		name, url := CweURL(cwe)
+		if name == "" {
+			continue
+		}
+		identifiers = append(identifiers, gitLabIdentifier{
+			Type:  "cwe",
+			Name:  name,
+			Value: strings.TrimPrefix(name, "CWE-"),
+			URL:   url,
+		})
+	}
+	return identifiers
+}
+
+func gitLabLinks(references []string) []gitLabLink {
+	var links []gitLabLink
+	for _, reference := range references {
+		if strings.HasPrefix(reference, "http://") || strings.HasPrefix(reference, "https://") {
+			links = append(links, gitLabLink{URL: reference})
+		}
+	}
+	return links
+}
+
+func gitLabSeverity(severity Severity) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo:
+		return string(severity)
+	default:
+		return "Unknown"
+	}
+}
+
+// gitLabVulnerabilityID derives a stable uuid-like id so GitLab can track a vulnerability across pipelines
+func gitLabVulnerabilityID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	id := hex.EncodeToString(sum[:16])
+	return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32])
+}
+
+func analyzerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/califio/code-secure-analyzer" && dep.Version != "" {
+				return dep.Version
+			}
+		}
+	}
+	return "dev"
+}
+
+func truncate(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,227 @@
+package analyzer
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+//go:embed assets/report.html
+var htmlReportTemplate string
+
+type htmlReport struct {
+	Repo            string
+	Branch          string
+	Commit          string
+	Scanner         string
+	GeneratedAt     string
+	Summary         []htmlSeverityCount
+	HasSast         bool
+	HasSca          bool
+	Findings        []htmlFinding
+	Vulnerabilities []htmlVulnerability
+}
+
+type htmlSeverityCount struct {
+	Severity Severity
+	Count    int
+}
+
+type htmlLink struct {
+	Name string
+	URL  string
+}
+
+type htmlLocation struct {
+	Location string
+	Snippet  string
+	URL      string
+}
+
+type htmlFinding struct {
+	Name           string
+	Rule           string
+	Severity       Severity
+	Path           string
+	Location       string
+	URL            string
+	Snippet        string
+	Description    string
+	Recommendation string
+	Cwes           []htmlLink
+	Flow           []htmlLocation
+}
+
+type htmlVulnerability struct {
+	Identity     string
+	Name         string
+	Severity     Severity
+	Package      string
+	Version      string
+	Path         string
+	URL          string
+	FixedVersion string
+	Description  string
+	Cwes         []htmlLink
+	References   []string
+	Paths        [][]string
+}
+
+// HTMLHandler renders findings to a self-contained html report which works offline
+type HTMLHandler struct {
+	output        string
+	scannerName   string
+	sourceManager git.GitEnv
+	report        htmlReport
+}
+
+func NewHTMLHandler() *HTMLHandler {
+	output := os.Getenv("HTML_OUTPUT")
+	if output == "" {
+		output = "code-secure-report.html"
+	}
+	return &HTMLHandler{output: output}
+}
+
+func (handler *HTMLHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	handler.scannerName = scannerName
+	handler.sourceManager = sourceManager
+	handler.report = htmlReport{Scanner: scannerName}
+	if sourceManager != nil {
+		handler.report.Repo = sourceManager.ProjectURL()
+		handler.report.Branch = sourceManager.CommitBranch()
+		handler.report.Commit = sourceManager.CommitSha()
+	}
+	return &CiScanInfo{}, nil
+}
+
+func (handler *HTMLHandler) OnCompleted() {}
+
+func (handler *HTMLHandler) OnError(err error) {}
+
+func (handler *HTMLHandler) HandleSastFindings(input HandleSastFindingPros) {
+	handler.report.HasSast = true
+	handler.report.Findings = nil
+	for _, finding := range input.Result.Findings {
+		item := htmlFinding{
+			Name:           finding.Name,
+			Rule:           findingRule(finding),
+			Severity:       finding.Severity,
+			Description:    finding.Description,
+			Recommendation: finding.Recommendation,
+		}
+		if finding.Location != nil {
+			location := handler.location(*finding.Location)
+			item.Path = finding.Location.Path
+			item.Location = location.Location
+			item.URL = location.URL
+			item.Snippet = location.Snippet
+		}
+		if finding.Metadata != nil {
+			item.Cwes = cweLinks(finding.Metadata.Cwes)
+			for _, step := range finding.Metadata.FindingFlow {
+				item.Flow = append(item.Flow, handler.location(step))
+			}
+		}
+		handler.report.Findings = append(handler.report.Findings, item)
+	}
+	sort.SliceStable(handler.report.Findings, func(i, j int) bool {
+		return handler.report.Findings[i].Severity.Rank() > handler.report.Findings[j].Severity.Rank()
+	})
+	handler.write()
+}
+
+func (handler *HTMLHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	handler.report.HasSca = true
+	handler.report.Vulnerabilities = nil
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	graph := NewDependencyGraph(result)
+	for _, vulnerability := range result.Vulnerabilities {
+		pkg, ok := packages[vulnerability.PkgId]
+		if !ok {
+			pkg = Package{PkgId: vulnerability.PkgId, Name: vulnerability.PkgName}
+		}
+		item := htmlVulnerability{
+			Identity:     vulnerability.Identity,
+			Name:         vulnerability.Name,
+			Severity:     vulnerability.Severity,
+			Package:      packageName(pkg),
+			Version:      pkg.Version,
+			FixedVersion: vulnerability.FixedVersion,
+			Description:  vulnerability.Description,
+			Paths:        graph.PathNames(vulnerability.PkgId),
+		}
+		if pkg.Location != nil {
+			item.Path = *pkg.Location
+			item.URL = handler.location(FindingLocation{Path: *pkg.Location}).URL
+		}
+		if vulnerability.Metadata != nil {
+			item.Cwes = cweLinks(vulnerability.Metadata.Cwes)
+			item.References = vulnerability.Metadata.References
+		}
+		handler.report.Vulnerabilities = append(handler.report.Vulnerabilities, item)
+	}
+	sort.SliceStable(handler.report.Vulnerabilities, func(i, j int) bool {
+		return handler.report.Vulnerabilities[i].Severity.Rank() > handler.report.Vulnerabilities[j].Severity.Rank()
+	})
+	handler.write()
+}
+
+func (handler *HTMLHandler) location(location FindingLocation) htmlLocation {
+	result := htmlLocation{Location: location.String(), Snippet: location.Snippet}
+	if handler.sourceManager != nil && handler.sourceManager.CommitSha() != "" && location.Path != "" {
+		result.URL = LocationURL(handler.sourceManager, location)
+	}
+	return result
+}
+
+func (handler *HTMLHandler) write() {
+	counts := make(map[Severity]int)
+	for _, finding := range handler.report.Findings {
+		counts[finding.Severity]++
+	}
+	for _, vulnerability := range handler.report.Vulnerabilities {
+		counts[vulnerability.Severity]++
+	}
+	handler.report.Summary = nil
+	for _, severity := range []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo} {
+		handler.report.Summary = append(handler.report.Summary, htmlSeverityCount{Severity: severity, Count: counts[severity]})
+	}
+	handler.report.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	tmpl, err := template.New("report").Parse(htmlReportTemplate)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, handler.report)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("Save html report to: " + handler.output)
+	err = os.WriteFile(handler.output, buffer.Bytes(), 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func cweLinks(cwes []string) []htmlLink {
+	var links []htmlLink
+	for _, cwe := range cwes {
+		name, url := CweURL(cwe)
+		if name != "" {
+			links = append(links, htmlLink{Name: name, URL: url})
+		}
+	}
+	return links
+}
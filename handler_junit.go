@@ -0,0 +1,236 @@
+package analyzer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitHandler writes findings as JUnit test cases so CI test dashboards can display them.
+// Findings below the severity threshold and fixed findings are reported as passing test cases
+type JUnitHandler struct {
+	output      string
+	threshold   Severity
+	withFixed   bool
+	scannerName string
+}
+
+func NewJUnitHandler() (*JUnitHandler, error) {
+	output := os.Getenv("JUNIT_OUTPUT")
+	if output == "" {
+		output = "code-secure-junit.xml"
+	}
+	threshold := SeverityInfo
+	if value := os.Getenv("JUNIT_SEVERITY_THRESHOLD"); value != "" {
+		severity, ok := ParseSeverity(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid JUNIT_SEVERITY_THRESHOLD: %s", value)
+		}
+		threshold = severity
+	}
+	return &JUnitHandler{
+		output:    output,
+		threshold: threshold,
+		withFixed: os.Getenv("JUNIT_FIXED_FINDINGS") == "true",
+	}, nil
+}
+
+func (handler *JUnitHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	handler.scannerName = scannerName
+	return &CiScanInfo{}, nil
+}
+
+func (handler *JUnitHandler) OnCompleted() {}
+
+func (handler *JUnitHandler) OnError(err error) {}
+
+func (handler *JUnitHandler) HandleSastFindings(input HandleSastFindingPros) {
+	suite := junitTestSuite{Name: handler.scannerName}
+	for _, finding := range input.Result.Findings {
+		suite.TestCases = append(suite.TestCases, handler.sastTestCase(finding))
+	}
+	if handler.withFixed && input.FindingResult != nil {
+		for _, finding := range input.FindingResult.FixedFindings {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      finding.Name + " @ " + findingLocation(finding),
+				ClassName: findingRule(finding),
+				File:      findingPath(finding),
+				SystemOut: "Fixed",
+			})
+		}
+	}
+	handler.write(suite)
+}
+
+func (handler *JUnitHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	suite := junitTestSuite{Name: handler.scannerName}
+	for _, vulnerability := range result.Vulnerabilities {
+		pkg, ok := packages[vulnerability.PkgId]
+		if !ok {
+			pkg = Package{PkgId: vulnerability.PkgId, Name: vulnerability.PkgName}
+		}
+		location := ""
+		if pkg.Location != nil {
+			location = *pkg.Location
+		}
+		testCase := junitTestCase{
+			Name:      fmt.Sprintf("%s in %s@%s", vulnerability.Identity, packageName(pkg), pkg.Version),
+			ClassName: packageName(pkg),
+			File:      location,
+		}
+		if vulnerability.IsSuppressed() {
+			// not affected or fixed by VEX, reported without failing the pipeline
+			testCase.Skipped = &junitSkipped{Message: "VEX: " + vexDescription(*vulnerability.Vex)}
+		} else if handler.fails(vulnerability.Severity) {
+			text := fmt.Sprintf("Severity: %s\nPackage: %s@%s\n", vulnerability.Severity, packageName(pkg), pkg.Version)
+			if location != "" {
+				text += "Location: " + location + "\n"
+			}
+			if vulnerability.FixedVersion != "" {
+				text += "Fixed Version: " + vulnerability.FixedVersion + "\n"
+			}
+			text += "\n" + vulnerability.Description
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("[%s] %s", vulnerability.Severity, vulnerability.Name),
+				Type:    string(vulnerability.Severity),
+				Text:    text,
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	handler.write(suite)
+}
+
+// fails reports a severity at or above the threshold. An unknown severity fails as it cannot be ranked
+func (handler *JUnitHandler) fails(severity Severity) bool {
+	return severity.Rank() == 0 || severity.Rank() >= handler.threshold.Rank()
+}
+
+func (handler *JUnitHandler) sastTestCase(finding SastFinding) junitTestCase {
+	testCase := junitTestCase{
+		Name:      finding.Name + " @ " + findingLocation(finding),
+		ClassName: findingRule(finding),
+		File:      findingPath(finding),
+	}
+	if !handler.fails(finding.Severity) {
+		return testCase
+	}
+	text := fmt.Sprintf("Severity: %s\nLocation: %s\n", finding.Severity, findingLocation(finding))
+	if finding.Description != "" {
+		text += "\nDescription:\n" + finding.Description + "\n"
+	}
+	if finding.Recommendation != "" {
+		text += "\nRecommendation:\n" + finding.Recommendation + "\n"
+	}
+	testCase.Failure = &junitFailure{
+		Message: fmt.Sprintf("[%s] %s", finding.Severity, finding.Name),
+		Type:    string(finding.Severity),
+		Text:    text,
+	}
+	return testCase
+}
+
+func (handler *JUnitHandler) write(suite junitTestSuite) {
+	// group test cases by rule then file
+	sort.SliceStable(suite.TestCases, func(i, j int) bool {
+		if suite.TestCases[i].ClassName != suite.TestCases[j].ClassName {
+			return suite.TestCases[i].ClassName < suite.TestCases[j].ClassName
+		}
+		return suite.TestCases[i].File < suite.TestCases[j].File
+	})
+	suite.Tests = len(suite.TestCases)
+	for _, testCase := range suite.TestCases {
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+		if testCase.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+	suites := junitTestSuites{
+		Name:     AnalyzerName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Suites:   []junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("Save junit report to: " + handler.output)
+	err = os.WriteFile(handler.output, append([]byte(xml.Header), data...), 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func findingRule(finding SastFinding) string {
+	if finding.RuleID != "" {
+		return finding.RuleID
+	}
+	if finding.Identity != "" {
+		return finding.Identity
+	}
+	return finding.Name
+}
+
+func findingPath(finding SastFinding) string {
+	if finding.Location == nil {
+		return ""
+	}
+	return finding.Location.Path
+}
+
+func findingLocation(finding SastFinding) string {
+	if finding.Location == nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(finding.Location.String())
+}
@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// MarkdownHandler writes a markdown report with a summary table and a section per finding
+type MarkdownHandler struct {
+	output        string
+	scannerName   string
+	sourceManager git.GitEnv
+}
+
+func NewMarkdownHandler() *MarkdownHandler {
+	output := os.Getenv("MARKDOWN_OUTPUT")
+	if output == "" {
+		output = "code-secure-report.md"
+	}
+	return &MarkdownHandler{output: output}
+}
+
+func (handler *MarkdownHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	handler.scannerName = scannerName
+	handler.sourceManager = sourceManager
+	return &CiScanInfo{}, nil
+}
+
+func (handler *MarkdownHandler) OnCompleted() {}
+
+func (handler *MarkdownHandler) OnError(err error) {}
+
+func (handler *MarkdownHandler) HandleSastFindings(input HandleSastFindingPros) {
+	var builder strings.Builder
+	handler.writeHeader(&builder)
+	var severities []Severity
+	for _, finding := range input.Result.Findings {
+		severities = append(severities, finding.Severity)
+	}
+	writeSeveritySummary(&builder, severities)
+	builder.WriteString("\n## Findings\n")
+	if len(input.Result.Findings) == 0 {
+		builder.WriteString("\nThere are no findings\n")
+	}
+	for index, finding := range input.Result.Findings {
+		builder.WriteString(fmt.Sprintf("\n### %d. [%s] %s\n\n", index+1, finding.Severity, findingRule(finding)))
+		builder.WriteString(FindingMarkdown(handler.sourceManager, finding, ""))
+		builder.WriteString("\n")
+	}
+	handler.write(builder.String())
+}
+
+func (handler *MarkdownHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	var builder strings.Builder
+	handler.writeHeader(&builder)
+	var severities []Severity
+	for _, vulnerability := range result.Vulnerabilities {
+		severities = append(severities, vulnerability.Severity)
+	}
+	writeSeveritySummary(&builder, severities)
+	builder.WriteString("\n## Vulnerable Dependencies\n\n")
+	if len(result.Vulnerabilities) == 0 {
+		builder.WriteString("There are no vulnerable dependencies\n")
+		handler.write(builder.String())
+		return
+	}
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	builder.WriteString("| Severity | Advisory | Package | Version | Fixed Version | Location |\n")
+	builder.WriteString("|---|---|---|---|---|---|\n")
+	for _, vulnerability := range result.Vulnerabilities {
+		pkg, ok := packages[vulnerability.PkgId]
+		if !ok {
+			pkg = Package{PkgId: vulnerability.PkgId, Name: vulnerability.PkgName}
+		}
+		location := ""
+		if pkg.Location != nil {
+			location = markdownLocation(handler.sourceManager, FindingLocation{Path: *pkg.Location})
+		}
+		builder.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+			vulnerability.Severity,
+			escapeMarkdownCell(vulnerability.Identity),
+			escapeMarkdownCell(packageName(pkg)),
+			escapeMarkdownCell(pkg.Version),
+			escapeMarkdownCell(vulnerability.FixedVersion),
+			escapeMarkdownCell(location),
+		))
+	}
+	handler.write(builder.String())
+}
+
+func (handler *MarkdownHandler) writeHeader(builder *strings.Builder) {
+	builder.WriteString("# Code Secure Report\n\n")
+	builder.WriteString("| | |\n|---|---|\n")
+	if handler.sourceManager != nil {
+		builder.WriteString(fmt.Sprintf("| Repo | %s |\n", handler.sourceManager.ProjectURL()))
+		if handler.sourceManager.CommitBranch() != "" {
+			builder.WriteString(fmt.Sprintf("| Branch | %s |\n", handler.sourceManager.CommitBranch()))
+		}
+		builder.WriteString(fmt.Sprintf("| Commit | %s |\n", handler.sourceManager.CommitSha()))
+	}
+	builder.WriteString(fmt.Sprintf("| Scanner | %s |\n", handler.scannerName))
+}
+
+func (handler *MarkdownHandler) write(content string) {
+	logger.Info("Save markdown report to: " + handler.output)
+	err := os.WriteFile(handler.output, []byte(content), 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func writeSeveritySummary(builder *strings.Builder, severities []Severity) {
+	counts := make(map[Severity]int)
+	for _, severity := range severities {
+		counts[severity]++
+	}
+	builder.WriteString("\n## Summary\n\n| Severity | Count |\n|---|---|\n")
+	for _, severity := range []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo} {
+		builder.WriteString(fmt.Sprintf("| %s | %d |\n", severity, counts[severity]))
+	}
+	builder.WriteString(fmt.Sprintf("| **Total** | **%d** |\n", len(severities)))
+}
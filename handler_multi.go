@@ -0,0 +1,169 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// BlockingHandler is implemented by handlers that can block the pipeline.
+// DeferExit leaves exiting the process to the caller which aggregates the decision of every handler
+type BlockingHandler interface {
+	IsBlock() bool
+	DeferExit()
+}
+
+// RequiredHandler is implemented by handlers whose failure to start fails the whole chain
+type RequiredHandler interface {
+	Required() bool
+}
+
+// sourceReportingHandler is implemented by handlers that report to the source manager (check run, commit status,
+// merge request gate and SARIF upload). A MultiHandler reports once for the chain with the aggregated decision
+type sourceReportingHandler interface {
+	deferSourceReport()
+}
+
+type namedHandler struct {
+	name    string
+	handler Handler
+	started bool
+}
+
+// MultiHandler fans out every event to several handlers (sinks).
+// A sink which fails to start is logged and skipped so that the remaining sinks still receive the results,
+// unless it is a required sink
+type MultiHandler struct {
+	handlers     []*namedHandler
+	sourceReport bool
+	reporter     *sourceReporter
+	isBlock      bool
+	deferExit    bool
+}
+
+func NewMultiHandler(handlers ...Handler) *MultiHandler {
+	multiHandler := &MultiHandler{}
+	for _, handler := range handlers {
+		multiHandler.Add(fmt.Sprintf("%T", handler), handler)
+	}
+	return multiHandler
+}
+
+func (handler *MultiHandler) Add(name string, sink Handler) {
+	if sink == nil {
+		return
+	}
+	if blockingHandler, ok := sink.(BlockingHandler); ok {
+		blockingHandler.DeferExit()
+	}
+	if reportingHandler, ok := sink.(sourceReportingHandler); ok {
+		reportingHandler.deferSourceReport()
+		handler.sourceReport = true
+	}
+	handler.handlers = append(handler.handlers, &namedHandler{name: name, handler: sink})
+}
+
+func (handler *MultiHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	scanInfo := &CiScanInfo{}
+	var lastErr error
+	for _, sink := range handler.handlers {
+		info, err := sink.handler.OnStart(sourceManager, scannerName, scannerType)
+		if err != nil {
+			if requiredHandler, ok := sink.handler.(RequiredHandler); ok && requiredHandler.Required() {
+				return nil, fmt.Errorf("%s: %w", sink.name, err)
+			}
+			logger.Error(fmt.Sprintf("%s: %s", sink.name, err.Error()))
+			lastErr = err
+			continue
+		}
+		sink.started = true
+		mergeScanInfo(scanInfo, info)
+	}
+	// only fail when no sink is able to receive results
+	if len(handler.startedHandlers()) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	if handler.sourceReport && sourceManager != nil {
+		detailsURL := scanInfo.ScanUrl
+		if detailsURL == "" {
+			detailsURL = sourceManager.JobURL()
+		}
+		handler.reporter = newSourceReporter(sourceManager, scannerName, detailsURL)
+	}
+	return scanInfo, nil
+}
+
+func (handler *MultiHandler) OnCompleted() {
+	for _, sink := range handler.startedHandlers() {
+		sink.handler.OnCompleted()
+		if blockingHandler, ok := sink.handler.(BlockingHandler); ok && blockingHandler.IsBlock() {
+			logger.Warn(sink.name + " blocks the pipeline")
+			handler.isBlock = true
+		}
+	}
+	handler.reporter.complete(handler.isBlock)
+	if handler.isBlock && !handler.deferExit {
+		logger.Info("block due security config")
+		os.Exit(1)
+	}
+}
+
+func (handler *MultiHandler) OnError(err error) {
+	for _, sink := range handler.startedHandlers() {
+		sink.handler.OnError(err)
+	}
+	handler.reporter.fail(err)
+}
+
+func (handler *MultiHandler) HandleSastFindings(input HandleSastFindingPros) {
+	for _, sink := range handler.startedHandlers() {
+		sink.handler.HandleSastFindings(input)
+		// share the triage of a remote handler with the following sinks
+		if provider, ok := sink.handler.(FindingResultProvider); ok && provider.FindingResult() != nil {
+			input.FindingResult = provider.FindingResult()
+		}
+	}
+	handler.reporter.handleSastFindings(input)
+}
+
+func (handler *MultiHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	for _, sink := range handler.startedHandlers() {
+		sink.handler.HandleSCA(sourceManager, result)
+	}
+	handler.reporter.handleSCA(result)
+}
+
+func (handler *MultiHandler) IsBlock() bool {
+	return handler.isBlock
+}
+
+func (handler *MultiHandler) DeferExit() {
+	handler.deferExit = true
+}
+
+func (handler *MultiHandler) startedHandlers() []*namedHandler {
+	var started []*namedHandler
+	for _, sink := range handler.handlers {
+		if sink.started {
+			started = append(started, sink)
+		}
+	}
+	return started
+}
+
+func mergeScanInfo(target *CiScanInfo, source *CiScanInfo) {
+	if source == nil {
+		return
+	}
+	if target.ScanId == "" {
+		target.ScanId = source.ScanId
+	}
+	if target.ScanUrl == "" {
+		target.ScanUrl = source.ScanUrl
+	}
+	if target.LastCommitSha == "" {
+		target.LastCommitSha = source.LastCommitSha
+	}
+}
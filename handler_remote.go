@@ -9,47 +9,141 @@ import (
 	"os"
 )
 
+// maxUploadResumes is the number of times a chunked upload is resumed after a chunk failed past the retries
+const maxUploadResumes = 2
+
 type RemoteHandler struct {
-	server   string
-	token    string
-	scanInfo *CiScanInfo
-	isBlock  bool
-	client   *Client
+	server        string
+	scannerName   string
+	scanInfo      *CiScanInfo
+	findingResult *UploadFindingResponse
+	isBlock       bool
+	client        *Client
+	reporter      *sourceReporter
+	deferReport   bool
+	policy        *Policy
+	deferExit     bool
+	// uploadErr fails the scan on completion when the results did not reach the server
+	uploadErr error
+	// spool receives the results which did not reach the server when CODE_SECURE_OFFLINE_MODE is spool
+	spool         *SpoolHandler
+	spoolKey      string
+	sourceManager git.GitEnv
+	scannerType   ScannerType
 }
 
 func NewRemoteHandler(codeSecureServer, codeSecureToken string) (*RemoteHandler, error) {
-	apiClient := NewClient(codeSecureServer, codeSecureToken)
+	return NewRemoteHandlerWithCredentials(codeSecureServer, StaticToken(codeSecureToken))
+}
+
+// NewRemoteHandlerWithCredentials creates a RemoteHandler which authenticates with credentials, e.g. OIDCCredentials
+func NewRemoteHandlerWithCredentials(codeSecureServer string, credentials CredentialProvider) (*RemoteHandler, error) {
+	apiClient, err := NewClientWithCredentials(codeSecureServer, credentials)
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteHandler(codeSecureServer, apiClient)
+}
+
+func newRemoteHandler(codeSecureServer string, apiClient *Client) (*RemoteHandler, error) {
 	if apiClient.TestConnection() {
-		return &RemoteHandler{server: codeSecureServer, token: codeSecureToken, client: apiClient, isBlock: false}, nil
+		key := spoolKey(os.Getenv("CODE_SECURE_TOKEN"))
+		if offlineMode() == OfflineModeSpool {
+			replayPendingSpool(apiClient, key)
+		}
+		return &RemoteHandler{server: codeSecureServer, client: apiClient, isBlock: false, spoolKey: key}, nil
 	}
 	return nil, errors.New("failed to connect to remote server")
 }
 
+// newRemoteOrOfflineHandler falls back to a SpoolHandler when the server is unreachable and CODE_SECURE_OFFLINE_MODE
+// is spool. Invalid tls and proxy settings fail whatever the mode
+func newRemoteOrOfflineHandler(codeSecureServer string, credentials CredentialProvider) (Handler, error) {
+	key := spoolKey(os.Getenv("CODE_SECURE_TOKEN"))
+	// the spool files are signed, the key is checked before scanning rather than when the results are spooled
+	if offlineMode() == OfflineModeSpool && key == "" {
+		return nil, errors.New("CODE_SECURE_SPOOL_KEY is required by CODE_SECURE_OFFLINE_MODE=spool without CODE_SECURE_TOKEN")
+	}
+	apiClient, err := NewClientWithCredentials(codeSecureServer, credentials)
+	if err != nil {
+		return nil, err
+	}
+	handler, err := newRemoteHandler(codeSecureServer, apiClient)
+	if err == nil {
+		return handler, nil
+	}
+	if offlineMode() != OfflineModeSpool {
+		return nil, err
+	}
+	logger.Warn(fmt.Sprintf("%s, the results are reported locally and spooled to %s", err.Error(), spoolDir()))
+	return NewSpoolHandler(spoolDir(), key), nil
+}
+
+// newUploadDependencyRequest uploads the suppressed vulnerabilities apart so that they do not block the pipeline
+func newUploadDependencyRequest(scanId string, result ScaResult) UploadDependencyRequest {
+	var vulnerabilities, suppressed []Vulnerability
+	for _, vulnerability := range result.Vulnerabilities {
+		if vulnerability.IsSuppressed() {
+			suppressed = append(suppressed, vulnerability)
+		} else {
+			vulnerabilities = append(vulnerabilities, vulnerability)
+		}
+	}
+	return UploadDependencyRequest{
+		ScanId:                    scanId,
+		Packages:                  result.Packages,
+		PackageDependencies:       result.PackageDependencies,
+		Vulnerabilities:           vulnerabilities,
+		SuppressedVulnerabilities: suppressed,
+		Remediations:              result.Remediations,
+	}
+}
+
 func (handler *RemoteHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
-	response, err := handler.client.UploadDependency(UploadDependencyRequest{
-		ScanId:              handler.scanInfo.ScanId,
-		Packages:            result.Packages,
-		PackageDependencies: result.PackageDependencies,
-		Vulnerabilities:     result.Vulnerabilities,
-	})
+	if handler.spool != nil {
+		handler.spool.HandleSCA(sourceManager, result)
+		return
+	}
+	response, err := handler.client.UploadDependency(newUploadDependencyRequest(handler.scanInfo.ScanId, result))
 	if err != nil {
+		handler.uploadErr = err
 		logger.Error(err.Error())
+		if offlineMode() == OfflineModeSpool {
+			handler.startSpool(err).HandleSCA(sourceManager, result)
+		}
 		return
 	}
-	handler.isBlock = response.IsBlock
+	handler.reporter.handleSCA(result)
+	if len(result.LicenseViolations) > 0 {
+		logger.Warn(fmt.Sprintf("there are %d license violations", len(result.LicenseViolations)))
+		printLicenseViolations(result.LicenseViolations)
+	}
+	decision := handler.policy.EvaluateSca(result)
+	reportPolicyDecision(decision)
+	handler.isBlock = handler.policy.IsBlock(response.IsBlock, decision) || hasDeniedLicense(result.LicenseViolations)
 }
 
 func (handler *RemoteHandler) HandleSastFindings(input HandleSastFindingPros) {
-	response, err := handler.client.UploadFinding(UploadFindingRequest{
+	if handler.spool != nil {
+		handler.spool.HandleSastFindings(input)
+		return
+	}
+	response, err := handler.uploadFinding(UploadFindingRequest{
 		ScanId:       handler.scanInfo.ScanId,
 		Findings:     input.Result.Findings,
 		Strategy:     input.Strategy,
 		ChangedFiles: input.ChangedFiles,
 	})
 	if err != nil {
+		handler.uploadErr = err
 		logger.Error(err.Error())
+		if offlineMode() == OfflineModeSpool {
+			handler.startSpool(err).HandleSastFindings(input)
+		}
 		return
 	}
+	handler.findingResult = response
+	handler.reporter.handleSastFindings(input)
 	err = SaveFindingResult(*response)
 	if err != nil {
 		logger.Error(err.Error())
@@ -89,21 +183,8 @@ func (handler *RemoteHandler) HandleSastFindings(input HandleSastFindingPros) {
 				for _, newFinding := range response.NewFindings {
 					location := newFinding.Location
 					if location != nil {
-						locationUrl := fmt.Sprintf("%s/%s/%s#L%d", sourceManager.BlobURL(), sourceManager.CommitSha(), location.Path, location.StartLine)
 						remoteFindingUrl := fmt.Sprintf("%s/#/finding/%s", handler.server, newFinding.ID)
-						msg := fmt.Sprintf("**[%s](%s)**\n\n**Location:** `%s` @ [%s](%s)\n\n**Description**\n\n%s", newFinding.Name, remoteFindingUrl, location.Snippet, location.Path, locationUrl, newFinding.Description)
-						if newFinding.Recommendation != "" {
-							msg += fmt.Sprintf("\n\n**Recommendation**\n\n %s", newFinding.Recommendation)
-						}
-						if newFinding.Metadata != nil && len(newFinding.Metadata.FindingFlow) > 0 {
-							flow := ""
-							for index, step := range newFinding.Metadata.FindingFlow {
-								url := fmt.Sprintf("%s/%s/%s#L%d", sourceManager.BlobURL(), sourceManager.CommitSha(), step.Path, step.StartLine)
-								flow += fmt.Sprintf("%d. `%s` @ [%s](%s)\n", index+1, step.Snippet, step.Path, url)
-							}
-							codeFlow := fmt.Sprintf("\n\n<details>\n<summary>SastFinding Flow</summary>\n\n%s\n</details>", flow)
-							msg += codeFlow
-						}
+						msg := FindingMarkdown(sourceManager, newFinding, remoteFindingUrl)
 						_ = sourceManager.CreateMRDiscussion(git.MRDiscussionOption{
 							Title:     newFinding.Name,
 							Body:      msg,
@@ -117,10 +198,64 @@ func (handler *RemoteHandler) HandleSastFindings(input HandleSastFindingPros) {
 			}
 		}
 	}
-	handler.isBlock = response.IsBlock
+	decision := handler.policy.EvaluateSast(input.Result.Findings, input.ChangedFiles, newFindingFilter(response))
+	reportPolicyDecision(decision)
+	handler.isBlock = handler.policy.IsBlock(response.IsBlock, decision)
+}
+
+// uploadFinding resumes a chunked upload which failed on a chunk, only the missing chunks are sent again
+func (handler *RemoteHandler) uploadFinding(request UploadFindingRequest) (*UploadFindingResponse, error) {
+	response, err := handler.client.UploadFinding(request)
+	for resume := 1; err != nil && resume <= maxUploadResumes && handler.client.hasPendingUpload(request.ScanId); resume++ {
+		logger.Warn(fmt.Sprintf("%s, resume the upload (%d/%d)", err.Error(), resume, maxUploadResumes))
+		response, err = handler.client.UploadFinding(request)
+	}
+	return response, err
 }
 
 func (handler *RemoteHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	policy, err := policyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	handler.policy = policy
+	handler.scannerName = scannerName
+	handler.scannerType = scannerType
+	handler.sourceManager = sourceManager
+	scanInfo, err := handler.client.InitScan(newCiScanRequest(sourceManager, scannerName, scannerType))
+	if err != nil {
+		if offlineMode() != OfflineModeSpool {
+			return nil, err
+		}
+		handler.startSpool(err)
+		return &CiScanInfo{}, nil
+	}
+	handler.scanInfo = scanInfo
+	if !handler.deferReport && sourceManager != nil {
+		handler.reporter = newSourceReporter(sourceManager, scannerName, scanInfo.ScanUrl)
+	}
+	return scanInfo, nil
+}
+
+// startSpool reports the results locally and spools them when Code Secure fails during the scan
+func (handler *RemoteHandler) startSpool(err error) *SpoolHandler {
+	if handler.spool != nil {
+		return handler.spool
+	}
+	logger.Warn(fmt.Sprintf("%s, the results are reported locally and spooled to %s", err.Error(), spoolDir()))
+	handler.spool = NewSpoolHandler(spoolDir(), handler.spoolKey)
+	if handler.deferExit {
+		handler.spool.DeferExit()
+	}
+	// the check run and the commit status of the remote handler already started
+	if handler.deferReport || handler.reporter != nil {
+		handler.spool.deferSourceReport()
+	}
+	handler.spool.start(handler.sourceManager, handler.scannerName, handler.scannerType, handler.policy)
+	return handler.spool
+}
+
+func newCiScanRequest(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) CiScanRequest {
 	gitAction := GitCommitBranch
 	scanTitle := sourceManager.CommitTitle()
 	commitBranch := sourceManager.CommitBranch()
@@ -138,7 +273,7 @@ func (handler *RemoteHandler) OnStart(sourceManager git.GitEnv, scannerName stri
 		mergeRequestId = sourceManager.MergeRequestID()
 	}
 	isDefault := commitBranch == sourceManager.DefaultBranch()
-	body := CiScanRequest{
+	return CiScanRequest{
 		Source:         sourceManager.Provider(),
 		RepoId:         sourceManager.ProjectID(),
 		RepoUrl:        sourceManager.ProjectURL(),
@@ -154,12 +289,22 @@ func (handler *RemoteHandler) OnStart(sourceManager git.GitEnv, scannerName stri
 		JobUrl:         sourceManager.JobURL(),
 		IsDefault:      Ptr(isDefault),
 	}
-	scanInfo, err := handler.client.InitScan(body)
-	handler.scanInfo = scanInfo
-	return scanInfo, err
 }
 
 func (handler *RemoteHandler) OnCompleted() {
+	if handler.spool != nil {
+		// the scan created on Code Secure is failed, the spooled scan replaces it once replayed
+		if handler.scanInfo != nil && handler.uploadErr != nil {
+			handler.updateScanError(handler.uploadErr)
+		}
+		handler.reporter.complete(handler.spool.IsBlock())
+		handler.spool.OnCompleted()
+		return
+	}
+	if handler.uploadErr != nil {
+		handler.OnError(handler.uploadErr)
+		return
+	}
 	err := handler.client.UpdateScan(handler.scanInfo.ScanId, UpdateCIScanRequest{
 		Status:      Ptr(StatusCompleted),
 		Description: nil,
@@ -167,17 +312,57 @@ func (handler *RemoteHandler) OnCompleted() {
 	if err != nil {
 		logger.Error(err.Error())
 	}
-	if handler.isBlock {
+	handler.reporter.complete(handler.isBlock)
+	if handler.isBlock && !handler.deferExit {
 		logger.Info(fmt.Sprintf("block due security config"))
 		os.Exit(1)
 	}
 }
 
+func (handler *RemoteHandler) FindingResult() *UploadFindingResponse {
+	return handler.findingResult
+}
+
+func (handler *RemoteHandler) IsBlock() bool {
+	if handler.spool != nil {
+		return handler.spool.IsBlock()
+	}
+	return handler.isBlock
+}
+
+func (handler *RemoteHandler) DeferExit() {
+	handler.deferExit = true
+}
+
+// Required fails a handler chain when the scan cannot be created on Code Secure. In spool mode the handler spools
+// the results instead of failing
+func (handler *RemoteHandler) Required() bool {
+	return true
+}
+
+func (handler *RemoteHandler) deferSourceReport() {
+	handler.deferReport = true
+}
+
 func (handler *RemoteHandler) OnError(err error) {
-	_ = handler.client.UpdateScan(handler.scanInfo.ScanId, UpdateCIScanRequest{
+	handler.reporter.fail(err)
+	if handler.spool != nil {
+		handler.spool.OnError(err)
+	}
+	if handler.scanInfo == nil {
+		return
+	}
+	handler.updateScanError(err)
+}
+
+func (handler *RemoteHandler) updateScanError(err error) {
+	updateErr := handler.client.UpdateScan(handler.scanInfo.ScanId, UpdateCIScanRequest{
 		Status:      Ptr(StatusError),
 		Description: Ptr(err.Error()),
 	})
+	if updateErr != nil {
+		logger.Error(updateErr.Error())
+	}
 }
 
 func SaveFindingResult(result UploadFindingResponse) error {
@@ -0,0 +1,48 @@
+package analyzer
+
+import (
+	"os"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// SarifHandler writes sast findings to a SARIF file
+type SarifHandler struct {
+	output      string
+	scannerName string
+}
+
+func NewSarifHandler() *SarifHandler {
+	output := os.Getenv("SARIF_OUTPUT")
+	if output == "" {
+		output = "code-secure.sarif"
+	}
+	return &SarifHandler{output: output}
+}
+
+func (handler *SarifHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	handler.scannerName = scannerName
+	return &CiScanInfo{}, nil
+}
+
+func (handler *SarifHandler) OnCompleted() {}
+
+func (handler *SarifHandler) OnError(err error) {}
+
+func (handler *SarifHandler) HandleSastFindings(input HandleSastFindingPros) {
+	data, err := NewSarifLog(handler.scannerName, input.Result.Findings).JSON()
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("Save sarif to: " + handler.output)
+	err = os.WriteFile(handler.output, data, 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+func (handler *SarifHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	logger.Warn("sarif output only supports sast findings")
+}
@@ -1,6 +1,12 @@
 package analyzer
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/git"
+)
 
 func Ptr[T any](v T) *T {
 	return &v
@@ -13,3 +19,17 @@ func IsDir(path string) bool {
 	}
 	return info.IsDir()
 }
+
+// LocationURL links a finding location to the source file at the scanned commit
+func LocationURL(sourceManager git.GitEnv, location FindingLocation) string {
+	return fmt.Sprintf("%s/%s/%s#L%d", sourceManager.BlobURL(), sourceManager.CommitSha(), location.Path, location.StartLine)
+}
+
+// CweURL returns the normalized CWE-XXX name and its MITRE definition url
+func CweURL(cwe string) (string, string) {
+	id := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(cwe)), "CWE-")
+	if id == "" {
+		return "", ""
+	}
+	return "CWE-" + id, fmt.Sprintf("https://cwe.mitre.org/data/definitions/%s.html", id)
+}
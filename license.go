@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+)
+
+var licenseOperatorRegex = regexp.MustCompile(`(?i)\s(AND|OR|WITH)\s`)
+
+var licenseTokenRegex = regexp.MustCompile(`[A-Za-z0-9.+:-]+`)
+
+// licenseAliases maps license names commonly declared by package managers to their SPDX identifier
+var licenseAliases = map[string]string{
+	"apache 2":                    "Apache-2.0",
+	"apache 2.0":                  "Apache-2.0",
+	"apache-2":                    "Apache-2.0",
+	"apache license 2.0":          "Apache-2.0",
+	"apache license, version 2.0": "Apache-2.0",
+	"apache software license":     "Apache-2.0",
+	"the apache software license, version 2.0": "Apache-2.0",
+	"mit license":                "MIT",
+	"the mit license":            "MIT",
+	"bsd 2-clause":               "BSD-2-Clause",
+	"bsd 3-clause":               "BSD-3-Clause",
+	"new bsd license":            "BSD-3-Clause",
+	"simplified bsd license":     "BSD-2-Clause",
+	"isc license":                "ISC",
+	"gplv2":                      "GPL-2.0-only",
+	"gplv2+":                     "GPL-2.0-or-later",
+	"gplv3":                      "GPL-3.0-only",
+	"gplv3+":                     "GPL-3.0-or-later",
+	"lgplv2":                     "LGPL-2.0-only",
+	"lgplv3":                     "LGPL-3.0-only",
+	"agplv3":                     "AGPL-3.0-only",
+	"mpl 2.0":                    "MPL-2.0",
+	"mozilla public license 2.0": "MPL-2.0",
+	"eclipse public license 1.0": "EPL-1.0",
+	"eclipse public license 2.0": "EPL-2.0",
+}
+
+// SpdxLicenseID returns the canonical SPDX identifier of a license id, ignoring case
+func SpdxLicenseID(value string) (string, bool) {
+	id, ok := spdxLicenseIDs[strings.ToLower(strings.TrimSpace(value))]
+	return id, ok
+}
+
+// IsLicenseExpression reports whether a license is an SPDX expression such as "MIT OR Apache-2.0"
+func IsLicenseExpression(value string) bool {
+	return licenseOperatorRegex.MatchString(value) || strings.ContainsAny(value, "()")
+}
+
+// NormalizeLicense converts a declared license to a SPDX identifier or expression. Common license names are
+// mapped to their identifier and "MIT/Apache-2.0" becomes "MIT OR Apache-2.0". Licenses that are not on the SPDX
+// list are returned as declared
+func NormalizeLicense(license string) string {
+	license = strings.TrimSpace(license)
+	if id, ok := normalizeLicenseID(license); ok {
+		return id
+	}
+	if !IsLicenseExpression(license) && strings.Contains(license, "/") {
+		options := strings.Split(license, "/")
+		for index, option := range options {
+			id, ok := normalizeLicenseID(option)
+			if !ok {
+				return license
+			}
+			options[index] = id
+		}
+		return strings.Join(options, " OR ")
+	}
+	if IsLicenseExpression(license) {
+		return licenseTokenRegex.ReplaceAllStringFunc(license, func(token string) string {
+			switch strings.ToUpper(token) {
+			case "AND", "OR", "WITH":
+				return strings.ToUpper(token)
+			}
+			if id, ok := SpdxLicenseID(token); ok {
+				return id
+			}
+			return token
+		})
+	}
+	return license
+}
+
+func normalizeLicenseID(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if id, ok := SpdxLicenseID(value); ok {
+		return id, true
+	}
+	id, ok := licenseAliases[strings.ToLower(value)]
+	return id, ok
+}
@@ -0,0 +1,263 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+type LicenseAction string
+
+const (
+	LicenseActionAllow  LicenseAction = "allow"
+	LicenseActionReview LicenseAction = "review"
+	LicenseActionDeny   LicenseAction = "deny"
+)
+
+func (action LicenseAction) rank() int {
+	switch action {
+	case LicenseActionDeny:
+		return 2
+	case LicenseActionReview:
+		return 1
+	}
+	return 0
+}
+
+// licenseCategories are the names usable in the policy lists in place of license ids
+var licenseCategories = map[string][]string{
+	"copyleft":      {"GPL-*", "AGPL-*", "SSPL-*", "EUPL-*", "OSL-*", "CC-BY-SA-*", "CC-BY-NC-SA-*"},
+	"weak-copyleft": {"LGPL-*", "MPL-*", "EPL-*", "CDDL-*", "CPL-*"},
+}
+
+// LicensePolicy decides whether the license of a package is allowed, denied or needs a review.
+// The lists hold SPDX ids, glob patterns (GPL-*) or a license category (copyleft, weak-copyleft).
+// When the allow list is not empty, licenses that are not allowed need a review
+type LicensePolicy struct {
+	Allow  []string `json:"allow,omitempty"`
+	Deny   []string `json:"deny,omitempty"`
+	Review []string `json:"review,omitempty"`
+}
+
+// LicenseViolation is a package whose license is denied or needs a review, Paths are the dependency paths which
+// introduce the package
+type LicenseViolation struct {
+	Package Package
+	License string
+	Action  LicenseAction
+	Paths   [][]string
+}
+
+// NewLicensePolicy reads the comma separated lists LICENSE_ALLOW, LICENSE_DENY and LICENSE_REVIEW.
+// It returns nil when no list is configured
+func NewLicensePolicy() *LicensePolicy {
+	policy := &LicensePolicy{
+		Allow:  licensePatterns(os.Getenv("LICENSE_ALLOW")),
+		Deny:   licensePatterns(os.Getenv("LICENSE_DENY")),
+		Review: licensePatterns(os.Getenv("LICENSE_REVIEW")),
+	}
+	if len(policy.Allow) == 0 && len(policy.Deny) == 0 && len(policy.Review) == 0 {
+		return nil
+	}
+	return policy
+}
+
+func licensePatterns(value string) []string {
+	var patterns []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if category, ok := licenseCategories[strings.ToLower(item)]; ok {
+			patterns = append(patterns, category...)
+			continue
+		}
+		patterns = append(patterns, NormalizeLicense(item))
+	}
+	return patterns
+}
+
+// Evaluate returns the violations of the packages of a sca result. Packages without license are not evaluated
+func (policy *LicensePolicy) Evaluate(result ScaResult) []LicenseViolation {
+	if policy == nil {
+		return nil
+	}
+	graph := NewDependencyGraph(result)
+	var violations []LicenseViolation
+	for _, pkg := range result.Packages {
+		if strings.TrimSpace(pkg.License) == "" {
+			continue
+		}
+		license := NormalizeLicense(pkg.License)
+		action := policy.Check(license)
+		if action == LicenseActionAllow {
+			continue
+		}
+		violations = append(violations, LicenseViolation{
+			Package: pkg,
+			License: license,
+			Action:  action,
+			Paths:   graph.PathNames(pkg.PkgId),
+		})
+	}
+	return violations
+}
+
+// Check evaluates a license id or expression. An OR expression takes the most permissive option, an AND expression
+// the most restrictive license. Expressions that can not be parsed are evaluated as a single license
+func (policy *LicensePolicy) Check(license string) LicenseAction {
+	license = NormalizeLicense(license)
+	if IsLicenseExpression(license) {
+		parser := &licenseExpressionParser{policy: policy, tokens: licenseExpressionTokens(license)}
+		action, err := parser.parseOr()
+		if err == nil && parser.position == len(parser.tokens) {
+			return action
+		}
+	}
+	return policy.checkLicense(license, "")
+}
+
+func (policy *LicensePolicy) checkLicense(license string, exception string) LicenseAction {
+	if exception != "" {
+		if action, ok := policy.match(license + " WITH " + exception); ok {
+			return action
+		}
+	}
+	if action, ok := policy.match(license); ok {
+		return action
+	}
+	if len(policy.Allow) > 0 {
+		return LicenseActionReview
+	}
+	return LicenseActionAllow
+}
+
+// match checks the deny list first so that a license listed twice is denied
+func (policy *LicensePolicy) match(license string) (LicenseAction, bool) {
+	switch {
+	case matchLicense(policy.Deny, license):
+		return LicenseActionDeny, true
+	case matchLicense(policy.Review, license):
+		return LicenseActionReview, true
+	case matchLicense(policy.Allow, license):
+		return LicenseActionAllow, true
+	}
+	return "", false
+}
+
+func matchLicense(patterns []string, license string) bool {
+	license = strings.ToLower(license)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		// a license with an exception only matches patterns listing the exception
+		if strings.Contains(pattern, " with ") != strings.Contains(license, " with ") {
+			continue
+		}
+		if matched, _ := path.Match(pattern, license); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func licenseExpressionTokens(expression string) []string {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+	return strings.Fields(expression)
+}
+
+// licenseExpressionParser evaluates a SPDX license expression while parsing it
+type licenseExpressionParser struct {
+	policy   *LicensePolicy
+	tokens   []string
+	position int
+}
+
+func (parser *licenseExpressionParser) peek() string {
+	if parser.position < len(parser.tokens) {
+		return parser.tokens[parser.position]
+	}
+	return ""
+}
+
+func (parser *licenseExpressionParser) next() string {
+	token := parser.peek()
+	parser.position++
+	return token
+}
+
+func (parser *licenseExpressionParser) parseOr() (LicenseAction, error) {
+	action, err := parser.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for strings.EqualFold(parser.peek(), "OR") {
+		parser.next()
+		option, err := parser.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		if option.rank() < action.rank() {
+			action = option
+		}
+	}
+	return action, nil
+}
+
+func (parser *licenseExpressionParser) parseAnd() (LicenseAction, error) {
+	action, err := parser.parseTerm()
+	if err != nil {
+		return "", err
+	}
+	for strings.EqualFold(parser.peek(), "AND") {
+		parser.next()
+		term, err := parser.parseTerm()
+		if err != nil {
+			return "", err
+		}
+		if term.rank() > action.rank() {
+			action = term
+		}
+	}
+	return action, nil
+}
+
+func (parser *licenseExpressionParser) parseTerm() (LicenseAction, error) {
+	token := parser.next()
+	switch {
+	case token == "":
+		return "", errors.New("unexpected end of license expression")
+	case token == "(":
+		action, err := parser.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if parser.next() != ")" {
+			return "", errors.New("missing ) in license expression")
+		}
+		return action, nil
+	case token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR") || strings.EqualFold(token, "WITH"):
+		return "", fmt.Errorf("unexpected %s in license expression", token)
+	}
+	exception := ""
+	if strings.EqualFold(parser.peek(), "WITH") {
+		parser.next()
+		exception = parser.next()
+		if exception == "" {
+			return "", errors.New("missing exception in license expression")
+		}
+	}
+	return parser.policy.checkLicense(token, exception), nil
+}
+
+// hasDeniedLicense reports whether a violation blocks the pipeline, licenses to review do not block
+func hasDeniedLicense(violations []LicenseViolation) bool {
+	for _, violation := range violations {
+		if violation.Action == LicenseActionDeny {
+			return true
+		}
+	}
+	return false
+}
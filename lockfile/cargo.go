@@ -0,0 +1,44 @@
+package lockfile
+
+import (
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+)
+
+// ParseCargoLock reads the [[package]] tables of Cargo.lock. Packages without source are the crates of the workspace
+// and only contribute their dependencies
+func ParseCargoLock(path string) (*analyzer.ScaResult, error) {
+	tables, err := parseTomlTables(path, "package")
+	if err != nil {
+		return nil, err
+	}
+	builder := newBuilder()
+	ids := make(map[string]string)
+	versions := make(map[string][]string)
+	for _, table := range tables {
+		name, version := tomlString(table.values["name"]), tomlString(table.values["version"])
+		if name == "" || version == "" || table.values["source"] == "" {
+			continue
+		}
+		ids[name+" "+version] = builder.add(analyzer.Package{Name: name, Version: version, Type: "cargo"})
+		versions[name] = append(versions[name], version)
+	}
+	for _, table := range tables {
+		name, version := tomlString(table.values["name"]), tomlString(table.values["version"])
+		parent := ids[name+" "+version]
+		for _, dependency := range tomlArray(table.values["dependencies"]) {
+			// a dependency is "name" when a single version is locked, otherwise "name version (source)"
+			fields := strings.Fields(dependency)
+			key := ""
+			switch {
+			case len(fields) >= 2:
+				key = fields[0] + " " + fields[1]
+			case len(versions[fields[0]]) == 1:
+				key = fields[0] + " " + versions[fields[0]][0]
+			}
+			builder.depend(parent, ids[key])
+		}
+	}
+	return builder.result(), nil
+}
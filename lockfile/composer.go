@@ -0,0 +1,52 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+)
+
+type composerLock struct {
+	Packages    []composerPackage `json:"packages"`
+	PackagesDev []composerPackage `json:"packages-dev"`
+}
+
+type composerPackage struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version"`
+	Require map[string]string `json:"require"`
+}
+
+// ParseComposerLock reads the packages and dev packages of composer.lock
+func ParseComposerLock(path string) (*analyzer.ScaResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock composerLock
+	if err = json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	packages := append(lock.Packages, lock.PackagesDev...)
+	builder := newBuilder()
+	ids := make(map[string]string)
+	for _, item := range packages {
+		if item.Name == "" || item.Version == "" {
+			continue
+		}
+		pkg := analyzer.Package{Name: item.Name, Version: item.Version, Type: "composer"}
+		if vendor, name, found := strings.Cut(item.Name, "/"); found {
+			pkg.Group, pkg.Name = vendor, name
+		}
+		ids[strings.ToLower(item.Name)] = builder.add(pkg)
+	}
+	for _, item := range packages {
+		parent := ids[strings.ToLower(item.Name)]
+		for _, name := range sortedKeys(item.Require) {
+			builder.depend(parent, ids[strings.ToLower(name)])
+		}
+	}
+	return builder.result(), nil
+}
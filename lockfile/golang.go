@@ -0,0 +1,135 @@
+package lockfile
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/versioning"
+)
+
+// ParseGoMod reads the requirements of go.mod. Modules only listed in the go.sum next to it are added with
+// their highest version, since go.mod of old go versions does not list every indirect requirement
+func ParseGoMod(path string) (*analyzer.ScaResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	builder := newBuilder()
+	modules := make(map[string]bool)
+	replaces := make(map[string][2]string)
+	var requires [][2]string
+	block := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = strings.TrimSpace(line[:index])
+		}
+		if line == "" {
+			continue
+		}
+		if line == ")" {
+			block = ""
+			continue
+		}
+		fields := strings.Fields(line)
+		if strings.HasSuffix(line, "(") {
+			block = fields[0]
+			continue
+		}
+		directive := block
+		if directive == "" {
+			directive, fields = fields[0], fields[1:]
+		}
+		switch directive {
+		case "require":
+			if len(fields) >= 2 {
+				requires = append(requires, [2]string{unquote(fields[0]), fields[1]})
+			}
+		case "replace":
+			// replace old [version] => new [version], local directory replacements have no version
+			arrow := indexOf(fields, "=>")
+			if arrow < 1 || arrow+1 >= len(fields) {
+				continue
+			}
+			replacement := [2]string{unquote(fields[arrow+1]), ""}
+			if arrow+2 < len(fields) {
+				replacement[1] = fields[arrow+2]
+			}
+			replaces[unquote(fields[0])] = replacement
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, require := range requires {
+		name, version := require[0], require[1]
+		if replacement, ok := replaces[name]; ok {
+			if replacement[1] == "" {
+				continue
+			}
+			name, version = replacement[0], replacement[1]
+		}
+		modules[name] = true
+		builder.add(analyzer.Package{Name: name, Version: version, Type: "gomod"})
+	}
+	sums, err := parseGoSum(filepath.Join(filepath.Dir(path), "go.sum"))
+	if err != nil {
+		return nil, err
+	}
+	for _, sum := range sums {
+		if !modules[sum[0]] {
+			builder.add(analyzer.Package{Name: sum[0], Version: sum[1], Type: "gomod"})
+		}
+	}
+	return builder.result(), nil
+}
+
+// parseGoSum returns the highest version of each module which has a content hash in go.sum
+func parseGoSum(path string) ([][2]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		// lines of the go.mod hash ("v1.2.3/go.mod") are for modules which are not necessarily downloaded
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		current, ok := versions[fields[0]]
+		if !ok {
+			names = append(names, fields[0])
+		}
+		if !ok || versioning.CompareSemver(fields[1], current) > 0 {
+			versions[fields[0]] = fields[1]
+		}
+	}
+	var result [][2]string
+	for _, name := range names {
+		result = append(result, [2]string{name, versions[name]})
+	}
+	return result, nil
+}
+
+func unquote(value string) string {
+	return strings.Trim(value, "\"`")
+}
+
+func indexOf(values []string, value string) int {
+	for index, item := range values {
+		if item == value {
+			return index
+		}
+	}
+	return -1
+}
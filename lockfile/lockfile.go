@@ -0,0 +1,171 @@
+// Package lockfile builds the package inventory of a project from the lockfiles of common ecosystems
+package lockfile
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// Parser reads a lockfile. Packages are identified by their package url
+type Parser func(path string) (*analyzer.ScaResult, error)
+
+// parsers by lockfile name
+var parsers = map[string]Parser{
+	"go.mod":            ParseGoMod,
+	"package-lock.json": ParsePackageLock,
+	"yarn.lock":         ParseYarnLock,
+	"pnpm-lock.yaml":    ParsePnpmLock,
+	"requirements.txt":  ParseRequirements,
+	"poetry.lock":       ParsePoetryLock,
+	"pom.xml":           ParsePom,
+	"Gemfile.lock":      ParseGemfileLock,
+	"Cargo.lock":        ParseCargoLock,
+	"composer.lock":     ParseComposerLock,
+}
+
+// directories which contain installed or generated dependencies instead of project sources
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	".venv":        true,
+	"venv":         true,
+}
+
+// RegisterParser adds or replaces the parser of a lockfile name
+func RegisterParser(name string, parser Parser) {
+	parsers[name] = parser
+}
+
+// Scanner is a sca scanner reading every supported lockfile of a project
+type Scanner struct {
+	projectPath string
+}
+
+func NewScanner(projectPath string) *Scanner {
+	return &Scanner{projectPath: projectPath}
+}
+
+func (scanner *Scanner) Name() string {
+	return "lockfile"
+}
+
+func (scanner *Scanner) Type() analyzer.ScannerType {
+	return analyzer.ScannerTypeDependency
+}
+
+// Scan parses the lockfiles of the project. The location of a package is the lockfile path relative to the project,
+// and its PkgId is the location followed by the package url so the same package in two lockfiles is reported twice
+func (scanner *Scanner) Scan() (*analyzer.ScaResult, error) {
+	var files []string
+	err := filepath.WalkDir(scanner.projectPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if skipDirs[entry.Name()] && path != scanner.projectPath {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := parsers[entry.Name()]; ok {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	result := &analyzer.ScaResult{}
+	for _, file := range files {
+		location, err := filepath.Rel(scanner.projectPath, file)
+		if err != nil {
+			return nil, err
+		}
+		location = filepath.ToSlash(location)
+		fileResult, err := parsers[filepath.Base(file)](file)
+		if err != nil {
+			// a broken lockfile should not hide the packages of the others
+			logger.Warn(fmt.Sprintf("failed to parse %s: %s", location, err))
+			continue
+		}
+		logger.Info(fmt.Sprintf("%s: %d packages", location, len(fileResult.Packages)))
+		merge(result, fileResult, location)
+	}
+	return result, nil
+}
+
+func merge(result *analyzer.ScaResult, fileResult *analyzer.ScaResult, location string) {
+	for _, pkg := range fileResult.Packages {
+		pkg.PkgId = location + ":" + pkg.PkgId
+		pkg.Location = analyzer.Ptr(location)
+		result.Packages = append(result.Packages, pkg)
+	}
+	for _, dependency := range fileResult.PackageDependencies {
+		item := analyzer.PackageDependency{PkgId: location + ":" + dependency.PkgId}
+		for _, child := range dependency.Dependencies {
+			item.Dependencies = append(item.Dependencies, location+":"+child)
+		}
+		result.PackageDependencies = append(result.PackageDependencies, item)
+	}
+}
+
+// builder collects the packages and dependencies of a lockfile in order and without duplicates
+type builder struct {
+	packages     []analyzer.Package
+	ids          map[string]bool
+	dependencies map[string][]string
+	children     map[string]bool
+	parents      []string
+}
+
+func newBuilder() *builder {
+	return &builder{ids: make(map[string]bool), dependencies: make(map[string][]string), children: make(map[string]bool)}
+}
+
+// add registers a package and returns its id
+func (builder *builder) add(pkg analyzer.Package) string {
+	pkg.PkgId = analyzer.PackageURL(pkg)
+	if !builder.ids[pkg.PkgId] {
+		builder.ids[pkg.PkgId] = true
+		builder.packages = append(builder.packages, pkg)
+	}
+	return pkg.PkgId
+}
+
+func (builder *builder) depend(parent, child string) {
+	if parent == "" || child == "" || parent == child || builder.children[parent+" "+child] {
+		return
+	}
+	builder.children[parent+" "+child] = true
+	if _, ok := builder.dependencies[parent]; !ok {
+		builder.parents = append(builder.parents, parent)
+	}
+	builder.dependencies[parent] = append(builder.dependencies[parent], child)
+}
+
+func (builder *builder) result() *analyzer.ScaResult {
+	result := &analyzer.ScaResult{Packages: builder.packages}
+	for _, parent := range builder.parents {
+		if !builder.ids[parent] {
+			continue
+		}
+		item := analyzer.PackageDependency{PkgId: parent}
+		for _, child := range builder.dependencies[parent] {
+			if builder.ids[child] {
+				item.Dependencies = append(item.Dependencies, child)
+			}
+		}
+		if len(item.Dependencies) > 0 {
+			result.PackageDependencies = append(result.PackageDependencies, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package lockfile
+
+import (
+	"encoding/xml"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+)
+
+var mavenPropertyRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+type pomProject struct {
+	GroupID              string          `xml:"groupId"`
+	ArtifactID           string          `xml:"artifactId"`
+	Version              string          `xml:"version"`
+	Parent               pomDependency   `xml:"parent"`
+	Properties           pomProperties   `xml:"properties"`
+	Dependencies         []pomDependency `xml:"dependencies>dependency"`
+	DependencyManagement []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+}
+
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Scope      string `xml:"scope"`
+}
+
+type pomProperties map[string]string
+
+func (properties *pomProperties) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	*properties = make(map[string]string)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err = decoder.DecodeElement(&value, &element); err != nil {
+				return err
+			}
+			(*properties)[element.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// ParsePom reads the dependencies declared in pom.xml. Versions are resolved from the properties and the
+// dependency management of the pom; transitive dependencies need the maven resolver and are not listed
+func ParsePom(path string) (*analyzer.ScaResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var project pomProject
+	if err = xml.Unmarshal(data, &project); err != nil {
+		return nil, err
+	}
+	properties := map[string]string{
+		"project.groupId":        firstNonEmpty(project.GroupID, project.Parent.GroupID),
+		"project.artifactId":     project.ArtifactID,
+		"project.version":        firstNonEmpty(project.Version, project.Parent.Version),
+		"project.parent.version": project.Parent.Version,
+		"pom.version":            firstNonEmpty(project.Version, project.Parent.Version),
+	}
+	for key, value := range project.Properties {
+		properties[key] = value
+	}
+	managed := make(map[string]string)
+	for _, dependency := range project.DependencyManagement {
+		managed[resolveMaven(dependency.GroupID, properties)+":"+resolveMaven(dependency.ArtifactID, properties)] = resolveMaven(dependency.Version, properties)
+	}
+	builder := newBuilder()
+	for _, dependency := range project.Dependencies {
+		group := resolveMaven(dependency.GroupID, properties)
+		artifact := resolveMaven(dependency.ArtifactID, properties)
+		version := resolveMaven(dependency.Version, properties)
+		if version == "" {
+			version = managed[group+":"+artifact]
+		}
+		// unresolved versions come from a parent pom or a bom which are not available offline
+		if group == "" || artifact == "" || version == "" || strings.Contains(version, "${") {
+			continue
+		}
+		builder.add(analyzer.Package{Group: group, Name: artifact, Version: version, Type: "pom"})
+	}
+	return builder.result(), nil
+}
+
+// resolveMaven replaces ${property} references, properties may reference other properties
+func resolveMaven(value string, properties map[string]string) string {
+	value = strings.TrimSpace(value)
+	for depth := 0; depth < 10 && strings.Contains(value, "${"); depth++ {
+		resolved := mavenPropertyRegex.ReplaceAllStringFunc(value, func(reference string) string {
+			if property, ok := properties[reference[2:len(reference)-1]]; ok {
+				return property
+			}
+			return reference
+		})
+		if resolved == value {
+			break
+		}
+		value = resolved
+	}
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
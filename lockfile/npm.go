@@ -0,0 +1,162 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+)
+
+type packageLock struct {
+	LockfileVersion int                           `json:"lockfileVersion"`
+	Packages        map[string]packageLockPackage `json:"packages"`
+	Dependencies    map[string]packageLockV1      `json:"dependencies"`
+}
+
+type packageLockPackage struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Link                 bool              `json:"link"`
+	Dependencies         map[string]string `json:"dependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+}
+
+type packageLockV1 struct {
+	Version      string                   `json:"version"`
+	Requires     map[string]string        `json:"requires"`
+	Dependencies map[string]packageLockV1 `json:"dependencies"`
+}
+
+// ParsePackageLock reads package-lock.json (and npm-shrinkwrap.json) of lockfile version 1, 2 and 3
+func ParsePackageLock(path string) (*analyzer.ScaResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock packageLock
+	if err = json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	builder := newBuilder()
+	if len(lock.Packages) > 0 {
+		parsePackageLockPackages(builder, lock.Packages)
+	} else {
+		parsePackageLockV1(builder, lock.Dependencies, nil)
+	}
+	return builder.result(), nil
+}
+
+// parsePackageLockPackages reads the "packages" of lockfile v2 and v3, keyed by their node_modules path
+func parsePackageLockPackages(builder *builder, packages map[string]packageLockPackage) {
+	paths := make([]string, 0, len(packages))
+	for path := range packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	ids := make(map[string]string)
+	for _, path := range paths {
+		item := packages[path]
+		if path == "" || item.Link || item.Version == "" {
+			continue
+		}
+		name := item.Name
+		if name == "" {
+			name = nodeModuleName(path)
+		}
+		ids[path] = builder.add(npmPackage(name, item.Version, "npm"))
+	}
+	for _, path := range paths {
+		item := packages[path]
+		parent := ids[path]
+		if parent == "" {
+			continue
+		}
+		for _, dependencies := range []map[string]string{item.Dependencies, item.OptionalDependencies, item.PeerDependencies} {
+			for _, name := range sortedKeys(dependencies) {
+				builder.depend(parent, ids[resolveNodeModule(packages, path, name)])
+			}
+		}
+	}
+}
+
+// resolveNodeModule finds the installed path of a dependency like node does: in the nested node_modules
+// of the package first, then in the node_modules of each ancestor
+func resolveNodeModule(packages map[string]packageLockPackage, from, name string) string {
+	base := from
+	for {
+		candidate := "node_modules/" + name
+		if base != "" {
+			candidate = base + "/node_modules/" + name
+		}
+		if _, ok := packages[candidate]; ok {
+			return candidate
+		}
+		if base == "" {
+			return ""
+		}
+		index := strings.LastIndex(base, "/node_modules/")
+		if index < 0 {
+			base = ""
+		} else {
+			base = base[:index]
+		}
+	}
+}
+
+func nodeModuleName(path string) string {
+	index := strings.LastIndex(path, "node_modules/")
+	if index < 0 {
+		return path
+	}
+	return path[index+len("node_modules/"):]
+}
+
+// parsePackageLockV1 reads the nested "dependencies" of lockfile v1. scopes are the enclosing dependency maps
+func parsePackageLockV1(builder *builder, dependencies map[string]packageLockV1, scopes []map[string]packageLockV1) map[string]string {
+	scopes = append([]map[string]packageLockV1{dependencies}, scopes...)
+	ids := make(map[string]string)
+	for _, name := range sortedKeys(dependencies) {
+		item := dependencies[name]
+		ids[name] = builder.add(npmPackage(name, item.Version, "npm"))
+	}
+	for _, name := range sortedKeys(dependencies) {
+		item := dependencies[name]
+		nested := parsePackageLockV1(builder, item.Dependencies, scopes)
+		for _, required := range sortedKeys(item.Requires) {
+			if id, ok := nested[required]; ok {
+				builder.depend(ids[name], id)
+				continue
+			}
+			for _, scope := range scopes {
+				if dependency, ok := scope[required]; ok {
+					builder.depend(ids[name], analyzer.PackageURL(npmPackage(required, dependency.Version, "npm")))
+					break
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// npmPackage keeps the scope of a package in its group, e.g. @babel/core
+func npmPackage(name, version, pkgType string) analyzer.Package {
+	pkg := analyzer.Package{Name: name, Version: version, Type: pkgType}
+	if strings.HasPrefix(name, "@") {
+		if scope, packageName, found := strings.Cut(name, "/"); found {
+			pkg.Group, pkg.Name = scope, packageName
+		}
+	}
+	return pkg
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,117 @@
+package lockfile
+
+import (
+	"os"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+	"gopkg.in/yaml.v3"
+)
+
+type pnpmLock struct {
+	LockfileVersion any                         `yaml:"lockfileVersion"`
+	Packages        map[string]pnpmPackage      `yaml:"packages"`
+	Snapshots       map[string]pnpmPackage      `yaml:"snapshots"`
+	Importers       map[string]pnpmDependencies `yaml:"importers"`
+}
+
+type pnpmPackage struct {
+	Name                 string            `yaml:"name"`
+	Version              string            `yaml:"version"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+type pnpmDependencies struct {
+	Dependencies map[string]any `yaml:"dependencies"`
+}
+
+// ParsePnpmLock reads pnpm-lock.yaml of lockfile version 5 to 9
+func ParsePnpmLock(path string) (*analyzer.ScaResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock pnpmLock
+	if err = yaml.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	builder := newBuilder()
+	ids := make(map[string]string)
+	keys := sortedKeys(lock.Packages)
+	// since v9 the dependencies of a package are in snapshots, keyed with the peer dependencies suffix
+	if len(lock.Snapshots) > 0 {
+		keys = sortedKeys(lock.Snapshots)
+	}
+	for _, key := range keys {
+		name, version := pnpmPackageKey(key)
+		item := lock.Packages[key]
+		if item.Name != "" {
+			name = item.Name
+		}
+		if item.Version != "" {
+			version = item.Version
+		}
+		if name == "" || version == "" {
+			continue
+		}
+		ids[name+"@"+pnpmVersion(version)] = builder.add(npmPackage(name, pnpmVersion(version), "pnpm"))
+	}
+	for _, key := range keys {
+		name, version := pnpmPackageKey(key)
+		item, ok := lock.Snapshots[key]
+		if !ok {
+			item = lock.Packages[key]
+		}
+		parent := ids[name+"@"+pnpmVersion(version)]
+		for _, dependencies := range []map[string]string{item.Dependencies, item.OptionalDependencies} {
+			for _, dependency := range sortedKeys(dependencies) {
+				builder.depend(parent, ids[pnpmDependencyKey(dependency, dependencies[dependency])])
+			}
+		}
+	}
+	return builder.result(), nil
+}
+
+// pnpmPackageKey parses the keys of the packages: /name/1.0.0 (v5), /name@1.0.0 (v6) and name@1.0.0(peer@2.0.0) (v9)
+func pnpmPackageKey(key string) (string, string) {
+	key = strings.TrimPrefix(key, "/")
+	if index := strings.Index(key, "("); index > 0 {
+		key = key[:index]
+	}
+	// v5 keys separate the version with a slash and the peer dependencies with an underscore
+	if index := strings.LastIndex(key, "/"); index > 0 && index+1 < len(key) && isDigit(key[index+1]) {
+		return key[:index], pnpmVersion(key[index+1:])
+	}
+	if index := strings.LastIndex(key, "@"); index > 0 {
+		return key[:index], key[index+1:]
+	}
+	return "", ""
+}
+
+// pnpmDependencyKey resolves a dependency reference, which is a version or an alias such as /other-name/1.0.0
+func pnpmDependencyKey(name, reference string) string {
+	if index := strings.Index(reference, "("); index > 0 {
+		reference = reference[:index]
+	}
+	if reference == "" {
+		return ""
+	}
+	if strings.HasPrefix(reference, "/") || (!isDigit(reference[0]) && strings.LastIndex(reference, "@") > 0) {
+		aliasName, aliasVersion := pnpmPackageKey(reference)
+		return aliasName + "@" + aliasVersion
+	}
+	return name + "@" + pnpmVersion(reference)
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+// pnpmVersion strips the peer dependencies suffix of a version, e.g. 1.0.0_react@18.0.0 or 1.0.0(react@18.0.0)
+func pnpmVersion(version string) string {
+	if index := strings.IndexAny(version, "_("); index > 0 {
+		return version[:index]
+	}
+	return version
+}
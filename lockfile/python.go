@@ -0,0 +1,188 @@
+package lockfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// name[extras]==version ; markers
+var requirementRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*===?\s*([^\s;#,]+)`)
+
+var tomlKeyValueRegex = regexp.MustCompile(`^"?([^"=\s]+)"?\s*=\s*(.*)$`)
+
+// ParseRequirements reads the pinned requirements (name==version) of requirements.txt and the files it includes with -r.
+// Requirements without an exact version can not be matched and are skipped
+func ParseRequirements(path string) (*analyzer.ScaResult, error) {
+	builder := newBuilder()
+	if err := parseRequirements(builder, path, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+	return builder.result(), nil
+}
+
+func parseRequirements(builder *builder, path string, visited map[string]bool) error {
+	if visited[path] {
+		return nil
+	}
+	visited[path] = true
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	line := ""
+	for scanner.Scan() {
+		// a trailing backslash continues the requirement on the next line, e.g. before --hash options
+		line += scanner.Text()
+		if strings.HasSuffix(line, "\\") {
+			line = strings.TrimSuffix(line, "\\")
+			continue
+		}
+		requirement := strings.TrimSpace(line)
+		line = ""
+		if index := strings.Index(requirement, " #"); index >= 0 {
+			requirement = strings.TrimSpace(requirement[:index])
+		}
+		if requirement == "" || strings.HasPrefix(requirement, "#") {
+			continue
+		}
+		for _, option := range []string{"-r ", "--requirement ", "-c ", "--constraint "} {
+			if strings.HasPrefix(requirement, option) {
+				include := strings.TrimSpace(strings.TrimPrefix(requirement, option))
+				if !filepath.IsAbs(include) {
+					include = filepath.Join(filepath.Dir(path), include)
+				}
+				if err = parseRequirements(builder, include, visited); err != nil {
+					logger.Warn(fmt.Sprintf("failed to read %s: %s", include, err))
+				}
+			}
+		}
+		if strings.HasPrefix(requirement, "-") {
+			continue
+		}
+		match := requirementRegex.FindStringSubmatch(requirement)
+		if match == nil {
+			continue
+		}
+		builder.add(analyzer.Package{Name: match[1], Version: match[2], Type: "pip"})
+	}
+	return scanner.Err()
+}
+
+// ParsePoetryLock reads the [[package]] tables of poetry.lock
+func ParsePoetryLock(path string) (*analyzer.ScaResult, error) {
+	tables, err := parseTomlTables(path, "package")
+	if err != nil {
+		return nil, err
+	}
+	builder := newBuilder()
+	ids := make(map[string]string)
+	for _, table := range tables {
+		name, version := tomlString(table.values["name"]), tomlString(table.values["version"])
+		if name == "" || version == "" {
+			continue
+		}
+		ids[normalizePythonName(name)] = builder.add(analyzer.Package{Name: name, Version: version, Type: "poetry"})
+	}
+	for _, table := range tables {
+		parent := ids[normalizePythonName(tomlString(table.values["name"]))]
+		for _, dependency := range table.children["dependencies"] {
+			builder.depend(parent, ids[normalizePythonName(dependency)])
+		}
+	}
+	return builder.result(), nil
+}
+
+func normalizePythonName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(name))
+}
+
+// tomlTable is an array table entry ([[name]]) with its string values and the keys of its sub tables ([name.sub])
+type tomlTable struct {
+	values   map[string]string
+	children map[string][]string
+}
+
+// parseTomlTables reads the entries of an array of tables, which is enough for lockfiles written by tools.
+// Multi line arrays are joined into a single value
+func parseTomlTables(path, name string) ([]*tomlTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var tables []*tomlTable
+	var table *tomlTable
+	child := ""
+	pending := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if pending != "" {
+			pending += " " + line
+			if !strings.HasSuffix(line, "]") {
+				continue
+			}
+			line, pending = pending, ""
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case line == "[["+name+"]]":
+			table = &tomlTable{values: make(map[string]string), children: make(map[string][]string)}
+			tables = append(tables, table)
+			child = ""
+			continue
+		case strings.HasPrefix(line, "["+name+"."):
+			child = strings.TrimSuffix(strings.TrimPrefix(line, "["+name+"."), "]")
+			continue
+		case strings.HasPrefix(line, "["):
+			table, child = nil, ""
+			continue
+		}
+		if table == nil {
+			continue
+		}
+		match := tomlKeyValueRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if strings.HasPrefix(match[2], "[") && !strings.HasSuffix(match[2], "]") {
+			pending = line
+			continue
+		}
+		if child != "" {
+			table.children[child] = append(table.children[child], match[1])
+		} else {
+			table.values[match[1]] = match[2]
+		}
+	}
+	return tables, scanner.Err()
+}
+
+func tomlString(value string) string {
+	return strings.Trim(strings.TrimSpace(value), "\"'")
+}
+
+// tomlArray returns the strings of an inline array: ["a", "b 1.0"]
+func tomlArray(value string) []string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = tomlString(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package lockfile
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+)
+
+// ParseGemfileLock reads the specs of the GEM, GIT and PATH sections of Gemfile.lock
+func ParseGemfileLock(path string) (*analyzer.ScaResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	builder := newBuilder()
+	ids := make(map[string]string)
+	var dependencies [][2]string
+	section := ""
+	inSpecs := false
+	parent := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case indent == 0:
+			section = trimmed
+			inSpecs = false
+		case section != "GEM" && section != "GIT" && section != "PATH":
+			continue
+		case indent == 2:
+			inSpecs = trimmed == "specs:"
+		case indent == 4 && inSpecs:
+			// name (version) or name (version-platform)
+			name, version := gemSpec(trimmed)
+			if version == "" {
+				parent = ""
+				continue
+			}
+			parent = builder.add(analyzer.Package{Name: name, Version: version, Type: "bundler"})
+			if _, ok := ids[name]; !ok {
+				ids[name] = parent
+			}
+		case indent == 6 && inSpecs && parent != "":
+			name, _ := gemSpec(trimmed)
+			dependencies = append(dependencies, [2]string{parent, name})
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, dependency := range dependencies {
+		builder.depend(dependency[0], ids[dependency[1]])
+	}
+	return builder.result(), nil
+}
+
+func gemSpec(value string) (string, string) {
+	name, version, found := strings.Cut(value, " (")
+	if !found {
+		return strings.TrimSpace(value), ""
+	}
+	return name, strings.TrimSuffix(version, ")")
+}
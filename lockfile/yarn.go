@@ -0,0 +1,131 @@
+package lockfile
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+)
+
+type yarnEntry struct {
+	specs        []string
+	version      string
+	dependencies [][2]string
+}
+
+// ParseYarnLock reads yarn.lock of yarn classic (v1) and yarn berry (v2+)
+func ParseYarnLock(path string) (*analyzer.ScaResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []*yarnEntry
+	var entry *yarnEntry
+	inDependencies := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case indent == 0:
+			entry = nil
+			inDependencies = false
+			// the header lists the specs resolved to this entry: "a@^1.0.0", a@^1.1.0:
+			header := strings.TrimSuffix(trimmed, ":")
+			if header == "__metadata" {
+				continue
+			}
+			entry = &yarnEntry{}
+			for _, spec := range strings.Split(header, ",") {
+				entry.specs = append(entry.specs, strings.Trim(strings.TrimSpace(spec), "\""))
+			}
+			entries = append(entries, entry)
+		case entry == nil:
+			continue
+		case indent == 2:
+			key, value := yarnKeyValue(trimmed)
+			inDependencies = key == "dependencies" || key == "optionalDependencies"
+			if key == "version" {
+				entry.version = value
+			}
+		case indent >= 4 && inDependencies:
+			key, value := yarnKeyValue(trimmed)
+			entry.dependencies = append(entry.dependencies, [2]string{key, value})
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	builder := newBuilder()
+	ids := make(map[string]string)
+	for _, entry := range entries {
+		if entry.version == "" || len(entry.specs) == 0 {
+			continue
+		}
+		name := yarnSpecName(entry.specs[0])
+		// workspace packages of berry are the project itself
+		if strings.Contains(entry.specs[0], "@workspace:") {
+			continue
+		}
+		id := builder.add(npmPackage(name, entry.version, "yarn"))
+		for _, spec := range entry.specs {
+			ids[spec] = id
+		}
+	}
+	for _, entry := range entries {
+		parent := ids[entry.specs[0]]
+		for _, dependency := range entry.dependencies {
+			spec := dependency[0] + "@" + dependency[1]
+			child, ok := ids[spec]
+			if !ok {
+				// berry omits the default npm protocol in dependencies
+				child = ids[dependency[0]+"@npm:"+dependency[1]]
+			}
+			builder.depend(parent, child)
+		}
+	}
+	return builder.result(), nil
+}
+
+// yarnKeyValue splits `key "value"` (classic) and `key: value` (berry)
+func yarnKeyValue(line string) (string, string) {
+	var key, value string
+	if strings.HasPrefix(line, "\"") {
+		end := strings.Index(line[1:], "\"")
+		if end < 0 {
+			return strings.Trim(line, "\""), ""
+		}
+		key, value = line[1:end+1], line[end+2:]
+	} else {
+		index := strings.IndexAny(line, " :")
+		if index < 0 {
+			return line, ""
+		}
+		key, value = line[:index], line[index:]
+	}
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), ":"))
+	return strings.TrimSuffix(key, ":"), strings.Trim(value, "\"")
+}
+
+// yarnSpecName strips the range of a spec, keeping the scope: @babel/core@^7.0.0 is @babel/core
+func yarnSpecName(spec string) string {
+	index := strings.LastIndex(spec, "@")
+	if index <= 0 {
+		return spec
+	}
+	name := spec[:index]
+	// berry specs may contain a protocol with an @, e.g. "pkg@patch:pkg@npm%3A1.0.0#..."
+	if strings.Contains(name, "@") && !strings.HasPrefix(name, "@") {
+		return name[:strings.Index(name, "@")]
+	}
+	if strings.HasPrefix(name, "@") && strings.Count(name, "@") > 1 {
+		return name[:strings.Index(name[1:], "@")+1]
+	}
+	return name
+}
@@ -0,0 +1,72 @@
+package osv
+
+import (
+	"math"
+	"strings"
+)
+
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3BaseScore computes the base score of a CVSS 3.x vector (https://www.first.org/cvss/v3.1/specification-document)
+func cvss3BaseScore(vector string) (float64, bool) {
+	if !strings.HasPrefix(vector, "CVSS:3.") {
+		return 0, false
+	}
+	metrics := make(map[string]string)
+	for _, part := range strings.Split(vector, "/")[1:] {
+		key, value, found := strings.Cut(part, ":")
+		if !found {
+			return 0, false
+		}
+		metrics[key] = value
+	}
+	values := make(map[string]float64)
+	for key, weights := range cvss3Weights {
+		weight, ok := weights[metrics[key]]
+		if !ok {
+			return 0, false
+		}
+		values[key] = weight
+	}
+	scope := metrics["S"]
+	if scope != "U" && scope != "C" {
+		return 0, false
+	}
+	privileges := map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	if scope == "C" {
+		privileges = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+	}
+	privilege, ok := privileges[metrics["PR"]]
+	if !ok {
+		return 0, false
+	}
+	iss := 1 - (1-values["C"])*(1-values["I"])*(1-values["A"])
+	impact := 6.42 * iss
+	if scope == "C" {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, true
+	}
+	exploitability := 8.22 * values["AV"] * values["AC"] * privilege * values["UI"]
+	if scope == "C" {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// roundUp is the Roundup function of CVSS 3.1 which avoids floating point errors
+func roundUp(value float64) float64 {
+	integer := int(math.Round(value * 100000))
+	if integer%10000 == 0 {
+		return float64(integer) / 100000
+	}
+	return (math.Floor(float64(integer)/10000) + 1) / 10
+}
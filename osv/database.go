@@ -0,0 +1,144 @@
+package osv
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// Database is an in memory index of OSV advisories by ecosystem and package name
+type Database struct {
+	entries map[string]map[string][]*Entry
+	size    int
+}
+
+func NewDatabase() *Database {
+	return &Database{entries: make(map[string]map[string][]*Entry)}
+}
+
+// LoadDatabase loads an OSV export from disk. path is either a zip (e.g. npm/all.zip from
+// https://osv-vulnerabilities.storage.googleapis.com), or a directory of zip and json files
+func LoadDatabase(path string) (*Database, error) {
+	database := NewDatabase()
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return database, database.loadFile(path)
+	}
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		return database.loadFile(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+func (database *Database) loadFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip":
+		return database.LoadZip(path)
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return database.addJSON(path, data)
+	default:
+		return nil
+	}
+}
+
+// LoadZip loads every json advisory of a zip archive
+func (database *Database) LoadZip(path string) error {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || !strings.HasSuffix(file.Name, ".json") {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(content)
+		_ = content.Close()
+		if err != nil {
+			return err
+		}
+		if err = database.addJSON(path+"!"+file.Name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (database *Database) addJSON(name string, data []byte) error {
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// an export may contain files which are not advisories, skip them instead of failing the whole database
+		logger.Warn(fmt.Sprintf("skip invalid osv advisory %s: %s", name, err))
+		return nil
+	}
+	database.Add(&entry)
+	return nil
+}
+
+// Add indexes an advisory. Withdrawn advisories are ignored
+func (database *Database) Add(entry *Entry) {
+	if entry.ID == "" || entry.Withdrawn != "" {
+		return
+	}
+	added := false
+	for _, affected := range entry.Affected {
+		ecosystem := baseEcosystem(affected.Package.Ecosystem)
+		name := normalizeName(ecosystem, affected.Package.Name)
+		if name == "" {
+			continue
+		}
+		if database.entries[ecosystem] == nil {
+			database.entries[ecosystem] = make(map[string][]*Entry)
+		}
+		entries := database.entries[ecosystem][name]
+		if len(entries) > 0 && entries[len(entries)-1] == entry {
+			continue
+		}
+		database.entries[ecosystem][name] = append(entries, entry)
+		added = true
+	}
+	if added {
+		database.size++
+	}
+}
+
+// Size is the number of advisories in the database
+func (database *Database) Size() int {
+	return database.size
+}
+
+// Lookup returns the advisories mentioning a package
+func (database *Database) Lookup(ecosystem, name string) []*Entry {
+	ecosystem = baseEcosystem(ecosystem)
+	return database.entries[ecosystem][normalizeName(ecosystem, name)]
+}
+
+// baseEcosystem strips the release suffix of an ecosystem, e.g. "Debian:11" is "Debian"
+func baseEcosystem(ecosystem string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return base
+}
@@ -0,0 +1,319 @@
+package osv
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/versioning"
+)
+
+var pypiSeparatorRegex = regexp.MustCompile(`[-_.]+`)
+
+var commitRegex = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// Ecosystem returns the OSV ecosystem of a package, or "" when the package type is not supported
+func Ecosystem(pkg analyzer.Package) string {
+	return versioning.EcosystemOf(analyzer.PurlType(pkg.Type))
+}
+
+// PackageName returns the name of a package in its OSV ecosystem, e.g. org.springframework:spring-core for maven
+func PackageName(ecosystem string, pkg analyzer.Package) string {
+	switch ecosystem {
+	case versioning.Maven:
+		if pkg.Group == "" {
+			return pkg.Name
+		}
+		return pkg.Group + ":" + pkg.Name
+	case versioning.Npm:
+		if strings.HasPrefix(pkg.Group, "@") {
+			return pkg.Group + "/" + pkg.Name
+		}
+		return pkg.Name
+	case versioning.Go, versioning.Packagist, versioning.SwiftURL:
+		if pkg.Group == "" {
+			return pkg.Name
+		}
+		return pkg.Group + "/" + pkg.Name
+	default:
+		return pkg.Name
+	}
+}
+
+func normalizeName(ecosystem, name string) string {
+	switch ecosystem {
+	case versioning.PyPI:
+		return pypiSeparatorRegex.ReplaceAllString(strings.ToLower(name), "-")
+	case versioning.Packagist, versioning.NuGet:
+		return strings.ToLower(name)
+	default:
+		return name
+	}
+}
+
+// Match returns the vulnerabilities of the database affecting a package
+func (database *Database) Match(pkg analyzer.Package) []analyzer.Vulnerability {
+	ecosystem := Ecosystem(pkg)
+	if ecosystem == "" || pkg.Version == "" {
+		return nil
+	}
+	name := PackageName(ecosystem, pkg)
+	version := pkg.Version
+	if ecosystem == versioning.Go {
+		version = strings.TrimPrefix(version, "v")
+	}
+	var vulnerabilities []analyzer.Vulnerability
+	seen := make(map[string]bool)
+	for _, entry := range database.Lookup(ecosystem, name) {
+		if seen[entry.ID] {
+			continue
+		}
+		for _, affected := range entry.Affected {
+			if baseEcosystem(affected.Package.Ecosystem) != ecosystem || normalizeName(ecosystem, affected.Package.Name) != normalizeName(ecosystem, name) {
+				continue
+			}
+			isAffected, fixedVersion := isAffected(ecosystem, affected, version)
+			if !isAffected {
+				continue
+			}
+			// the same vulnerability is often published by several databases (GHSA, PYSEC, GO) under aliases
+			duplicated := false
+			for _, alias := range entry.Aliases {
+				duplicated = duplicated || seen[alias]
+			}
+			seen[entry.ID] = true
+			for _, alias := range entry.Aliases {
+				seen[alias] = true
+			}
+			if !duplicated {
+				vulnerabilities = append(vulnerabilities, newVulnerability(entry, affected, pkg, name, fixedVersion))
+			}
+			break
+		}
+	}
+	return vulnerabilities
+}
+
+// Enrich adds the vulnerabilities of the database to a sca result. Vulnerabilities already reported for a package are kept
+func (database *Database) Enrich(result *analyzer.ScaResult) {
+	reported := make(map[string]bool)
+	for _, vulnerability := range result.Vulnerabilities {
+		reported[vulnerability.PkgId+"|"+vulnerability.Identity] = true
+	}
+	for _, pkg := range result.Packages {
+		for _, vulnerability := range database.Match(pkg) {
+			key := pkg.PkgId + "|" + vulnerability.Identity
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
+			result.Vulnerabilities = append(result.Vulnerabilities, vulnerability)
+		}
+	}
+}
+
+// isAffected evaluates the versions and ranges of an affected package and returns the version fixing it
+func isAffected(ecosystem string, affected Affected, version string) (bool, string) {
+	result := false
+	for _, affectedVersion := range affected.Versions {
+		if affectedVersion == version {
+			result = true
+		}
+	}
+	fixedVersion := ""
+	for _, versionRange := range affected.Ranges {
+		var compare func(a, b string) int
+		switch versionRange.Type {
+		case "SEMVER":
+			compare = versioning.CompareSemver
+		case "ECOSYSTEM":
+			compare = func(a, b string) int {
+				return versioning.Compare(ecosystem, a, b)
+			}
+		case "GIT":
+			// commit ranges need the repository history, only commits named by the range are matched offline
+			if inGitRange(versionRange, version) {
+				result = true
+			}
+			continue
+		default:
+			continue
+		}
+		if inRange(versionRange, version, compare) {
+			result = true
+			if fixed := nextFixedVersion(versionRange, version, compare); fixed != "" {
+				fixedVersion = fixed
+			}
+		} else if result && fixedVersion == "" {
+			fixedVersion = nextFixedVersion(versionRange, version, compare)
+		}
+	}
+	return result, fixedVersion
+}
+
+func inRange(versionRange Range, version string, compare func(a, b string) int) bool {
+	events := append([]Event{}, versionRange.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return compareEvent(events[i], events[j], compare) < 0
+	})
+	affected := false
+	for _, event := range events {
+		switch {
+		case event.Introduced != "":
+			if event.Introduced == "0" || compare(version, event.Introduced) >= 0 {
+				affected = true
+			}
+		case event.Fixed != "":
+			if compare(version, event.Fixed) >= 0 {
+				affected = false
+			}
+		case event.LastAffected != "":
+			if compare(version, event.LastAffected) > 0 {
+				affected = false
+			}
+		case event.Limit != "":
+			if event.Limit != "*" && compare(version, event.Limit) >= 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+func compareEvent(a, b Event, compare func(a, b string) int) int {
+	versionA, versionB := eventVersion(a), eventVersion(b)
+	switch {
+	case versionA == "0" && versionB == "0":
+		return 0
+	case versionA == "0":
+		return -1
+	case versionB == "0":
+		return 1
+	default:
+		return compare(versionA, versionB)
+	}
+}
+
+func eventVersion(event Event) string {
+	for _, version := range []string{event.Introduced, event.Fixed, event.LastAffected, event.Limit} {
+		if version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+// nextFixedVersion is the lowest fixed version above the version
+func nextFixedVersion(versionRange Range, version string, compare func(a, b string) int) string {
+	result := ""
+	for _, event := range versionRange.Events {
+		if event.Fixed == "" || compare(event.Fixed, version) <= 0 {
+			continue
+		}
+		if result == "" || compare(event.Fixed, result) < 0 {
+			result = event.Fixed
+		}
+	}
+	return result
+}
+
+func inGitRange(versionRange Range, version string) bool {
+	version = strings.ToLower(version)
+	if !commitRegex.MatchString(version) {
+		return false
+	}
+	for _, event := range versionRange.Events {
+		for _, commit := range []string{event.Introduced, event.LastAffected} {
+			if commit != "" && commit != "0" && strings.HasPrefix(commit, version) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newVulnerability(entry *Entry, affected Affected, pkg analyzer.Package, name, fixedVersion string) analyzer.Vulnerability {
+	identity := entry.ID
+	for _, alias := range entry.Aliases {
+		if strings.HasPrefix(alias, "CVE-") {
+			identity = alias
+			break
+		}
+	}
+	vulnerability := analyzer.Vulnerability{
+		Identity:     identity,
+		Name:         entry.Summary,
+		Description:  entry.Details,
+		FixedVersion: fixedVersion,
+		PkgId:        pkg.PkgId,
+		PkgName:      name,
+		Metadata: &analyzer.FindingMetadata{
+			Cwes:       entry.DatabaseSpecific.CweIDs,
+			References: []string{"https://osv.dev/vulnerability/" + entry.ID},
+		},
+	}
+	if vulnerability.Name == "" {
+		vulnerability.Name = identity
+	}
+	if vulnerability.Description == "" {
+		vulnerability.Description = entry.Summary
+	}
+	if entry.Published != "" {
+		vulnerability.PublishedAt = analyzer.Ptr(entry.Published)
+	}
+	for _, reference := range entry.References {
+		vulnerability.Metadata.References = append(vulnerability.Metadata.References, reference.URL)
+	}
+	score := -1.0
+	if vector := cvssVector(append(append([]Severity{}, affected.Severity...), entry.Severity...)); vector != "" {
+		vulnerability.Metadata.Cvss = analyzer.Ptr(vector)
+		if value, ok := cvss3BaseScore(vector); ok {
+			score = value
+			vulnerability.Metadata.CvssScore = analyzer.Ptr(strconv.FormatFloat(value, 'f', 1, 64))
+		}
+	}
+	vulnerability.Severity = severity(entry.DatabaseSpecific.Severity, score)
+	return vulnerability
+}
+
+// cvssVector prefers CVSS 3 vectors because their score can be computed
+func cvssVector(severities []Severity) string {
+	for _, severityType := range []string{"CVSS_V3", "CVSS_V4", "CVSS_V2"} {
+		for _, item := range severities {
+			if item.Type == severityType && item.Score != "" {
+				return item.Score
+			}
+		}
+	}
+	return ""
+}
+
+func severity(databaseSeverity string, score float64) analyzer.Severity {
+	switch strings.ToUpper(databaseSeverity) {
+	case "CRITICAL":
+		return analyzer.SeverityCritical
+	case "HIGH":
+		return analyzer.SeverityHigh
+	case "MODERATE", "MEDIUM":
+		return analyzer.SeverityMedium
+	case "LOW":
+		return analyzer.SeverityLow
+	}
+	switch {
+	case score >= 9:
+		return analyzer.SeverityCritical
+	case score >= 7:
+		return analyzer.SeverityHigh
+	case score >= 4:
+		return analyzer.SeverityMedium
+	case score > 0:
+		return analyzer.SeverityLow
+	case score == 0:
+		return analyzer.SeverityInfo
+	default:
+		// advisories without any rating are not ranked, the policy decides whether they block
+		return analyzer.SeverityUnknown
+	}
+}
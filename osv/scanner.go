@@ -0,0 +1,37 @@
+package osv
+
+import (
+	"fmt"
+
+	"github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// Scanner wraps a scanner producing a package inventory (e.g. an SBOM) and matches its packages against the database
+type Scanner struct {
+	inventory analyzer.ScaScanner
+	database  *Database
+}
+
+func NewScanner(inventory analyzer.ScaScanner, database *Database) *Scanner {
+	return &Scanner{inventory: inventory, database: database}
+}
+
+func (scanner *Scanner) Name() string {
+	return scanner.inventory.Name()
+}
+
+func (scanner *Scanner) Type() analyzer.ScannerType {
+	return analyzer.ScannerTypeDependency
+}
+
+func (scanner *Scanner) Scan() (*analyzer.ScaResult, error) {
+	result, err := scanner.inventory.Scan()
+	if err != nil {
+		return nil, err
+	}
+	count := len(result.Vulnerabilities)
+	scanner.database.Enrich(result)
+	logger.Info(fmt.Sprintf("osv: %d vulnerabilities matched in %d packages", len(result.Vulnerabilities)-count, len(result.Packages)))
+	return result, nil
+}
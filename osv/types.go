@@ -0,0 +1,59 @@
+package osv
+
+// Entry is an advisory in the OSV format (https://ossf.github.io/osv-schema/)
+type Entry struct {
+	ID               string           `json:"id"`
+	Modified         string           `json:"modified"`
+	Published        string           `json:"published"`
+	Withdrawn        string           `json:"withdrawn"`
+	Aliases          []string         `json:"aliases"`
+	Summary          string           `json:"summary"`
+	Details          string           `json:"details"`
+	Severity         []Severity       `json:"severity"`
+	Affected         []Affected       `json:"affected"`
+	References       []Reference      `json:"references"`
+	DatabaseSpecific DatabaseSpecific `json:"database_specific"`
+}
+
+type Severity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type Affected struct {
+	Package  AffectedPackage `json:"package"`
+	Severity []Severity      `json:"severity"`
+	Ranges   []Range         `json:"ranges"`
+	Versions []string        `json:"versions"`
+}
+
+type AffectedPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Purl      string `json:"purl"`
+}
+
+type Range struct {
+	Type   string  `json:"type"`
+	Repo   string  `json:"repo"`
+	Events []Event `json:"events"`
+}
+
+// Event is one of introduced, fixed, last_affected or limit
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+type Reference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// DatabaseSpecific holds the fields set by GitHub advisories
+type DatabaseSpecific struct {
+	CweIDs   []string `json:"cwe_ids"`
+	Severity string   `json:"severity"`
+}
@@ -0,0 +1,523 @@
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/logger"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultPolicyFile = ".code-secure/policy.yml"
+	// maxPolicyExamples is the number of results listed in the explanation of a rule
+	maxPolicyExamples = 5
+)
+
+var cweRegex = regexp.MustCompile(`(?i)^(?:cwe-)?(\d+)`)
+
+type PolicyAction string
+
+const (
+	// PolicyActionBlock blocks the pipeline when the rule matches more results than its threshold
+	PolicyActionBlock PolicyAction = "block"
+	// PolicyActionWarn reports the rule without blocking
+	PolicyActionWarn PolicyAction = "warn"
+	// PolicyActionAllow exempts the results it matches from the block and warn rules
+	PolicyActionAllow PolicyAction = "allow"
+)
+
+// PolicyCombine is how the local decision is combined with the decision of Code Secure
+type PolicyCombine string
+
+const (
+	// PolicyCombineAny blocks when the server or the local policy blocks
+	PolicyCombineAny PolicyCombine = "any"
+	// PolicyCombineLocal ignores the decision of the server
+	PolicyCombineLocal PolicyCombine = "local"
+	// PolicyCombineServer only reports the local decision
+	PolicyCombineServer PolicyCombine = "server"
+)
+
+// Policy decides locally whether the results of a scan block the pipeline. It is read from the YAML file of
+// CODE_SECURE_POLICY, .code-secure/policy.yml by default:
+//
+//	combine: any
+//	rules:
+//	  - name: allow-suppressed-with-ticket
+//	    action: allow
+//	    when: {suppressed: true, ticket: true}
+//	  - name: new-critical
+//	    action: block
+//	    when: {new: true, severity: [Critical]}
+//	  - name: high-in-changed-files
+//	    action: block
+//	    threshold: 3
+//	    when: {severity: [High], changedFiles: true}
+//	  - name: sql-injection
+//	    action: block
+//	    when: {cwe: [CWE-89]}
+//	suppressions:
+//	  - id: CVE-2024-1234
+//	    ticket: SEC-42
+//	    expires: 2026-12-31
+type Policy struct {
+	Combine      PolicyCombine       `yaml:"combine"`
+	Rules        []PolicyRule        `yaml:"rules"`
+	Suppressions []PolicySuppression `yaml:"suppressions"`
+}
+
+type PolicyRule struct {
+	Name   string       `yaml:"name"`
+	Action PolicyAction `yaml:"action"`
+	// Threshold is the number of matching results tolerated by the rule
+	Threshold int             `yaml:"threshold"`
+	When      PolicyCondition `yaml:"when"`
+}
+
+// PolicyCondition matches a SAST finding or a vulnerability, every field which is set must match
+type PolicyCondition struct {
+	// Kind is sast or sca
+	Kind        string     `yaml:"kind"`
+	Severity    []Severity `yaml:"severity"`
+	MinSeverity Severity   `yaml:"minSeverity"`
+	// New matches the results which are not in the previous scans, every result is new without Code Secure. The SCA
+	// vulnerabilities are always new
+	New *bool `yaml:"new"`
+	// ChangedFiles matches the findings located in the files changed by the merge request or the commits, the SCA
+	// vulnerabilities are never in the changed files
+	ChangedFiles *bool    `yaml:"changedFiles"`
+	Cwe          []string `yaml:"cwe"`
+	// ID are globs of rule ids, identities and advisory ids
+	ID []string `yaml:"id"`
+	// Path are globs of file paths, ** matches any number of directories
+	Path []string `yaml:"path"`
+	// Package are globs of package names
+	Package    []string `yaml:"package"`
+	Suppressed *bool    `yaml:"suppressed"`
+	// Ticket matches the results suppressed with a ticket
+	Ticket *bool `yaml:"ticket"`
+}
+
+// PolicySuppression marks results as suppressed until it expires, allow rules decide what a suppression exempts
+type PolicySuppression struct {
+	ID      string `yaml:"id"`
+	Path    string `yaml:"path"`
+	Package string `yaml:"package"`
+	Ticket  string `yaml:"ticket"`
+	Reason  string `yaml:"reason"`
+	// Expires is a date (2006-01-02), the suppression ends at the end of the day
+	Expires string `yaml:"expires"`
+}
+
+// PolicyViolation is a block or warn rule which matched more results than its threshold
+type PolicyViolation struct {
+	Rule      string
+	Action    PolicyAction
+	Threshold int
+	Count     int
+	// Examples describe the first matching results
+	Examples []string
+}
+
+func (violation PolicyViolation) String() string {
+	return fmt.Sprintf("rule %q (%s): %d results match, %d tolerated: %s", violation.Rule, violation.Action,
+		violation.Count, violation.Threshold, strings.Join(violation.Examples, "; "))
+}
+
+// PolicyDecision is the result of a policy, Allowed counts the results exempted by each allow rule
+type PolicyDecision struct {
+	Block      bool
+	Violations []PolicyViolation
+	Allowed    map[string]int
+}
+
+// policySubject is a SAST finding or a vulnerability as seen by the rules
+type policySubject struct {
+	kind           string
+	ids            []string
+	severity       Severity
+	cwes           []string
+	path           string
+	pkg            string
+	isNew          bool
+	inChangedFiles bool
+	suppression    *PolicySuppression
+	label          string
+}
+
+// LoadPolicy reads and validates a policy file
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if err = policy.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &policy, nil
+}
+
+// policyFromEnv loads the policy of CODE_SECURE_POLICY or the default policy file, nil when there is none
+func policyFromEnv() (*Policy, error) {
+	file := os.Getenv("CODE_SECURE_POLICY")
+	if file == "" {
+		if _, err := os.Stat(defaultPolicyFile); err != nil {
+			return nil, nil
+		}
+		file = defaultPolicyFile
+	}
+	return LoadPolicy(file)
+}
+
+func (policy *Policy) validate() error {
+	switch policy.Combine {
+	case "":
+		policy.Combine = PolicyCombineAny
+	case PolicyCombineAny, PolicyCombineLocal, PolicyCombineServer:
+	default:
+		return errors.New("invalid combine: " + string(policy.Combine))
+	}
+	for index := range policy.Rules {
+		rule := &policy.Rules[index]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", index+1)
+		}
+		switch rule.Action {
+		case PolicyActionBlock, PolicyActionWarn, PolicyActionAllow:
+		default:
+			return fmt.Errorf("rule %s: invalid action %q", rule.Name, rule.Action)
+		}
+		when := &rule.When
+		if when.Kind != "" && when.Kind != "sast" && when.Kind != "sca" {
+			return fmt.Errorf("rule %s: invalid kind %q", rule.Name, when.Kind)
+		}
+		if when.Kind == "sca" && (when.New != nil || when.ChangedFiles != nil) {
+			return fmt.Errorf("rule %s: new and changedFiles only apply to sast", rule.Name)
+		}
+		for i, value := range when.Severity {
+			severity, ok := ParseSeverity(string(value))
+			if !ok {
+				return fmt.Errorf("rule %s: invalid severity %q", rule.Name, value)
+			}
+			when.Severity[i] = severity
+		}
+		if when.MinSeverity != "" {
+			severity, ok := ParseSeverity(string(when.MinSeverity))
+			if !ok {
+				return fmt.Errorf("rule %s: invalid severity %q", rule.Name, when.MinSeverity)
+			}
+			when.MinSeverity = severity
+		}
+		for i, value := range when.Cwe {
+			cwe := normalizeCwe(value)
+			if cwe == "" {
+				return fmt.Errorf("rule %s: invalid cwe %q", rule.Name, value)
+			}
+			when.Cwe[i] = cwe
+		}
+	}
+	for _, suppression := range policy.Suppressions {
+		if suppression.ID == "" && suppression.Path == "" && suppression.Package == "" {
+			return errors.New("a suppression needs an id, a path or a package")
+		}
+		if suppression.Expires != "" {
+			if _, err := time.Parse(time.DateOnly, suppression.Expires); err != nil {
+				return fmt.Errorf("suppression %s: invalid expires %q", suppression.ID, suppression.Expires)
+			}
+		}
+	}
+	return nil
+}
+
+// EvaluateSast decides on the findings of a SAST scan. isNew tells the findings which are not in the previous scans,
+// nil when every finding is new
+func (policy *Policy) EvaluateSast(findings []SastFinding, changedFiles []ChangedFile, isNew func(SastFinding) bool) PolicyDecision {
+	if policy == nil {
+		return PolicyDecision{}
+	}
+	changed := make(map[string]bool)
+	for _, file := range changedFiles {
+		if file.Status != Delete {
+			changed[file.To] = true
+		}
+	}
+	var subjects []policySubject
+	for _, finding := range findings {
+		subject := policySubject{
+			kind:     "sast",
+			ids:      []string{finding.RuleID, finding.Identity, finding.ID},
+			severity: finding.Severity,
+			isNew:    isNew == nil || isNew(finding),
+			label:    string(finding.Severity) + " " + firstNonEmpty(finding.RuleID, finding.Name, finding.Identity),
+		}
+		if finding.Location != nil {
+			subject.path = finding.Location.Path
+			subject.inChangedFiles = changed[finding.Location.Path]
+			subject.label += " at " + finding.Location.String()
+		}
+		if finding.Metadata != nil {
+			subject.cwes = finding.Metadata.Cwes
+		}
+		subjects = append(subjects, subject)
+	}
+	return policy.evaluate(subjects)
+}
+
+// EvaluateSca decides on the vulnerabilities of a SCA scan. The vulnerabilities suppressed by VEX are not
+// affecting the project and are ignored
+func (policy *Policy) EvaluateSca(result ScaResult) PolicyDecision {
+	if policy == nil {
+		return PolicyDecision{}
+	}
+	graph := NewDependencyGraph(result)
+	locations := make(map[string]string)
+	for _, pkg := range result.Packages {
+		if pkg.Location != nil {
+			locations[pkg.PkgId] = *pkg.Location
+		}
+	}
+	var subjects []policySubject
+	for _, vulnerability := range result.Vulnerabilities {
+		if vulnerability.IsSuppressed() {
+			continue
+		}
+		name := graph.Name(vulnerability.PkgId)
+		subject := policySubject{
+			kind:     "sca",
+			ids:      []string{vulnerability.Identity, vulnerability.Name},
+			severity: vulnerability.Severity,
+			pkg:      firstNonEmpty(vulnerability.PkgName, name),
+			path:     locations[vulnerability.PkgId],
+			isNew:    true,
+			label:    fmt.Sprintf("%s %s in %s", vulnerability.Severity, vulnerability.Identity, name),
+		}
+		if vulnerability.Metadata != nil {
+			subject.cwes = vulnerability.Metadata.Cwes
+		}
+		subjects = append(subjects, subject)
+	}
+	return policy.evaluate(subjects)
+}
+
+func (policy *Policy) evaluate(subjects []policySubject) PolicyDecision {
+	decision := PolicyDecision{Allowed: make(map[string]int)}
+	now := time.Now()
+	for i := range subjects {
+		subjects[i].suppression = policy.suppression(subjects[i], now)
+	}
+	// the allow rules exempt the results from every other rule
+	allowed := make([]bool, len(subjects))
+	for _, rule := range policy.Rules {
+		if rule.Action != PolicyActionAllow {
+			continue
+		}
+		for i, subject := range subjects {
+			if !allowed[i] && rule.When.matches(subject) {
+				allowed[i] = true
+				decision.Allowed[rule.Name]++
+			}
+		}
+	}
+	for _, rule := range policy.Rules {
+		if rule.Action == PolicyActionAllow {
+			continue
+		}
+		violation := PolicyViolation{Rule: rule.Name, Action: rule.Action, Threshold: rule.Threshold}
+		for i, subject := range subjects {
+			if allowed[i] || !rule.When.matches(subject) {
+				continue
+			}
+			violation.Count++
+			if len(violation.Examples) < maxPolicyExamples {
+				violation.Examples = append(violation.Examples, subject.label)
+			}
+		}
+		if violation.Count > rule.Threshold {
+			decision.Violations = append(decision.Violations, violation)
+			decision.Block = decision.Block || rule.Action == PolicyActionBlock
+		}
+	}
+	return decision
+}
+
+func (policy *Policy) suppression(subject policySubject, now time.Time) *PolicySuppression {
+	for i, suppression := range policy.Suppressions {
+		if suppression.Expires != "" {
+			expires, _ := time.Parse(time.DateOnly, suppression.Expires)
+			if now.After(expires.AddDate(0, 0, 1)) {
+				continue
+			}
+		}
+		if suppression.ID != "" && !matchAny([]string{suppression.ID}, subject.ids...) {
+			continue
+		}
+		if suppression.Path != "" && !matchGlob(suppression.Path, subject.path) {
+			continue
+		}
+		if suppression.Package != "" && !matchGlob(suppression.Package, subject.pkg) {
+			continue
+		}
+		return &policy.Suppressions[i]
+	}
+	return nil
+}
+
+func (condition PolicyCondition) matches(subject policySubject) bool {
+	if condition.Kind != "" && condition.Kind != subject.kind {
+		return false
+	}
+	if len(condition.Severity) > 0 && !slices.Contains(condition.Severity, subject.severity) {
+		return false
+	}
+	if condition.MinSeverity != "" && subject.severity.Rank() < condition.MinSeverity.Rank() {
+		return false
+	}
+	if condition.New != nil && *condition.New != subject.isNew {
+		return false
+	}
+	if condition.ChangedFiles != nil && *condition.ChangedFiles != subject.inChangedFiles {
+		return false
+	}
+	if len(condition.Cwe) > 0 && !matchCwe(condition.Cwe, subject.cwes) {
+		return false
+	}
+	if len(condition.ID) > 0 && !matchAny(condition.ID, subject.ids...) {
+		return false
+	}
+	if len(condition.Path) > 0 && !matchAny(condition.Path, subject.path) {
+		return false
+	}
+	if len(condition.Package) > 0 && !matchAny(condition.Package, subject.pkg) {
+		return false
+	}
+	if condition.Suppressed != nil && *condition.Suppressed != (subject.suppression != nil) {
+		return false
+	}
+	if condition.Ticket != nil && *condition.Ticket != (subject.suppression != nil && subject.suppression.Ticket != "") {
+		return false
+	}
+	return true
+}
+
+// IsBlock combines the decision of the server with the local decision, the server decides without policy
+func (policy *Policy) IsBlock(serverBlock bool, decision PolicyDecision) bool {
+	if policy == nil {
+		return serverBlock
+	}
+	switch policy.Combine {
+	case PolicyCombineLocal:
+		return decision.Block
+	case PolicyCombineServer:
+		return serverBlock
+	}
+	return serverBlock || decision.Block
+}
+
+// normalizeCwe turns 89, CWE-89 and "CWE-89: Improper Neutralization..." into CWE-89
+func normalizeCwe(value string) string {
+	match := cweRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return ""
+	}
+	return "CWE-" + strings.TrimLeft(match[1], "0")
+}
+
+func matchCwe(cwes []string, subjectCwes []string) bool {
+	for _, cwe := range subjectCwes {
+		if slices.Contains(cwes, normalizeCwe(cwe)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if value != "" && matchGlob(pattern, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlob matches a glob where * and ? do not match a slash and ** matches any number of directories
+func matchGlob(pattern, value string) bool {
+	var expression strings.Builder
+	expression.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expression.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expression.WriteString("[^/]")
+		default:
+			expression.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expression.WriteString("$")
+	matched, _ := regexp.MatchString(expression.String(), value)
+	return matched
+}
+
+// newFindingFilter tells the findings which are new according to the triage of Code Secure, nil without triage
+func newFindingFilter(response *UploadFindingResponse) func(SastFinding) bool {
+	if response == nil {
+		return nil
+	}
+	identities := make(map[string]bool)
+	for _, finding := range response.NewFindings {
+		identities[finding.Identity] = true
+	}
+	return func(finding SastFinding) bool {
+		return identities[finding.Identity]
+	}
+}
+
+// reportPolicyDecision explains which rules of the local policy triggered
+func reportPolicyDecision(decision PolicyDecision) {
+	rules := make([]string, 0, len(decision.Allowed))
+	for rule := range decision.Allowed {
+		rules = append(rules, rule)
+	}
+	slices.Sort(rules)
+	for _, rule := range rules {
+		logger.Info(fmt.Sprintf("policy rule %q allows %d results", rule, decision.Allowed[rule]))
+	}
+	if len(decision.Violations) == 0 {
+		return
+	}
+	for _, violation := range decision.Violations {
+		logger.Warn("policy " + violation.String())
+	}
+	tbl := table.NewWriter()
+	tbl.SetOutputMirror(os.Stdout)
+	tbl.SetStyle(table.StyleLight)
+	tbl.Style().Options.SeparateRows = true
+	tbl.AppendHeader(table.Row{"Rule", "Action", "Matches", "Tolerated", "Results"})
+	for _, violation := range decision.Violations {
+		tbl.AppendRow(table.Row{violation.Rule, violation.Action, violation.Count, violation.Threshold, strings.Join(violation.Examples, "\n")})
+	}
+	tbl.Render()
+}
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// purlTypes maps package types reported by scanners to package url types (https://github.com/package-url/purl-spec)
+var purlTypes = map[string]string{
+	"pom":         "maven",
+	"jar":         "maven",
+	"gradle":      "maven",
+	"sbt":         "maven",
+	"maven":       "maven",
+	"npm":         "npm",
+	"yarn":        "npm",
+	"pnpm":        "npm",
+	"node-pkg":    "npm",
+	"gomod":       "golang",
+	"gobinary":    "golang",
+	"golang":      "golang",
+	"go":          "golang",
+	"pip":         "pypi",
+	"pipenv":      "pypi",
+	"poetry":      "pypi",
+	"python-pkg":  "pypi",
+	"pypi":        "pypi",
+	"cargo":       "cargo",
+	"rust-binary": "cargo",
+	"crates.io":   "cargo",
+	"composer":    "composer",
+	"packagist":   "composer",
+	"bundler":     "gem",
+	"gemspec":     "gem",
+	"gem":         "gem",
+	"rubygems":    "gem",
+	"nuget":       "nuget",
+	"dotnet-core": "nuget",
+	"conan":       "conan",
+	"cocoapods":   "cocoapods",
+	"swift":       "swift",
+	"pub":         "pub",
+	"hex":         "hex",
+	"conda-pkg":   "conda",
+	"conda":       "conda",
+	"github":      "github",
+	"docker":      "docker",
+	"deb":         "deb",
+	"rpm":         "rpm",
+	"apk":         "apk",
+	"generic":     "generic",
+}
+
+// PurlType converts the package type of a scanner to a package url type
+func PurlType(pkgType string) string {
+	if purlType, ok := purlTypes[strings.ToLower(pkgType)]; ok {
+		return purlType
+	}
+	return "generic"
+}
+
+// PackageURL builds the package url of a package, e.g. pkg:maven/org.springframework/spring-core@4.1.6.RELEASE
+func PackageURL(pkg Package) string {
+	purlType := PurlType(pkg.Type)
+	namespace := pkg.Group
+	name := pkg.Name
+	// npm scope and go module path are kept in the namespace
+	if namespace == "" && strings.Contains(name, "/") && (purlType == "npm" || purlType == "golang" || purlType == "composer" || purlType == "github") {
+		index := strings.LastIndex(name, "/")
+		namespace, name = name[:index], name[index+1:]
+	}
+	if purlType == "pypi" {
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	}
+	result := "pkg:" + purlType + "/"
+	if namespace != "" {
+		var segments []string
+		for _, segment := range strings.Split(namespace, "/") {
+			segments = append(segments, purlEscape(segment))
+		}
+		result += strings.Join(segments, "/") + "/"
+	}
+	result += purlEscape(name)
+	if pkg.Version != "" {
+		result += "@" + purlEscape(pkg.Version)
+	}
+	return result
+}
+
+func purlEscape(value string) string {
+	return strings.NewReplacer("+", "%2B", "@", "%40").Replace(url.PathEscape(value))
+}
+
+// ParsePackageURL converts a package url to a package. The package type is the purl type
+func ParsePackageURL(purl string) (Package, error) {
+	if !strings.HasPrefix(purl, "pkg:") {
+		return Package{}, fmt.Errorf("invalid package url: %s", purl)
+	}
+	remainder := strings.TrimPrefix(purl, "pkg:")
+	// qualifiers and subpath are not part of the package identity
+	if index := strings.IndexAny(remainder, "?#"); index >= 0 {
+		remainder = remainder[:index]
+	}
+	remainder = strings.Trim(remainder, "/")
+	index := strings.Index(remainder, "/")
+	if index <= 0 {
+		return Package{}, fmt.Errorf("invalid package url: %s", purl)
+	}
+	pkg := Package{Type: strings.ToLower(remainder[:index])}
+	remainder = remainder[index+1:]
+	if index = strings.LastIndex(remainder, "@"); index >= 0 {
+		version, err := url.PathUnescape(remainder[index+1:])
+		if err != nil {
+			return Package{}, fmt.Errorf("invalid package url: %s", purl)
+		}
+		pkg.Version = version
+		remainder = remainder[:index]
+	}
+	var segments []string
+	for _, segment := range strings.Split(remainder, "/") {
+		value, err := url.PathUnescape(segment)
+		if err != nil {
+			return Package{}, fmt.Errorf("invalid package url: %s", purl)
+		}
+		segments = append(segments, value)
+	}
+	pkg.Name = segments[len(segments)-1]
+	namespace := strings.Join(segments[:len(segments)-1], "/")
+	if namespace == "" {
+		return pkg, nil
+	}
+	switch pkg.Type {
+	case "npm", "golang", "composer", "github":
+		pkg.Name = namespace + "/" + pkg.Name
+	default:
+		pkg.Group = namespace
+	}
+	return pkg, nil
+}
@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/versioning"
+)
+
+// a fixed version may list the fix of several release lines: "1.2.7, 2.0.3" or "1.2.7 || 2.0.3"
+var fixedVersionSeparatorRegex = regexp.MustCompile(`\s*(?:,|\|\||;|\s)\s*`)
+
+// Remediation is the upgrade of a vulnerable package fixing its vulnerabilities. TargetVersion is empty when no
+// version fixes every vulnerability, Breaking is set when the target changes the major version
+type Remediation struct {
+	PkgId              string   `json:"pkgId"`
+	Package            string   `json:"package"`
+	CurrentVersion     string   `json:"currentVersion"`
+	TargetVersion      string   `json:"targetVersion,omitempty"`
+	Breaking           bool     `json:"breaking"`
+	Fixes              []string `json:"fixes,omitempty"`
+	Unfixed            []string `json:"unfixed,omitempty"`
+	DirectDependencies []string `json:"directDependencies,omitempty"`
+}
+
+// RemediationPlan lists the upgrades of the packages declared in a manifest
+type RemediationPlan struct {
+	Location     string        `json:"location"`
+	Remediations []Remediation `json:"remediations"`
+}
+
+// NewRemediationPlans aggregates the fixed versions of the vulnerabilities of every package and proposes the lowest
+// version fixing all of them, within the major version of the package when possible. Vulnerabilities suppressed by
+// VEX need no upgrade
+func NewRemediationPlans(result ScaResult) []RemediationPlan {
+	graph := NewDependencyGraph(result)
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	var pkgIds []string
+	vulnerabilities := make(map[string][]Vulnerability)
+	for _, vulnerability := range result.Vulnerabilities {
+		if vulnerability.IsSuppressed() {
+			continue
+		}
+		if _, ok := vulnerabilities[vulnerability.PkgId]; !ok {
+			pkgIds = append(pkgIds, vulnerability.PkgId)
+		}
+		vulnerabilities[vulnerability.PkgId] = append(vulnerabilities[vulnerability.PkgId], vulnerability)
+	}
+	var plans []RemediationPlan
+	planIndex := make(map[string]int)
+	for _, pkgId := range pkgIds {
+		pkg, ok := packages[pkgId]
+		if !ok {
+			pkg = Package{PkgId: pkgId, Name: vulnerabilities[pkgId][0].PkgName}
+		}
+		remediation := newRemediation(pkg, vulnerabilities[pkgId])
+		for _, direct := range graph.DirectDependencies(pkgId) {
+			remediation.DirectDependencies = append(remediation.DirectDependencies, graph.Name(direct))
+		}
+		location := ""
+		if pkg.Location != nil {
+			location = *pkg.Location
+		}
+		index, ok := planIndex[location]
+		if !ok {
+			index = len(plans)
+			planIndex[location] = index
+			plans = append(plans, RemediationPlan{Location: location})
+		}
+		plans[index].Remediations = append(plans[index].Remediations, remediation)
+	}
+	return plans
+}
+
+func newRemediation(pkg Package, vulnerabilities []Vulnerability) Remediation {
+	ecosystem := versioning.EcosystemOf(PurlType(pkg.Type))
+	remediation := Remediation{PkgId: pkg.PkgId, Package: packageName(pkg), CurrentVersion: pkg.Version}
+	// fixes holds the fixed versions above the current version of every vulnerability which has a fix
+	var fixes [][]string
+	var candidates []string
+	for _, vulnerability := range vulnerabilities {
+		var fixed []string
+		for _, version := range fixedVersionSeparatorRegex.Split(vulnerability.FixedVersion, -1) {
+			version = strings.TrimLeft(version, "=>^~")
+			if version == "" || (pkg.Version != "" && versioning.Compare(ecosystem, version, pkg.Version) <= 0) {
+				continue
+			}
+			fixed = append(fixed, version)
+		}
+		if len(fixed) == 0 {
+			remediation.Unfixed = append(remediation.Unfixed, vulnerability.Identity)
+			continue
+		}
+		sort.SliceStable(fixed, func(i, j int) bool {
+			return versioning.Compare(ecosystem, fixed[i], fixed[j]) < 0
+		})
+		fixes = append(fixes, fixed)
+		candidates = append(candidates, fixed...)
+		remediation.Fixes = append(remediation.Fixes, vulnerability.Identity)
+	}
+	if len(fixes) == 0 {
+		return remediation
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return versioning.Compare(ecosystem, candidates[i], candidates[j]) < 0
+	})
+	// versions of the current major sort first, so the lowest version fixing everything stays within the major when possible
+	for _, candidate := range candidates {
+		if fixesAll(ecosystem, candidate, fixes) {
+			remediation.TargetVersion = candidate
+			break
+		}
+	}
+	remediation.Breaking = remediation.TargetVersion != "" && pkg.Version != "" &&
+		versioning.Major(ecosystem, remediation.TargetVersion) != versioning.Major(ecosystem, pkg.Version)
+	return remediation
+}
+
+// fixesAll reports whether a version fixes every vulnerability. A fixed version only covers its own release line,
+// except the highest fixed version which covers every later version
+func fixesAll(ecosystem, version string, fixes [][]string) bool {
+	for _, fixed := range fixes {
+		covered := false
+		for index, fix := range fixed {
+			if versioning.Compare(ecosystem, fix, version) > 0 {
+				continue
+			}
+			if index == len(fixed)-1 || versioning.Major(ecosystem, fix) == versioning.Major(ecosystem, version) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+// FindingMarkdown renders a finding in the layout used for merge request discussions.
+// sourceManager and findingURL are optional, locations are not linked without them
+func FindingMarkdown(sourceManager git.GitEnv, finding SastFinding, findingURL string) string {
+	msg := fmt.Sprintf("**%s**", finding.Name)
+	if findingURL != "" {
+		msg = fmt.Sprintf("**[%s](%s)**", finding.Name, findingURL)
+	}
+	if finding.Location != nil {
+		msg += fmt.Sprintf("\n\n**Location:** `%s` @ %s", finding.Location.Snippet, markdownLocation(sourceManager, *finding.Location))
+	}
+	msg += fmt.Sprintf("\n\n**Description**\n\n%s", finding.Description)
+	if finding.Recommendation != "" {
+		msg += fmt.Sprintf("\n\n**Recommendation**\n\n %s", finding.Recommendation)
+	}
+	if finding.Metadata != nil && len(finding.Metadata.FindingFlow) > 0 {
+		flow := ""
+		for index, step := range finding.Metadata.FindingFlow {
+			flow += fmt.Sprintf("%d. `%s` @ %s\n", index+1, step.Snippet, markdownLocation(sourceManager, step))
+		}
+		msg += fmt.Sprintf("\n\n<details>\n<summary>SastFinding Flow</summary>\n\n%s\n</details>", flow)
+	}
+	return msg
+}
+
+func markdownLocation(sourceManager git.GitEnv, location FindingLocation) string {
+	if sourceManager == nil || sourceManager.CommitSha() == "" {
+		return location.String()
+	}
+	return fmt.Sprintf("[%s](%s)", location.Path, LocationURL(sourceManager, location))
+}
+
+// escapeMarkdownCell keeps a value on a single markdown table cell
+func escapeMarkdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,240 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+	// AutomationDetails sets the code scanning category of the run, the analyses of a category replace each other
+	AutomationDetails *SarifAutomationDetails `json:"automationDetails,omitempty"`
+}
+
+// SarifAutomationDetails identifies the run, an id ending with a slash is a category
+type SarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name,omitempty"`
+	ShortDescription     sarifText          `json:"shortDescription"`
+	FullDescription      *sarifText         `json:"fullDescription,omitempty"`
+	Help                 *sarifText         `json:"help,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           sarifProperties    `json:"properties"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifProperties struct {
+	Tags             []string `json:"tags,omitempty"`
+	SecuritySeverity string   `json:"security-severity,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifText         `json:"message"`
+	Locations           []sarifLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	CodeFlows           []sarifCodeFlow   `json:"codeFlows,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifText            `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int        `json:"startLine,omitempty"`
+	EndLine     int        `json:"endLine,omitempty"`
+	StartColumn int        `json:"startColumn,omitempty"`
+	EndColumn   int        `json:"endColumn,omitempty"`
+	Snippet     *sarifText `json:"snippet,omitempty"`
+}
+
+type sarifCodeFlow struct {
+	ThreadFlows []sarifThreadFlow `json:"threadFlows"`
+}
+
+type sarifThreadFlow struct {
+	Locations []sarifThreadFlowLocation `json:"locations"`
+}
+
+type sarifThreadFlowLocation struct {
+	Location sarifLocation `json:"location"`
+}
+
+// NewSarifLog converts sast findings to a SARIF 2.1.0 log with one run for the scanner
+func NewSarifLog(toolName string, findings []SastFinding) SarifLog {
+	run := SarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationUri: "https://github.com/califio/code-secure-analyzer",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	ruleIndexes := make(map[string]int)
+	for _, finding := range findings {
+		ruleID := finding.RuleID
+		if ruleID == "" {
+			ruleID = finding.Identity
+		}
+		index, ok := ruleIndexes[ruleID]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndexes[ruleID] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, toSarifRule(ruleID, finding))
+		}
+		message := finding.Name
+		if finding.Description != "" {
+			message = finding.Description
+		}
+		result := sarifResult{
+			RuleID:    ruleID,
+			RuleIndex: index,
+			Level:     sarifLevel(finding.Severity),
+			Message:   sarifText{Text: message},
+		}
+		if finding.Location != nil {
+			result.Locations = []sarifLocation{toSarifLocation(*finding.Location)}
+		}
+		if finding.Identity != "" {
+			result.PartialFingerprints = map[string]string{"codeSecureIdentity/v1": finding.Identity}
+		}
+		if finding.Metadata != nil && len(finding.Metadata.FindingFlow) > 0 {
+			var flow []sarifThreadFlowLocation
+			for _, step := range finding.Metadata.FindingFlow {
+				flow = append(flow, sarifThreadFlowLocation{Location: toSarifLocation(step)})
+			}
+			result.CodeFlows = []sarifCodeFlow{{ThreadFlows: []sarifThreadFlow{{Locations: flow}}}}
+		}
+		run.Results = append(run.Results, result)
+	}
+	return SarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []SarifRun{run},
+	}
+}
+
+func (log SarifLog) JSON() ([]byte, error) {
+	return json.MarshalIndent(log, "", "  ")
+}
+
+func toSarifRule(ruleID string, finding SastFinding) sarifRule {
+	name := finding.Name
+	if name == "" {
+		name = ruleID
+	}
+	rule := sarifRule{
+		ID:                   ruleID,
+		Name:                 name,
+		ShortDescription:     sarifText{Text: name},
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevel(finding.Severity)},
+		Properties: sarifProperties{
+			Tags:             []string{"security"},
+			SecuritySeverity: securitySeverity(finding.Severity),
+		},
+	}
+	if finding.Description != "" {
+		rule.FullDescription = &sarifText{Text: finding.Description}
+	}
+	if finding.Recommendation != "" {
+		rule.Help = &sarifText{Text: finding.Recommendation}
+	}
+	if finding.Metadata != nil {
+		for _, cwe := range finding.Metadata.Cwes {
+			rule.Properties.Tags = append(rule.Properties.Tags, "external/cwe/"+strings.ToLower(cwe))
+		}
+	}
+	return rule
+}
+
+func toSarifLocation(location FindingLocation) sarifLocation {
+	result := sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{Uri: location.Path},
+		},
+	}
+	if location.StartLine > 0 {
+		region := &sarifRegion{
+			StartLine:   location.StartLine,
+			StartColumn: location.StartColumn,
+			EndColumn:   location.EndColumn,
+		}
+		if location.EndLine >= location.StartLine {
+			region.EndLine = location.EndLine
+		}
+		if location.Snippet != "" {
+			region.Snippet = &sarifText{Text: location.Snippet}
+		}
+		result.PhysicalLocation.Region = region
+	}
+	return result
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// securitySeverity is the CVSS-like score GitHub code scanning uses to rank alerts
+func securitySeverity(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "9.5"
+	case SeverityHigh:
+		return "8.0"
+	case SeverityMedium:
+		return "5.5"
+	case SeverityLow:
+		return "2.0"
+	default:
+		return "0.0"
+	}
+}
@@ -0,0 +1,640 @@
+package analyzer
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+const (
+	cycloneDXSpecVersion = "1.5"
+	cycloneDXNamespace   = "http://cyclonedx.org/schema/bom/1.5"
+)
+
+type CycloneDXBom struct {
+	BomFormat       string                   `json:"bomFormat"`
+	SpecVersion     string                   `json:"specVersion"`
+	SerialNumber    string                   `json:"serialNumber,omitempty"`
+	Version         int                      `json:"version"`
+	Metadata        *CycloneDXMetadata       `json:"metadata,omitempty"`
+	Components      []CycloneDXComponent     `json:"components,omitempty"`
+	Dependencies    []CycloneDXDependency    `json:"dependencies,omitempty"`
+	Vulnerabilities []CycloneDXVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string              `json:"timestamp,omitempty"`
+	Tools     *CycloneDXTools     `json:"tools,omitempty"`
+	Component *CycloneDXComponent `json:"component,omitempty"`
+}
+
+type CycloneDXTools struct {
+	Components []CycloneDXComponent `json:"components,omitempty"`
+}
+
+type CycloneDXComponent struct {
+	Type       string               `json:"type"`
+	BomRef     string               `json:"bom-ref,omitempty"`
+	Group      string               `json:"group,omitempty"`
+	Name       string               `json:"name"`
+	Version    string               `json:"version,omitempty"`
+	Licenses   []CycloneDXLicense   `json:"licenses,omitempty"`
+	Purl       string               `json:"purl,omitempty"`
+	Properties []CycloneDXProperty  `json:"properties,omitempty"`
+	Components []CycloneDXComponent `json:"components,omitempty"`
+}
+
+// CycloneDXLicense is either a license (id or name) or an SPDX expression
+type CycloneDXLicense struct {
+	License    *CycloneDXLicenseID `json:"license,omitempty"`
+	Expression string              `json:"expression,omitempty"`
+}
+
+type CycloneDXLicenseID struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+type CycloneDXVulnerability struct {
+	BomRef         string              `json:"bom-ref,omitempty"`
+	ID             string              `json:"id,omitempty"`
+	Source         *CycloneDXSource    `json:"source,omitempty"`
+	Ratings        []CycloneDXRating   `json:"ratings,omitempty"`
+	Cwes           []int               `json:"cwes,omitempty"`
+	Description    string              `json:"description,omitempty"`
+	Detail         string              `json:"detail,omitempty"`
+	Recommendation string              `json:"recommendation,omitempty"`
+	Advisories     []CycloneDXAdvisory `json:"advisories,omitempty"`
+	Published      string              `json:"published,omitempty"`
+	Analysis       *CycloneDXAnalysis  `json:"analysis,omitempty"`
+	Affects        []CycloneDXAffect   `json:"affects,omitempty"`
+	Properties     []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+type CycloneDXSource struct {
+	Name string `json:"name,omitempty" xml:"name,omitempty"`
+	URL  string `json:"url,omitempty" xml:"url,omitempty"`
+}
+
+type CycloneDXRating struct {
+	Score    *float64 `json:"score,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Method   string   `json:"method,omitempty"`
+	Vector   string   `json:"vector,omitempty"`
+}
+
+type CycloneDXAdvisory struct {
+	URL string `json:"url" xml:"url"`
+}
+
+// CycloneDXAnalysis is the VEX statement of a vulnerability
+type CycloneDXAnalysis struct {
+	State         string   `json:"state,omitempty"`
+	Justification string   `json:"justification,omitempty"`
+	Response      []string `json:"response,omitempty"`
+	Detail        string   `json:"detail,omitempty"`
+}
+
+type CycloneDXAffect struct {
+	Ref      string                   `json:"ref"`
+	Versions []CycloneDXAffectVersion `json:"versions,omitempty"`
+}
+
+// CycloneDXAffectVersion is a version or range of the affected component, status is affected, unaffected or unknown
+type CycloneDXAffectVersion struct {
+	Version string `json:"version,omitempty" xml:"version,omitempty"`
+	Range   string `json:"range,omitempty" xml:"range,omitempty"`
+	Status  string `json:"status,omitempty" xml:"status,omitempty"`
+}
+
+// NewCycloneDXBom converts a sca result to a CycloneDX 1.5 bom. component names the scanned project and is optional
+func NewCycloneDXBom(result ScaResult, component string) CycloneDXBom {
+	bom := CycloneDXBom{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: &CycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: &CycloneDXTools{Components: []CycloneDXComponent{{
+				Type:    "application",
+				Name:    AnalyzerName,
+				Version: analyzerVersion(),
+			}}},
+		},
+	}
+	if component != "" {
+		bom.Metadata.Component = &CycloneDXComponent{Type: "application", Name: component}
+	}
+	refs := make(map[string]string)
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		ref := cycloneDXBomRef(pkg)
+		if _, ok := refs[pkg.PkgId]; ok || ref == "" {
+			continue
+		}
+		refs[pkg.PkgId] = ref
+		packages[pkg.PkgId] = pkg
+		item := CycloneDXComponent{
+			Type:     "library",
+			BomRef:   ref,
+			Group:    pkg.Group,
+			Name:     pkg.Name,
+			Version:  pkg.Version,
+			Licenses: cycloneDXLicenses(pkg.License),
+			Purl:     PackageURL(pkg),
+		}
+		if pkg.Location != nil && *pkg.Location != "" {
+			item.Properties = append(item.Properties, CycloneDXProperty{Name: "code-secure:location", Value: *pkg.Location})
+		}
+		bom.Components = append(bom.Components, item)
+	}
+	for _, dependency := range result.PackageDependencies {
+		ref, ok := refs[dependency.PkgId]
+		if !ok {
+			continue
+		}
+		item := CycloneDXDependency{Ref: ref}
+		for _, child := range dependency.Dependencies {
+			if childRef, ok := refs[child]; ok {
+				item.DependsOn = append(item.DependsOn, childRef)
+			}
+		}
+		bom.Dependencies = append(bom.Dependencies, item)
+	}
+	for _, vulnerability := range result.Vulnerabilities {
+		item := CycloneDXVulnerability{
+			ID:          vulnerability.Identity,
+			Source:      advisorySource(vulnerability.Identity),
+			Description: vulnerability.Description,
+			Published:   cycloneDXTime(vulnerability.PublishedAt),
+		}
+		if vulnerability.Name != "" && vulnerability.Name != vulnerability.Identity {
+			item.Detail = vulnerability.Name
+		}
+		item.Ratings = []CycloneDXRating{{Severity: cycloneDXSeverity(vulnerability.Severity)}}
+		if vulnerability.Metadata != nil {
+			metadata := vulnerability.Metadata
+			if metadata.Cvss != nil && *metadata.Cvss != "" {
+				rating := &item.Ratings[0]
+				rating.Vector = *metadata.Cvss
+				rating.Method = cvssMethod(*metadata.Cvss)
+				if metadata.CvssScore != nil {
+					if score, err := strconv.ParseFloat(*metadata.CvssScore, 64); err == nil {
+						rating.Score = &score
+					}
+				}
+			}
+			for _, cwe := range metadata.Cwes {
+				name, _ := CweURL(cwe)
+				if id, err := strconv.Atoi(strings.TrimPrefix(name, "CWE-")); err == nil {
+					item.Cwes = append(item.Cwes, id)
+				}
+			}
+			for _, reference := range metadata.References {
+				if strings.HasPrefix(reference, "http://") || strings.HasPrefix(reference, "https://") {
+					item.Advisories = append(item.Advisories, CycloneDXAdvisory{URL: reference})
+				}
+			}
+		}
+		pkg, ok := packages[vulnerability.PkgId]
+		if ok {
+			item.Affects = []CycloneDXAffect{{Ref: refs[vulnerability.PkgId]}}
+			if vulnerability.FixedVersion != "" {
+				item.Recommendation = fmt.Sprintf("Upgrade %s to version %s or above", packageName(pkg), vulnerability.FixedVersion)
+			}
+		}
+		bom.Vulnerabilities = append(bom.Vulnerabilities, item)
+	}
+	return bom
+}
+
+func (bom CycloneDXBom) JSON() ([]byte, error) {
+	return json.MarshalIndent(bom, "", "  ")
+}
+
+func (bom CycloneDXBom) XML() ([]byte, error) {
+	data, err := xml.MarshalIndent(bom.toXML(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func cycloneDXBomRef(pkg Package) string {
+	if pkg.PkgId != "" {
+		return pkg.PkgId
+	}
+	return PackageURL(pkg)
+}
+
+func cycloneDXLicenses(license string) []CycloneDXLicense {
+	license = strings.TrimSpace(license)
+	if license == "" {
+		return nil
+	}
+	if IsLicenseExpression(license) {
+		return []CycloneDXLicense{{Expression: license}}
+	}
+	if id, ok := SpdxLicenseID(license); ok {
+		return []CycloneDXLicense{{License: &CycloneDXLicenseID{ID: id}}}
+	}
+	return []CycloneDXLicense{{License: &CycloneDXLicenseID{Name: license}}}
+}
+
+func advisorySource(identity string) *CycloneDXSource {
+	upper := strings.ToUpper(identity)
+	switch {
+	case strings.HasPrefix(upper, "CVE-"):
+		return &CycloneDXSource{Name: "NVD", URL: "https://nvd.nist.gov/vuln/detail/" + identity}
+	case strings.HasPrefix(upper, "GHSA-"):
+		return &CycloneDXSource{Name: "GitHub Advisories", URL: "https://github.com/advisories/" + identity}
+	default:
+		return nil
+	}
+}
+
+func cycloneDXSeverity(severity Severity) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo:
+		return strings.ToLower(string(severity))
+	default:
+		return "unknown"
+	}
+}
+
+func cvssMethod(vector string) string {
+	switch {
+	case strings.HasPrefix(vector, "CVSS:4.0"):
+		return "CVSSv4"
+	case strings.HasPrefix(vector, "CVSS:3.1"):
+		return "CVSSv31"
+	case strings.HasPrefix(vector, "CVSS:3"):
+		return "CVSSv3"
+	default:
+		return "CVSSv2"
+	}
+}
+
+// cycloneDXTime converts a published date to the date-time format required by CycloneDX
+func cycloneDXTime(value *string) string {
+	if value == nil {
+		return ""
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, *value); err == nil {
+			return parsed.UTC().Format(time.RFC3339)
+		}
+	}
+	return ""
+}
+
+// newUUID generates a random version 4 uuid
+func newUUID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+// CycloneDXHandler writes the sca result as a CycloneDX SBOM in json or xml
+type CycloneDXHandler struct {
+	output        string
+	format        string
+	sourceManager git.GitEnv
+}
+
+func NewCycloneDXHandler() (*CycloneDXHandler, error) {
+	output := os.Getenv("CYCLONEDX_OUTPUT")
+	if output == "" {
+		output = "code-secure.cdx.json"
+	}
+	format := strings.ToLower(os.Getenv("CYCLONEDX_FORMAT"))
+	if format == "" {
+		format = "json"
+		if strings.HasSuffix(output, ".xml") {
+			format = "xml"
+		}
+	}
+	if format != "json" && format != "xml" {
+		return nil, fmt.Errorf("invalid CYCLONEDX_FORMAT: %s", format)
+	}
+	return &CycloneDXHandler{output: output, format: format}, nil
+}
+
+func (handler *CycloneDXHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	handler.sourceManager = sourceManager
+	return &CiScanInfo{}, nil
+}
+
+func (handler *CycloneDXHandler) OnCompleted() {}
+
+func (handler *CycloneDXHandler) OnError(err error) {}
+
+func (handler *CycloneDXHandler) HandleSastFindings(input HandleSastFindingPros) {
+	logger.Warn("cyclonedx output only supports sca result")
+}
+
+func (handler *CycloneDXHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	component := ""
+	if sourceManager != nil {
+		component = sourceManager.ProjectName()
+	}
+	bom := NewCycloneDXBom(result, component)
+	var data []byte
+	var err error
+	if handler.format == "xml" {
+		data, err = bom.XML()
+	} else {
+		data, err = bom.JSON()
+	}
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.Info("Save CycloneDX SBOM to: " + handler.output)
+	err = os.WriteFile(handler.output, data, 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// xml representation, elements follow the sequence of the CycloneDX 1.5 xsd
+
+type cycloneDXXMLBom struct {
+	XMLName         xml.Name                     `xml:"bom"`
+	Xmlns           string                       `xml:"xmlns,attr"`
+	SerialNumber    string                       `xml:"serialNumber,attr,omitempty"`
+	Version         int                          `xml:"version,attr"`
+	Metadata        *cycloneDXXMLMetadata        `xml:"metadata,omitempty"`
+	Components      *cycloneDXXMLComponents      `xml:"components,omitempty"`
+	Dependencies    *cycloneDXXMLDependencies    `xml:"dependencies,omitempty"`
+	Vulnerabilities *cycloneDXXMLVulnerabilities `xml:"vulnerabilities,omitempty"`
+}
+
+type cycloneDXXMLMetadata struct {
+	Timestamp string                 `xml:"timestamp,omitempty"`
+	Tools     *cycloneDXXMLTools     `xml:"tools,omitempty"`
+	Component *cycloneDXXMLComponent `xml:"component,omitempty"`
+}
+
+type cycloneDXXMLTools struct {
+	Components cycloneDXXMLComponents `xml:"components"`
+}
+
+type cycloneDXXMLComponents struct {
+	Components []cycloneDXXMLComponent `xml:"component"`
+}
+
+type cycloneDXXMLComponent struct {
+	Type       string                  `xml:"type,attr"`
+	BomRef     string                  `xml:"bom-ref,attr,omitempty"`
+	Group      string                  `xml:"group,omitempty"`
+	Name       string                  `xml:"name"`
+	Version    string                  `xml:"version,omitempty"`
+	Licenses   *cycloneDXXMLLicenses   `xml:"licenses,omitempty"`
+	Purl       string                  `xml:"purl,omitempty"`
+	Properties *cycloneDXXMLProperties `xml:"properties,omitempty"`
+	Components *cycloneDXXMLComponents `xml:"components,omitempty"`
+}
+
+type cycloneDXXMLLicenses struct {
+	Licenses   []cycloneDXXMLLicense `xml:"license,omitempty"`
+	Expression string                `xml:"expression,omitempty"`
+}
+
+type cycloneDXXMLLicense struct {
+	ID   string `xml:"id,omitempty"`
+	Name string `xml:"name,omitempty"`
+}
+
+type cycloneDXXMLProperties struct {
+	Properties []cycloneDXXMLProperty `xml:"property"`
+}
+
+type cycloneDXXMLProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type cycloneDXXMLDependencies struct {
+	Dependencies []cycloneDXXMLDependency `xml:"dependency"`
+}
+
+type cycloneDXXMLDependency struct {
+	Ref          string                   `xml:"ref,attr"`
+	Dependencies []cycloneDXXMLDependency `xml:"dependency,omitempty"`
+}
+
+type cycloneDXXMLVulnerabilities struct {
+	Vulnerabilities []cycloneDXXMLVulnerability `xml:"vulnerability"`
+}
+
+type cycloneDXXMLVulnerability struct {
+	BomRef         string                  `xml:"bom-ref,attr,omitempty"`
+	ID             string                  `xml:"id,omitempty"`
+	Source         *CycloneDXSource        `xml:"source,omitempty"`
+	Ratings        *cycloneDXXMLRatings    `xml:"ratings,omitempty"`
+	Cwes           *cycloneDXXMLCwes       `xml:"cwes,omitempty"`
+	Description    string                  `xml:"description,omitempty"`
+	Detail         string                  `xml:"detail,omitempty"`
+	Recommendation string                  `xml:"recommendation,omitempty"`
+	Advisories     *cycloneDXXMLAdvisories `xml:"advisories,omitempty"`
+	Published      string                  `xml:"published,omitempty"`
+	Analysis       *cycloneDXXMLAnalysis   `xml:"analysis,omitempty"`
+	Affects        *cycloneDXXMLAffects    `xml:"affects,omitempty"`
+	Properties     *cycloneDXXMLProperties `xml:"properties,omitempty"`
+}
+
+type cycloneDXXMLRatings struct {
+	Ratings []cycloneDXXMLRating `xml:"rating"`
+}
+
+type cycloneDXXMLRating struct {
+	Score    *float64 `xml:"score,omitempty"`
+	Severity string   `xml:"severity,omitempty"`
+	Method   string   `xml:"method,omitempty"`
+	Vector   string   `xml:"vector,omitempty"`
+}
+
+type cycloneDXXMLCwes struct {
+	Cwes []int `xml:"cwe"`
+}
+
+type cycloneDXXMLAdvisories struct {
+	Advisories []CycloneDXAdvisory `xml:"advisory"`
+}
+
+type cycloneDXXMLAnalysis struct {
+	State         string                 `xml:"state,omitempty"`
+	Justification string                 `xml:"justification,omitempty"`
+	Responses     *cycloneDXXMLResponses `xml:"responses,omitempty"`
+	Detail        string                 `xml:"detail,omitempty"`
+}
+
+type cycloneDXXMLResponses struct {
+	Responses []string `xml:"response"`
+}
+
+type cycloneDXXMLAffects struct {
+	Targets []cycloneDXXMLTarget `xml:"target"`
+}
+
+type cycloneDXXMLTarget struct {
+	Ref      string                `xml:"ref"`
+	Versions *cycloneDXXMLVersions `xml:"versions,omitempty"`
+}
+
+type cycloneDXXMLVersions struct {
+	Versions []CycloneDXAffectVersion `xml:"version"`
+}
+
+func (bom CycloneDXBom) toXML() cycloneDXXMLBom {
+	result := cycloneDXXMLBom{
+		Xmlns:        cycloneDXNamespace,
+		SerialNumber: bom.SerialNumber,
+		Version:      bom.Version,
+	}
+	if bom.Metadata != nil {
+		result.Metadata = &cycloneDXXMLMetadata{Timestamp: bom.Metadata.Timestamp}
+		if bom.Metadata.Tools != nil {
+			result.Metadata.Tools = &cycloneDXXMLTools{Components: toXMLComponents(bom.Metadata.Tools.Components)}
+		}
+		if bom.Metadata.Component != nil {
+			component := toXMLComponent(*bom.Metadata.Component)
+			result.Metadata.Component = &component
+		}
+	}
+	if len(bom.Components) > 0 {
+		components := toXMLComponents(bom.Components)
+		result.Components = &components
+	}
+	if len(bom.Dependencies) > 0 {
+		result.Dependencies = &cycloneDXXMLDependencies{}
+		for _, dependency := range bom.Dependencies {
+			item := cycloneDXXMLDependency{Ref: dependency.Ref}
+			for _, ref := range dependency.DependsOn {
+				item.Dependencies = append(item.Dependencies, cycloneDXXMLDependency{Ref: ref})
+			}
+			result.Dependencies.Dependencies = append(result.Dependencies.Dependencies, item)
+		}
+	}
+	if len(bom.Vulnerabilities) > 0 {
+		result.Vulnerabilities = &cycloneDXXMLVulnerabilities{}
+		for _, vulnerability := range bom.Vulnerabilities {
+			result.Vulnerabilities.Vulnerabilities = append(result.Vulnerabilities.Vulnerabilities, toXMLVulnerability(vulnerability))
+		}
+	}
+	return result
+}
+
+func toXMLComponents(components []CycloneDXComponent) cycloneDXXMLComponents {
+	var result cycloneDXXMLComponents
+	for _, component := range components {
+		result.Components = append(result.Components, toXMLComponent(component))
+	}
+	return result
+}
+
+func toXMLComponent(component CycloneDXComponent) cycloneDXXMLComponent {
+	result := cycloneDXXMLComponent{
+		Type:       component.Type,
+		BomRef:     component.BomRef,
+		Group:      component.Group,
+		Name:       component.Name,
+		Version:    component.Version,
+		Purl:       component.Purl,
+		Properties: toXMLProperties(component.Properties),
+	}
+	if len(component.Components) > 0 {
+		components := toXMLComponents(component.Components)
+		result.Components = &components
+	}
+	if len(component.Licenses) > 0 {
+		result.Licenses = &cycloneDXXMLLicenses{}
+		for _, license := range component.Licenses {
+			if license.Expression != "" {
+				result.Licenses.Expression = license.Expression
+			} else if license.License != nil {
+				result.Licenses.Licenses = append(result.Licenses.Licenses, cycloneDXXMLLicense{ID: license.License.ID, Name: license.License.Name})
+			}
+		}
+	}
+	return result
+}
+
+func toXMLProperties(properties []CycloneDXProperty) *cycloneDXXMLProperties {
+	if len(properties) == 0 {
+		return nil
+	}
+	result := &cycloneDXXMLProperties{}
+	for _, property := range properties {
+		result.Properties = append(result.Properties, cycloneDXXMLProperty{Name: property.Name, Value: property.Value})
+	}
+	return result
+}
+
+func toXMLVulnerability(vulnerability CycloneDXVulnerability) cycloneDXXMLVulnerability {
+	result := cycloneDXXMLVulnerability{
+		BomRef:         vulnerability.BomRef,
+		ID:             vulnerability.ID,
+		Source:         vulnerability.Source,
+		Description:    vulnerability.Description,
+		Detail:         vulnerability.Detail,
+		Recommendation: vulnerability.Recommendation,
+		Published:      vulnerability.Published,
+		Properties:     toXMLProperties(vulnerability.Properties),
+	}
+	if len(vulnerability.Ratings) > 0 {
+		result.Ratings = &cycloneDXXMLRatings{}
+		for _, rating := range vulnerability.Ratings {
+			result.Ratings.Ratings = append(result.Ratings.Ratings, cycloneDXXMLRating(rating))
+		}
+	}
+	if len(vulnerability.Cwes) > 0 {
+		result.Cwes = &cycloneDXXMLCwes{Cwes: vulnerability.Cwes}
+	}
+	if len(vulnerability.Advisories) > 0 {
+		result.Advisories = &cycloneDXXMLAdvisories{Advisories: vulnerability.Advisories}
+	}
+	if vulnerability.Analysis != nil {
+		result.Analysis = &cycloneDXXMLAnalysis{
+			State:         vulnerability.Analysis.State,
+			Justification: vulnerability.Analysis.Justification,
+			Detail:        vulnerability.Analysis.Detail,
+		}
+		if len(vulnerability.Analysis.Response) > 0 {
+			result.Analysis.Responses = &cycloneDXXMLResponses{Responses: vulnerability.Analysis.Response}
+		}
+	}
+	if len(vulnerability.Affects) > 0 {
+		result.Affects = &cycloneDXXMLAffects{}
+		for _, affect := range vulnerability.Affects {
+			target := cycloneDXXMLTarget{Ref: affect.Ref}
+			if len(affect.Versions) > 0 {
+				target.Versions = &cycloneDXXMLVersions{Versions: affect.Versions}
+			}
+			result.Affects.Targets = append(result.Affects.Targets, target)
+		}
+	}
+	return result
+}
@@ -0,0 +1,379 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// matches the recommendation written by NewCycloneDXBom and most scanners, e.g. "Upgrade foo to version 1.2.3"
+var cycloneDXUpgradeRegex = regexp.MustCompile(`(?i)\bto(?: version)? v?([0-9][^\s,;]*[0-9a-zA-Z])`)
+
+// locations of the manifest file reported by syft, cdxgen and this analyzer
+var cycloneDXLocationProperties = []string{"code-secure:location", "syft:location:0:path", "SrcFile"}
+
+// ParseCycloneDXBom reads a CycloneDX bom in json or xml format
+func ParseCycloneDXBom(data []byte) (*CycloneDXBom, error) {
+	content := strings.TrimSpace(string(data))
+	if strings.HasPrefix(content, "<") {
+		var xmlBom cycloneDXXMLBom
+		if err := xml.Unmarshal(data, &xmlBom); err != nil {
+			return nil, err
+		}
+		bom := xmlBom.fromXML()
+		return &bom, nil
+	}
+	var bom CycloneDXBom
+	if err := json.Unmarshal(data, &bom); err != nil {
+		return nil, err
+	}
+	if bom.BomFormat != "CycloneDX" {
+		return nil, errors.New("not a CycloneDX bom")
+	}
+	return &bom, nil
+}
+
+// ScaResult converts the components, dependencies and vulnerabilities of the bom to a sca result.
+// The PkgId of a package is the bom-ref of its component
+func (bom CycloneDXBom) ScaResult() ScaResult {
+	var result ScaResult
+	pkgIds := make(map[string]string)
+	var walk func(components []CycloneDXComponent)
+	walk = func(components []CycloneDXComponent) {
+		for _, component := range components {
+			switch component.Type {
+			case "library", "framework", "application":
+				pkg := cycloneDXPackage(component)
+				if _, ok := pkgIds[pkg.PkgId]; !ok {
+					pkgIds[pkg.PkgId] = pkg.PkgId
+					if component.BomRef != "" {
+						pkgIds[component.BomRef] = pkg.PkgId
+					}
+					result.Packages = append(result.Packages, pkg)
+				}
+			}
+			walk(component.Components)
+		}
+	}
+	walk(bom.Components)
+	for _, dependency := range bom.Dependencies {
+		pkgId, ok := pkgIds[dependency.Ref]
+		if !ok {
+			continue
+		}
+		item := PackageDependency{PkgId: pkgId}
+		for _, ref := range dependency.DependsOn {
+			if child, ok := pkgIds[ref]; ok {
+				item.Dependencies = append(item.Dependencies, child)
+			}
+		}
+		if len(item.Dependencies) > 0 {
+			result.PackageDependencies = append(result.PackageDependencies, item)
+		}
+	}
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	for _, vulnerability := range bom.Vulnerabilities {
+		for _, affect := range vulnerability.Affects {
+			pkgId, ok := pkgIds[affect.Ref]
+			if !ok {
+				logger.Warn(fmt.Sprintf("vulnerability %s affects unknown component %s", vulnerability.ID, affect.Ref))
+				continue
+			}
+			pkg := packages[pkgId]
+			item := Vulnerability{
+				Identity:     vulnerability.ID,
+				Name:         vulnerability.ID,
+				Description:  vulnerability.Description,
+				FixedVersion: cycloneDXFixedVersion(vulnerability, affect),
+				PkgId:        pkgId,
+				PkgName:      packageName(pkg),
+				Metadata:     &FindingMetadata{},
+			}
+			if vulnerability.Detail != "" {
+				if item.Description == "" {
+					item.Description = vulnerability.Detail
+				} else {
+					item.Name = vulnerability.Detail
+				}
+			}
+			if vulnerability.Published != "" {
+				item.PublishedAt = Ptr(vulnerability.Published)
+			}
+			item.Severity = cycloneDXRatingSeverity(vulnerability.Ratings)
+			if rating := cycloneDXCvssRating(vulnerability.Ratings); rating != nil {
+				item.Metadata.Cvss = Ptr(rating.Vector)
+				if rating.Score != nil {
+					item.Metadata.CvssScore = Ptr(strconv.FormatFloat(*rating.Score, 'f', -1, 64))
+				}
+			}
+			for _, cwe := range vulnerability.Cwes {
+				item.Metadata.Cwes = append(item.Metadata.Cwes, fmt.Sprintf("CWE-%d", cwe))
+			}
+			for _, advisory := range vulnerability.Advisories {
+				item.Metadata.References = append(item.Metadata.References, advisory.URL)
+			}
+			result.Vulnerabilities = append(result.Vulnerabilities, item)
+		}
+	}
+	return result
+}
+
+func cycloneDXPackage(component CycloneDXComponent) Package {
+	pkg := Package{
+		Group:   component.Group,
+		Name:    component.Name,
+		Version: component.Version,
+	}
+	if component.Purl != "" {
+		purlPackage, err := ParsePackageURL(component.Purl)
+		if err != nil {
+			logger.Warn(err.Error())
+		} else {
+			pkg.Group, pkg.Name, pkg.Type = purlPackage.Group, purlPackage.Name, purlPackage.Type
+			if pkg.Version == "" {
+				pkg.Version = purlPackage.Version
+			}
+		}
+	}
+	switch {
+	case component.BomRef != "":
+		pkg.PkgId = component.BomRef
+	case component.Purl != "":
+		pkg.PkgId = component.Purl
+	default:
+		pkg.PkgId = packageName(pkg) + "@" + pkg.Version
+	}
+	var licenses []string
+	for _, license := range component.Licenses {
+		switch {
+		case license.Expression != "":
+			licenses = append(licenses, license.Expression)
+		case license.License != nil && license.License.ID != "":
+			licenses = append(licenses, license.License.ID)
+		case license.License != nil && license.License.Name != "":
+			licenses = append(licenses, license.License.Name)
+		}
+	}
+	pkg.License = strings.Join(licenses, " AND ")
+	for _, name := range cycloneDXLocationProperties {
+		for _, property := range component.Properties {
+			if property.Name == name && property.Value != "" && pkg.Location == nil {
+				pkg.Location = Ptr(strings.TrimPrefix(property.Value, "/"))
+			}
+		}
+	}
+	return pkg
+}
+
+func cycloneDXFixedVersion(vulnerability CycloneDXVulnerability, affect CycloneDXAffect) string {
+	for _, version := range affect.Versions {
+		if version.Status == "unaffected" && version.Version != "" {
+			return version.Version
+		}
+	}
+	if match := cycloneDXUpgradeRegex.FindStringSubmatch(vulnerability.Recommendation); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// cycloneDXRatingSeverity returns the highest severity of the ratings, falling back to the score
+func cycloneDXRatingSeverity(ratings []CycloneDXRating) Severity {
+	result := SeverityInfo
+	for _, rating := range ratings {
+		severity, ok := ParseSeverity(rating.Severity)
+		if !ok && rating.Score != nil {
+			severity = cvssSeverity(*rating.Score)
+		}
+		if severity.Rank() > result.Rank() {
+			result = severity
+		}
+	}
+	return result
+}
+
+func cvssSeverity(score float64) Severity {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	default:
+		return SeverityInfo
+	}
+}
+
+// cycloneDXCvssRating prefers the newest cvss version which has a vector
+func cycloneDXCvssRating(ratings []CycloneDXRating) *CycloneDXRating {
+	for _, method := range []string{"CVSSv4", "CVSSv31", "CVSSv3", "CVSSv2"} {
+		for index := range ratings {
+			if ratings[index].Method == method && ratings[index].Vector != "" {
+				return &ratings[index]
+			}
+		}
+	}
+	return nil
+}
+
+func (bom cycloneDXXMLBom) fromXML() CycloneDXBom {
+	result := CycloneDXBom{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  strings.TrimPrefix(bom.Xmlns, "http://cyclonedx.org/schema/bom/"),
+		SerialNumber: bom.SerialNumber,
+		Version:      bom.Version,
+	}
+	if bom.Components != nil {
+		result.Components = fromXMLComponents(*bom.Components)
+	}
+	if bom.Dependencies != nil {
+		// nested dependency elements are flattened to one entry per ref
+		var walk func(dependencies []cycloneDXXMLDependency)
+		walk = func(dependencies []cycloneDXXMLDependency) {
+			for _, dependency := range dependencies {
+				item := CycloneDXDependency{Ref: dependency.Ref}
+				for _, child := range dependency.Dependencies {
+					item.DependsOn = append(item.DependsOn, child.Ref)
+				}
+				if len(item.DependsOn) > 0 {
+					result.Dependencies = append(result.Dependencies, item)
+				}
+				walk(dependency.Dependencies)
+			}
+		}
+		walk(bom.Dependencies.Dependencies)
+	}
+	if bom.Vulnerabilities != nil {
+		for _, vulnerability := range bom.Vulnerabilities.Vulnerabilities {
+			result.Vulnerabilities = append(result.Vulnerabilities, fromXMLVulnerability(vulnerability))
+		}
+	}
+	return result
+}
+
+func fromXMLComponents(components cycloneDXXMLComponents) []CycloneDXComponent {
+	var result []CycloneDXComponent
+	for _, component := range components.Components {
+		item := CycloneDXComponent{
+			Type:       component.Type,
+			BomRef:     component.BomRef,
+			Group:      component.Group,
+			Name:       component.Name,
+			Version:    component.Version,
+			Purl:       component.Purl,
+			Properties: fromXMLProperties(component.Properties),
+		}
+		if component.Licenses != nil {
+			for _, license := range component.Licenses.Licenses {
+				item.Licenses = append(item.Licenses, CycloneDXLicense{License: &CycloneDXLicenseID{ID: license.ID, Name: license.Name}})
+			}
+			if component.Licenses.Expression != "" {
+				item.Licenses = append(item.Licenses, CycloneDXLicense{Expression: component.Licenses.Expression})
+			}
+		}
+		if component.Components != nil {
+			item.Components = fromXMLComponents(*component.Components)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func fromXMLProperties(properties *cycloneDXXMLProperties) []CycloneDXProperty {
+	if properties == nil {
+		return nil
+	}
+	var result []CycloneDXProperty
+	for _, property := range properties.Properties {
+		result = append(result, CycloneDXProperty{Name: property.Name, Value: property.Value})
+	}
+	return result
+}
+
+func fromXMLVulnerability(vulnerability cycloneDXXMLVulnerability) CycloneDXVulnerability {
+	result := CycloneDXVulnerability{
+		BomRef:         vulnerability.BomRef,
+		ID:             vulnerability.ID,
+		Source:         vulnerability.Source,
+		Description:    vulnerability.Description,
+		Detail:         vulnerability.Detail,
+		Recommendation: vulnerability.Recommendation,
+		Published:      vulnerability.Published,
+		Properties:     fromXMLProperties(vulnerability.Properties),
+	}
+	if vulnerability.Ratings != nil {
+		for _, rating := range vulnerability.Ratings.Ratings {
+			result.Ratings = append(result.Ratings, CycloneDXRating(rating))
+		}
+	}
+	if vulnerability.Cwes != nil {
+		result.Cwes = vulnerability.Cwes.Cwes
+	}
+	if vulnerability.Advisories != nil {
+		result.Advisories = vulnerability.Advisories.Advisories
+	}
+	if vulnerability.Analysis != nil {
+		result.Analysis = &CycloneDXAnalysis{
+			State:         vulnerability.Analysis.State,
+			Justification: vulnerability.Analysis.Justification,
+			Detail:        vulnerability.Analysis.Detail,
+		}
+		if vulnerability.Analysis.Responses != nil {
+			result.Analysis.Response = vulnerability.Analysis.Responses.Responses
+		}
+	}
+	if vulnerability.Affects != nil {
+		for _, target := range vulnerability.Affects.Targets {
+			affect := CycloneDXAffect{Ref: target.Ref}
+			if target.Versions != nil {
+				affect.Versions = target.Versions.Versions
+			}
+			result.Affects = append(result.Affects, affect)
+		}
+	}
+	return result
+}
+
+// CycloneDXScanner is a sca scanner reading the CycloneDX bom produced by an SBOM tool (syft, cdxgen, maven plugin)
+type CycloneDXScanner struct {
+	name string
+	path string
+}
+
+func NewCycloneDXScanner(name, path string) *CycloneDXScanner {
+	return &CycloneDXScanner{name: name, path: path}
+}
+
+func (scanner *CycloneDXScanner) Name() string {
+	return scanner.name
+}
+
+func (scanner *CycloneDXScanner) Type() ScannerType {
+	return ScannerTypeDependency
+}
+
+func (scanner *CycloneDXScanner) Scan() (*ScaResult, error) {
+	data, err := os.ReadFile(scanner.path)
+	if err != nil {
+		return nil, err
+	}
+	bom, err := ParseCycloneDXBom(data)
+	if err != nil {
+		return nil, err
+	}
+	result := bom.ScaResult()
+	return &result, nil
+}
@@ -0,0 +1,550 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+const (
+	spdxVersion        = "SPDX-2.3"
+	spdxDocumentID     = "SPDXRef-DOCUMENT"
+	spdxPackagePrefix  = "SPDXRef-Package-"
+	spdxNoAssertion    = "NOASSERTION"
+	spdxLocationPrefix = "Location: "
+)
+
+var spdxIDInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+type SpdxDocument struct {
+	SpdxVersion                string                   `json:"spdxVersion"`
+	DataLicense                string                   `json:"dataLicense"`
+	SPDXID                     string                   `json:"SPDXID"`
+	Name                       string                   `json:"name"`
+	DocumentNamespace          string                   `json:"documentNamespace"`
+	CreationInfo               SpdxCreationInfo         `json:"creationInfo"`
+	Packages                   []SpdxPackage            `json:"packages,omitempty"`
+	Relationships              []SpdxRelationship       `json:"relationships,omitempty"`
+	HasExtractedLicensingInfos []SpdxExtractedLicensing `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type SpdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type SpdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded,omitempty"`
+	LicenseDeclared  string            `json:"licenseDeclared,omitempty"`
+	CopyrightText    string            `json:"copyrightText,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []SpdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type SpdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type SpdxRelationship struct {
+	SpdxElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+type SpdxExtractedLicensing struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name,omitempty"`
+}
+
+// NewSpdxDocument converts a sca result to a SPDX 2.3 document. Packages without a parent are described by the document
+func NewSpdxDocument(result ScaResult, name string) SpdxDocument {
+	if name == "" {
+		name = AnalyzerName
+	}
+	document := SpdxDocument{
+		SpdxVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            spdxDocumentID,
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", spdxIDInvalidChars.ReplaceAllString(name, "-"), newUUID()),
+		CreationInfo: SpdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{fmt.Sprintf("Tool: %s-%s", AnalyzerName, analyzerVersion())},
+		},
+	}
+	ids := make(map[string]string)
+	used := make(map[string]bool)
+	extracted := make(map[string]bool)
+	for _, pkg := range result.Packages {
+		if _, ok := ids[pkg.PkgId]; ok {
+			continue
+		}
+		id := spdxPackageID(pkg, used)
+		ids[pkg.PkgId] = id
+		item := SpdxPackage{
+			SPDXID:           id,
+			Name:             spdxPackageName(pkg),
+			VersionInfo:      pkg.Version,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+			ExternalRefs: []SpdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  PackageURL(pkg),
+			}},
+		}
+		if pkg.License != "" {
+			license, info := spdxLicense(pkg.License)
+			item.LicenseDeclared = license
+			if info != nil && !extracted[info.LicenseID] {
+				extracted[info.LicenseID] = true
+				document.HasExtractedLicensingInfos = append(document.HasExtractedLicensingInfos, *info)
+			}
+		}
+		if pkg.Location != nil && *pkg.Location != "" {
+			item.Comment = spdxLocationPrefix + *pkg.Location
+		}
+		document.Packages = append(document.Packages, item)
+	}
+	hasParent := make(map[string]bool)
+	var dependencies []SpdxRelationship
+	for _, dependency := range result.PackageDependencies {
+		id, ok := ids[dependency.PkgId]
+		if !ok {
+			continue
+		}
+		for _, child := range dependency.Dependencies {
+			childID, ok := ids[child]
+			if !ok {
+				continue
+			}
+			hasParent[childID] = true
+			dependencies = append(dependencies, SpdxRelationship{
+				SpdxElementID:      id,
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSpdxElement: childID,
+			})
+		}
+	}
+	for _, pkg := range document.Packages {
+		if !hasParent[pkg.SPDXID] {
+			document.Relationships = append(document.Relationships, SpdxRelationship{
+				SpdxElementID:      spdxDocumentID,
+				RelationshipType:   "DESCRIBES",
+				RelatedSpdxElement: pkg.SPDXID,
+			})
+		}
+	}
+	document.Relationships = append(document.Relationships, dependencies...)
+	return document
+}
+
+func (document SpdxDocument) JSON() ([]byte, error) {
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// TagValue renders the document in the SPDX tag-value format
+func (document SpdxDocument) TagValue() []byte {
+	var buffer bytes.Buffer
+	writeTag := func(tag, value string) {
+		if value == "" {
+			return
+		}
+		if strings.Contains(value, "\n") {
+			value = "<text>" + value + "</text>"
+		}
+		buffer.WriteString(tag + ": " + value + "\n")
+	}
+	writeTag("SPDXVersion", document.SpdxVersion)
+	writeTag("DataLicense", document.DataLicense)
+	writeTag("SPDXID", document.SPDXID)
+	writeTag("DocumentName", document.Name)
+	writeTag("DocumentNamespace", document.DocumentNamespace)
+	for _, creator := range document.CreationInfo.Creators {
+		writeTag("Creator", creator)
+	}
+	writeTag("Created", document.CreationInfo.Created)
+	for _, pkg := range document.Packages {
+		buffer.WriteString("\n##### Package: " + pkg.Name + "\n\n")
+		writeTag("PackageName", pkg.Name)
+		writeTag("SPDXID", pkg.SPDXID)
+		writeTag("PackageVersion", pkg.VersionInfo)
+		writeTag("PackageDownloadLocation", pkg.DownloadLocation)
+		writeTag("FilesAnalyzed", fmt.Sprintf("%t", pkg.FilesAnalyzed))
+		writeTag("PackageLicenseConcluded", pkg.LicenseConcluded)
+		writeTag("PackageLicenseDeclared", pkg.LicenseDeclared)
+		writeTag("PackageCopyrightText", pkg.CopyrightText)
+		writeTag("PackageComment", pkg.Comment)
+		for _, ref := range pkg.ExternalRefs {
+			writeTag("ExternalRef", fmt.Sprintf("%s %s %s", ref.ReferenceCategory, ref.ReferenceType, ref.ReferenceLocator))
+		}
+	}
+	if len(document.Relationships) > 0 {
+		buffer.WriteString("\n##### Relationships\n\n")
+		for _, relationship := range document.Relationships {
+			writeTag("Relationship", fmt.Sprintf("%s %s %s", relationship.SpdxElementID, relationship.RelationshipType, relationship.RelatedSpdxElement))
+		}
+	}
+	if len(document.HasExtractedLicensingInfos) > 0 {
+		buffer.WriteString("\n##### Extracted Licenses\n\n")
+		for _, license := range document.HasExtractedLicensingInfos {
+			writeTag("LicenseID", license.LicenseID)
+			// extracted text is always wrapped so license texts with special characters are preserved
+			buffer.WriteString("ExtractedText: <text>" + license.ExtractedText + "</text>\n")
+			writeTag("LicenseName", license.Name)
+		}
+	}
+	return buffer.Bytes()
+}
+
+// ScaResult converts the packages and dependency relationships of the document to a sca result
+func (document SpdxDocument) ScaResult() ScaResult {
+	var result ScaResult
+	licenseNames := make(map[string]string)
+	for _, license := range document.HasExtractedLicensingInfos {
+		licenseNames[license.LicenseID] = license.Name
+		if license.Name == "" {
+			licenseNames[license.LicenseID] = license.ExtractedText
+		}
+	}
+	pkgIds := make(map[string]string)
+	for _, item := range document.Packages {
+		pkg := Package{Name: item.Name, Version: item.VersionInfo}
+		for _, ref := range item.ExternalRefs {
+			if ref.ReferenceType != "purl" {
+				continue
+			}
+			purlPackage, err := ParsePackageURL(ref.ReferenceLocator)
+			if err != nil {
+				logger.Warn(err.Error())
+				continue
+			}
+			pkg.Group, pkg.Name, pkg.Type = purlPackage.Group, purlPackage.Name, purlPackage.Type
+			if pkg.Version == "" {
+				pkg.Version = purlPackage.Version
+			}
+			break
+		}
+		pkg.PkgId = strings.TrimPrefix(item.SPDXID, spdxPackagePrefix)
+		pkg.License = spdxLicenseValue(item.LicenseDeclared, licenseNames)
+		if pkg.License == "" {
+			pkg.License = spdxLicenseValue(item.LicenseConcluded, licenseNames)
+		}
+		if strings.HasPrefix(item.Comment, spdxLocationPrefix) {
+			pkg.Location = Ptr(strings.TrimPrefix(item.Comment, spdxLocationPrefix))
+		}
+		pkgIds[item.SPDXID] = pkg.PkgId
+		result.Packages = append(result.Packages, pkg)
+	}
+	dependencies := make(map[string][]string)
+	var parents []string
+	addDependency := func(parent, child string) {
+		parentId, ok := pkgIds[parent]
+		if !ok {
+			return
+		}
+		childId, ok := pkgIds[child]
+		if !ok {
+			return
+		}
+		if _, ok = dependencies[parentId]; !ok {
+			parents = append(parents, parentId)
+		}
+		dependencies[parentId] = append(dependencies[parentId], childId)
+	}
+	for _, relationship := range document.Relationships {
+		switch {
+		case relationship.RelationshipType == "DEPENDS_ON":
+			addDependency(relationship.SpdxElementID, relationship.RelatedSpdxElement)
+		case strings.HasSuffix(relationship.RelationshipType, "DEPENDENCY_OF"):
+			addDependency(relationship.RelatedSpdxElement, relationship.SpdxElementID)
+		}
+	}
+	for _, parent := range parents {
+		result.PackageDependencies = append(result.PackageDependencies, PackageDependency{
+			PkgId:        parent,
+			Dependencies: dependencies[parent],
+		})
+	}
+	return result
+}
+
+// ParseSpdxDocument reads a SPDX document in json or tag-value format
+func ParseSpdxDocument(data []byte) (*SpdxDocument, error) {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		var document SpdxDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(document.SpdxVersion, "SPDX-") {
+			return nil, errors.New("not a SPDX document")
+		}
+		return &document, nil
+	}
+	return parseSpdxTagValue(data)
+}
+
+func parseSpdxTagValue(data []byte) (*SpdxDocument, error) {
+	document := &SpdxDocument{}
+	var pkg *SpdxPackage
+	var license *SpdxExtractedLicensing
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tag, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid SPDX tag-value line: %s", line)
+		}
+		value = strings.TrimSpace(value)
+		// multi line values are wrapped in <text></text>
+		if strings.HasPrefix(value, "<text>") {
+			value = strings.TrimPrefix(value, "<text>")
+			for !strings.Contains(value, "</text>") && scanner.Scan() {
+				value += "\n" + scanner.Text()
+			}
+			value, _, _ = strings.Cut(value, "</text>")
+		}
+		switch tag {
+		case "SPDXVersion":
+			document.SpdxVersion = value
+		case "DataLicense":
+			document.DataLicense = value
+		case "DocumentName":
+			document.Name = value
+		case "DocumentNamespace":
+			document.DocumentNamespace = value
+		case "Creator":
+			document.CreationInfo.Creators = append(document.CreationInfo.Creators, value)
+		case "Created":
+			document.CreationInfo.Created = value
+		case "SPDXID":
+			if pkg != nil {
+				pkg.SPDXID = value
+			} else {
+				document.SPDXID = value
+			}
+		case "PackageName":
+			document.Packages = append(document.Packages, SpdxPackage{Name: value})
+			pkg = &document.Packages[len(document.Packages)-1]
+		case "PackageVersion", "PackageDownloadLocation", "FilesAnalyzed", "PackageLicenseConcluded",
+			"PackageLicenseDeclared", "PackageCopyrightText", "PackageComment", "ExternalRef":
+			if pkg == nil {
+				return nil, fmt.Errorf("%s must follow PackageName", tag)
+			}
+			setSpdxPackageTag(pkg, tag, value)
+		case "Relationship":
+			fields := strings.Fields(value)
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("invalid SPDX relationship: %s", value)
+			}
+			document.Relationships = append(document.Relationships, SpdxRelationship{
+				SpdxElementID:      fields[0],
+				RelationshipType:   fields[1],
+				RelatedSpdxElement: fields[2],
+			})
+		case "LicenseID":
+			document.HasExtractedLicensingInfos = append(document.HasExtractedLicensingInfos, SpdxExtractedLicensing{LicenseID: value})
+			license = &document.HasExtractedLicensingInfos[len(document.HasExtractedLicensingInfos)-1]
+		case "ExtractedText", "LicenseName":
+			if license == nil {
+				return nil, fmt.Errorf("%s must follow LicenseID", tag)
+			}
+			if tag == "ExtractedText" {
+				license.ExtractedText = value
+			} else {
+				license.Name = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(document.SpdxVersion, "SPDX-") {
+		return nil, errors.New("not a SPDX document")
+	}
+	return document, nil
+}
+
+func setSpdxPackageTag(pkg *SpdxPackage, tag, value string) {
+	switch tag {
+	case "PackageVersion":
+		pkg.VersionInfo = value
+	case "PackageDownloadLocation":
+		pkg.DownloadLocation = value
+	case "FilesAnalyzed":
+		pkg.FilesAnalyzed = value == "true"
+	case "PackageLicenseConcluded":
+		pkg.LicenseConcluded = value
+	case "PackageLicenseDeclared":
+		pkg.LicenseDeclared = value
+	case "PackageCopyrightText":
+		pkg.CopyrightText = value
+	case "PackageComment":
+		pkg.Comment = value
+	case "ExternalRef":
+		fields := strings.Fields(value)
+		if len(fields) == 3 {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, SpdxExternalRef{
+				ReferenceCategory: fields[0],
+				ReferenceType:     fields[1],
+				ReferenceLocator:  fields[2],
+			})
+		}
+	}
+}
+
+func spdxPackageID(pkg Package, used map[string]bool) string {
+	value := pkg.PkgId
+	if value == "" {
+		value = pkg.Name + "-" + pkg.Version
+	}
+	id := spdxPackagePrefix + strings.Trim(spdxIDInvalidChars.ReplaceAllString(value, "-"), "-")
+	result := id
+	for index := 1; used[result]; index++ {
+		result = fmt.Sprintf("%s-%d", id, index)
+	}
+	used[result] = true
+	return result
+}
+
+func spdxPackageName(pkg Package) string {
+	if pkg.Group != "" {
+		return pkg.Group + ":" + pkg.Name
+	}
+	return pkg.Name
+}
+
+// spdxLicense converts a declared license to a SPDX license expression. Licenses that are not on the SPDX list are
+// referenced by a LicenseRef and returned as extracted licensing info
+func spdxLicense(license string) (string, *SpdxExtractedLicensing) {
+	license = strings.TrimSpace(license)
+	normalized := NormalizeLicense(license)
+	if _, ok := SpdxLicenseID(normalized); ok || IsLicenseExpression(normalized) {
+		return normalized, nil
+	}
+	id := "LicenseRef-" + strings.Trim(spdxIDInvalidChars.ReplaceAllString(license, "-"), "-")
+	return id, &SpdxExtractedLicensing{LicenseID: id, ExtractedText: license, Name: license}
+}
+
+func spdxLicenseValue(license string, licenseNames map[string]string) string {
+	switch license {
+	case "", spdxNoAssertion, "NONE":
+		return ""
+	}
+	if name, ok := licenseNames[license]; ok && name != "" {
+		return name
+	}
+	return license
+}
+
+// SpdxHandler writes the sca result as a SPDX 2.3 document in json or tag-value format
+type SpdxHandler struct {
+	output string
+	format string
+}
+
+func NewSpdxHandler() (*SpdxHandler, error) {
+	output := os.Getenv("SPDX_OUTPUT")
+	if output == "" {
+		output = "code-secure.spdx.json"
+	}
+	format := strings.ToLower(os.Getenv("SPDX_FORMAT"))
+	if format == "" {
+		format = "json"
+		if strings.HasSuffix(output, ".spdx") {
+			format = "tag-value"
+		}
+	}
+	if format != "json" && format != "tag-value" {
+		return nil, fmt.Errorf("invalid SPDX_FORMAT: %s", format)
+	}
+	return &SpdxHandler{output: output, format: format}, nil
+}
+
+func (handler *SpdxHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	return &CiScanInfo{}, nil
+}
+
+func (handler *SpdxHandler) OnCompleted() {}
+
+func (handler *SpdxHandler) OnError(err error) {}
+
+func (handler *SpdxHandler) HandleSastFindings(input HandleSastFindingPros) {
+	logger.Warn("spdx output only supports sca result")
+}
+
+func (handler *SpdxHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	name := ""
+	if sourceManager != nil {
+		name = sourceManager.ProjectName()
+	}
+	document := NewSpdxDocument(result, name)
+	data := document.TagValue()
+	if handler.format == "json" {
+		var err error
+		data, err = document.JSON()
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+	}
+	logger.Info("Save SPDX SBOM to: " + handler.output)
+	err := os.WriteFile(handler.output, data, 0644)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// SpdxScanner is a sca scanner reading the SPDX document produced by an SBOM tool
+type SpdxScanner struct {
+	name string
+	path string
+}
+
+func NewSpdxScanner(name, path string) *SpdxScanner {
+	return &SpdxScanner{name: name, path: path}
+}
+
+func (scanner *SpdxScanner) Name() string {
+	return scanner.name
+}
+
+func (scanner *SpdxScanner) Type() ScannerType {
+	return ScannerTypeDependency
+}
+
+func (scanner *SpdxScanner) Scan() (*ScaResult, error) {
+	data, err := os.ReadFile(scanner.path)
+	if err != nil {
+		return nil, err
+	}
+	document, err := ParseSpdxDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	result := document.ScaResult()
+	return &result, nil
+}
@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"os"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+// securityGate publishes the block decision as commit status and merge request gate. A nil securityGate is a no-op
+type securityGate struct {
+	gate       git.SecurityGate
+	name       string
+	targetURL  string
+	withStatus bool
+	withMRGate bool
+}
+
+func newSecurityGate(sourceManager git.GitEnv, scannerName string, targetURL string) *securityGate {
+	withStatus := os.Getenv("GITLAB_COMMIT_STATUS") == "true"
+	withMRGate := os.Getenv("GITLAB_MR_GATE") != ""
+	if !withStatus && !withMRGate {
+		return nil
+	}
+	gate, ok := sourceManager.(git.SecurityGate)
+	if !ok {
+		logger.Warn(sourceManager.Provider() + " does not support commit status")
+		return nil
+	}
+	result := &securityGate{
+		gate:       gate,
+		name:       "code-secure/" + scannerName,
+		targetURL:  targetURL,
+		withStatus: withStatus,
+		withMRGate: withMRGate,
+	}
+	result.setStatus(git.CommitStatusRunning, "Scanning")
+	return result
+}
+
+func (gate *securityGate) complete(isBlock bool) {
+	if gate == nil {
+		return
+	}
+	if isBlock {
+		gate.setStatus(git.CommitStatusFailed, "Blocked due security config")
+	} else {
+		gate.setStatus(git.CommitStatusSuccess, "No blocking findings")
+	}
+	if gate.withMRGate {
+		err := gate.gate.SetMergeRequestBlocked(isBlock, "blocking findings of "+gate.name)
+		if err != nil {
+			logger.Error(err.Error())
+		}
+	}
+}
+
+func (gate *securityGate) fail(err error) {
+	if gate == nil {
+		return
+	}
+	gate.setStatus(git.CommitStatusFailed, "Scan error: "+err.Error())
+}
+
+func (gate *securityGate) setStatus(state string, description string) {
+	if !gate.withStatus {
+		return
+	}
+	err := gate.gate.SetCommitStatus(git.CommitStatusOption{
+		Name:        gate.name,
+		State:       state,
+		TargetURL:   gate.targetURL,
+		Description: truncate(description, 255),
+	})
+	if err != nil {
+		logger.Error(err.Error())
+	}
+}
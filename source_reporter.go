@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+// sourceReporter reports a scan to the source manager: check run, commit status, merge request gate and SARIF upload.
+// A nil sourceReporter is a no-op
+type sourceReporter struct {
+	scannerName  string
+	checkRun     *checkRun
+	securityGate *securityGate
+}
+
+func newSourceReporter(sourceManager git.GitEnv, scannerName string, detailsURL string) *sourceReporter {
+	return &sourceReporter{
+		scannerName:  scannerName,
+		checkRun:     newCheckRun(sourceManager, scannerName, detailsURL),
+		securityGate: newSecurityGate(sourceManager, scannerName, detailsURL),
+	}
+}
+
+func (reporter *sourceReporter) handleSastFindings(input HandleSastFindingPros) {
+	if reporter == nil {
+		return
+	}
+	reporter.checkRun.annotateSastFindings(input.Result.Findings)
+	uploadSarif(input.SourceManager, reporter.scannerName, input.Strategy, input.Result.Findings)
+}
+
+func (reporter *sourceReporter) handleSCA(result ScaResult) {
+	if reporter == nil {
+		return
+	}
+	reporter.checkRun.annotateVulnerabilities(result)
+	reporter.checkRun.annotateLicenseViolations(result)
+}
+
+func (reporter *sourceReporter) complete(isBlock bool) {
+	if reporter == nil {
+		return
+	}
+	reporter.checkRun.complete(isBlock)
+	reporter.securityGate.complete(isBlock)
+}
+
+func (reporter *sourceReporter) fail(err error) {
+	if reporter == nil {
+		return
+	}
+	reporter.checkRun.fail(err)
+	reporter.securityGate.fail(err)
+}
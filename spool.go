@@ -0,0 +1,280 @@
+package analyzer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/califio/code-secure-analyzer/git"
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+type OfflineMode string
+
+const (
+	// OfflineModeFail stops the pipeline when Code Secure is unreachable
+	OfflineModeFail OfflineMode = "fail"
+	// OfflineModeSpool reports the results locally and spools them to be uploaded later
+	OfflineModeSpool OfflineMode = "spool"
+)
+
+const (
+	defaultSpoolDir = ".code-secure/spool"
+	spoolVersion    = 1
+)
+
+var spoolFileNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// offlineMode reads CODE_SECURE_OFFLINE_MODE, fail by default
+func offlineMode() OfflineMode {
+	mode := OfflineMode(strings.ToLower(os.Getenv("CODE_SECURE_OFFLINE_MODE")))
+	switch mode {
+	case "":
+		return OfflineModeFail
+	case OfflineModeFail, OfflineModeSpool:
+		return mode
+	}
+	logger.Warn("invalid CODE_SECURE_OFFLINE_MODE: " + string(mode))
+	return OfflineModeFail
+}
+
+// spoolDir reads CODE_SECURE_SPOOL_DIR. The directory must be kept between pipelines (e.g. cache) to replay the scans
+func spoolDir() string {
+	if dir := os.Getenv("CODE_SECURE_SPOOL_DIR"); dir != "" {
+		return dir
+	}
+	return defaultSpoolDir
+}
+
+// spoolKey reads the signing key of the spool files from CODE_SECURE_SPOOL_KEY, the token by default. It is required
+// with OIDC authentication
+func spoolKey(token string) string {
+	if key := os.Getenv("CODE_SECURE_SPOOL_KEY"); key != "" {
+		return key
+	}
+	return token
+}
+
+// SpoolRecord is a scan which could not reach the server, with everything needed to upload it later
+type SpoolRecord struct {
+	CreatedAt    time.Time                `json:"createdAt"`
+	Scan         CiScanRequest            `json:"scan"`
+	Findings     *UploadFindingRequest    `json:"findings,omitempty"`
+	Dependencies *UploadDependencyRequest `json:"dependencies,omitempty"`
+}
+
+// spoolFile signs the record with HMAC-SHA256 so that a replay does not upload results altered in the cache
+type spoolFile struct {
+	Version   int             `json:"version"`
+	Record    json.RawMessage `json:"record"`
+	Signature string          `json:"signature"`
+}
+
+func spoolSignature(key string, record []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(record)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WriteSpoolRecord saves a signed record in dir, the name of the file keeps the order of the scans
+func WriteSpoolRecord(dir, key string, record SpoolRecord) (string, error) {
+	if key == "" {
+		return "", errors.New("there is no key to sign the spool file")
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal(spoolFile{Version: spoolVersion, Record: data, Signature: spoolSignature(key, data)})
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%020d-%s.json", record.CreatedAt.UnixNano(), spoolFileNameRegex.ReplaceAllString(record.Scan.Scanner, "_"))
+	output := filepath.Join(dir, name)
+	return output, os.WriteFile(output, content, 0600)
+}
+
+// ReadSpoolRecord reads a spool file and verifies its signature
+func ReadSpoolRecord(path, key string) (*SpoolRecord, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file spoolFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Version != spoolVersion {
+		return nil, fmt.Errorf("%s: unsupported spool version %d", path, file.Version)
+	}
+	if !hmac.Equal([]byte(file.Signature), []byte(spoolSignature(key, file.Record))) {
+		return nil, fmt.Errorf("%s: invalid signature", path)
+	}
+	var record SpoolRecord
+	if err = json.Unmarshal(file.Record, &record); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// ReplaySpool uploads the spooled scans of dir from the oldest one and removes them once uploaded. The replay stops
+// at the first scan which fails to keep the order of the scans, a file with an invalid signature is renamed to
+// .rejected and skipped. It returns the number of uploaded scans
+func ReplaySpool(client *Client, dir, key string) (int, error) {
+	if key == "" {
+		return 0, errors.New("there is no key to verify the spool files")
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(paths)
+	replayed := 0
+	for _, path := range paths {
+		record, err := ReadSpoolRecord(path, key)
+		if err != nil {
+			logger.Error(err.Error())
+			if renameErr := os.Rename(path, path+".rejected"); renameErr != nil {
+				return replayed, renameErr
+			}
+			continue
+		}
+		if err = replaySpoolRecord(client, *record); err != nil {
+			return replayed, fmt.Errorf("replay %s: %w", filepath.Base(path), err)
+		}
+		if err = os.Remove(path); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+func replaySpoolRecord(client *Client, record SpoolRecord) error {
+	scanInfo, err := client.InitScan(record.Scan)
+	if err != nil {
+		return err
+	}
+	if record.Findings != nil {
+		request := *record.Findings
+		request.ScanId = scanInfo.ScanId
+		_, err = client.UploadFinding(request)
+	}
+	if err == nil && record.Dependencies != nil {
+		request := *record.Dependencies
+		request.ScanId = scanInfo.ScanId
+		_, err = client.UploadDependency(request)
+	}
+	status := UpdateCIScanRequest{Status: Ptr(StatusCompleted)}
+	if err != nil {
+		status = UpdateCIScanRequest{Status: Ptr(StatusError), Description: Ptr(err.Error())}
+	}
+	if updateErr := client.UpdateScan(scanInfo.ScanId, status); updateErr != nil && err == nil {
+		err = updateErr
+	}
+	if err == nil {
+		logger.Info(fmt.Sprintf("spooled scan of %s (%s) uploaded: %s", record.Scan.Scanner, record.Scan.CommitHash, scanInfo.ScanUrl))
+	}
+	return err
+}
+
+// replayPendingSpool uploads the scans spooled by previous pipelines once the server is reachable again
+func replayPendingSpool(client *Client, key string) {
+	if key == "" {
+		logger.Warn("CODE_SECURE_SPOOL_KEY is not set, the spooled scans are not replayed")
+		return
+	}
+	replayed, err := ReplaySpool(client, spoolDir(), key)
+	if replayed > 0 {
+		logger.Info(fmt.Sprintf("%d spooled scans uploaded", replayed))
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Error(err.Error())
+	}
+}
+
+// SpoolHandler replaces the RemoteHandler when Code Secure is unreachable: the results are reported by a LocalHandler
+// and spooled with the scan request so that ReplaySpool uploads them later
+type SpoolHandler struct {
+	local  *LocalHandler
+	dir    string
+	key    string
+	record SpoolRecord
+}
+
+func NewSpoolHandler(dir, key string) *SpoolHandler {
+	return &SpoolHandler{local: NewLocalHandler(), dir: dir, key: key}
+}
+
+func (handler *SpoolHandler) OnStart(sourceManager git.GitEnv, scannerName string, scannerType ScannerType) (*CiScanInfo, error) {
+	policy, err := policyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	handler.start(sourceManager, scannerName, scannerType, policy)
+	return &CiScanInfo{}, nil
+}
+
+func (handler *SpoolHandler) start(sourceManager git.GitEnv, scannerName string, scannerType ScannerType, policy *Policy) {
+	handler.record = SpoolRecord{
+		CreatedAt: time.Now().UTC(),
+		Scan:      newCiScanRequest(sourceManager, scannerName, scannerType),
+	}
+	handler.local.start(sourceManager, scannerName, policy)
+}
+
+func (handler *SpoolHandler) OnCompleted() {
+	output, err := WriteSpoolRecord(handler.dir, handler.key, handler.record)
+	if err != nil {
+		logger.Error("failed to spool the scan: " + err.Error())
+	} else {
+		logger.Info("Save spooled scan to: " + output)
+	}
+	handler.local.OnCompleted()
+}
+
+func (handler *SpoolHandler) OnError(err error) {
+	handler.local.OnError(err)
+}
+
+func (handler *SpoolHandler) HandleSastFindings(input HandleSastFindingPros) {
+	handler.record.Findings = &UploadFindingRequest{
+		Findings:     input.Result.Findings,
+		Strategy:     input.Strategy,
+		ChangedFiles: input.ChangedFiles,
+	}
+	handler.local.HandleSastFindings(input)
+}
+
+func (handler *SpoolHandler) HandleSCA(sourceManager git.GitEnv, result ScaResult) {
+	handler.record.Dependencies = Ptr(newUploadDependencyRequest("", result))
+	handler.local.HandleSCA(sourceManager, result)
+}
+
+func (handler *SpoolHandler) IsBlock() bool {
+	return handler.local.IsBlock()
+}
+
+func (handler *SpoolHandler) DeferExit() {
+	handler.local.DeferExit()
+}
+
+func (handler *SpoolHandler) Required() bool {
+	return handler.local.Required()
+}
+
+func (handler *SpoolHandler) deferSourceReport() {
+	handler.local.deferSourceReport()
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+func errorServer(t *testing.T, status int, header map[string]string, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		for key, value := range header {
+			writer.Header().Set(key, value)
+		}
+		writer.WriteHeader(status)
+		_, _ = writer.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func noRetryClient(url string) *analyzer.Client {
+	client := analyzer.NewClient(url, "token")
+	client.SetRetryPolicy(analyzer.RetryPolicy{})
+	return client
+}
+
+func TestAPIErrorProblemDetails(t *testing.T) {
+	server := errorServer(t, http.StatusBadRequest, map[string]string{"Content-Type": "application/problem+json"},
+		`{"type": "https://tools.ietf.org/html/rfc9110#section-15.5.1", "title": "One or more validation errors occurred.", "status": 400, "traceId": "00-4bf92f3577b34da6-01", "errors": {"ScanId": ["The ScanId field is required."], "Findings": ["Too many findings."]}}`)
+	_, err := noRetryClient(server.URL).UploadFinding(analyzer.UploadFindingRequest{})
+	var apiError *analyzer.APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	expected := analyzer.APIError{
+		Operation:  "upload finding",
+		StatusCode: http.StatusBadRequest,
+		Message:    "One or more validation errors occurred. (Findings: Too many findings.; ScanId: The ScanId field is required.)",
+		RequestID:  "00-4bf92f3577b34da6-01",
+	}
+	if *apiError != expected {
+		t.Errorf("expected %+v, got %+v", expected, *apiError)
+	}
+}
+
+func TestAPIErrorCodeAndRequestID(t *testing.T) {
+	server := errorServer(t, http.StatusNotFound, map[string]string{"X-Request-Id": "req-42"},
+		`{"code": "scan_not_found", "message": "Scan 42 does not exist"}`)
+	err := noRetryClient(server.URL).UpdateScan("42", analyzer.UpdateCIScanRequest{})
+	if !analyzer.IsAPIError(err, http.StatusNotFound) {
+		t.Fatalf("expected a not found APIError, got %v", err)
+	}
+	expected := "update scan: 404 Not Found (scan_not_found): Scan 42 does not exist [request id: req-42]"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestAPIErrorProxyPage(t *testing.T) {
+	server := errorServer(t, http.StatusRequestEntityTooLarge, nil, "<html><body>413 Request Entity Too Large</body></html>")
+	_, err := noRetryClient(server.URL).UploadDependency(analyzer.UploadDependencyRequest{})
+	var apiError *analyzer.APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusRequestEntityTooLarge || apiError.Message != "" {
+		t.Errorf("expected an APIError without message, got %+v", err)
+	}
+}
+
+func TestInitScanWithoutScanId(t *testing.T) {
+	server := errorServer(t, http.StatusOK, map[string]string{"Content-Type": "application/json"}, `{}`)
+	if _, err := noRetryClient(server.URL).InitScan(analyzer.CiScanRequest{}); err == nil {
+		t.Error("a scan without id should be an error")
+	}
+}
+
+func TestRemoteHandlerReportsUploadError(t *testing.T) {
+	var description string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		switch {
+		case request.URL.Path == "/api/ci/ping":
+			writer.WriteHeader(http.StatusOK)
+		case request.URL.Path == "/api/ci/scan":
+			_, _ = writer.Write([]byte(`{"scanId": "scan-1", "scanUrl": "http://localhost/scan-1"}`))
+		case request.URL.Path == "/api/ci/finding":
+			writer.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = writer.Write([]byte(`{"code": "invalid_strategy", "message": "unknown scan strategy"}`))
+		case request.URL.Path == "/api/ci/scan/scan-1":
+			var body analyzer.UpdateCIScanRequest
+			_ = json.NewDecoder(request.Body).Decode(&body)
+			if body.Description != nil {
+				description = *body.Description
+			}
+		}
+	}))
+	defer server.Close()
+	t.Setenv("CODE_SECURE_MAX_RETRIES", "0")
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("CI_SERVER_URL", server.URL)
+	sourceManager, err := git.NewGitLab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := analyzer.NewRemoteHandler(server.URL, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.DeferExit()
+	if _, err = handler.OnStart(sourceManager, "semgrep", analyzer.ScannerTypeSast); err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: SastResult, SourceManager: sourceManager})
+	handler.OnCompleted()
+	if !strings.Contains(description, "upload finding: 422 Unprocessable Entity (invalid_strategy): unknown scan strategy") {
+		t.Errorf("the scan should fail with the upload error, got %q", description)
+	}
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+// queryServer serves 5 accepted risk findings of the project gitlab/42 by pages
+func queryServer(t *testing.T) (*httptest.Server, *[]string) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		query := request.URL.Query()
+		switch {
+		case request.URL.Path == "/api/ci/findings":
+			if query.Get("source") != "gitlab" || query.Get("repoId") != "42" || strings.Join(query["status"], ",") != "AcceptedRisk,Incorrect" {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			page, _ := strconv.Atoi(query.Get("page"))
+			size, _ := strconv.Atoi(query.Get("size"))
+			pages = append(pages, query.Get("page"))
+			result := analyzer.Page[analyzer.RemoteFinding]{Page: page, Size: size, Count: 5, PageCount: (5 + size - 1) / size}
+			for i := (page - 1) * size; i < min(page*size, 5); i++ {
+				result.Items = append(result.Items, analyzer.RemoteFinding{
+					SastFinding: analyzer.SastFinding{ID: strconv.Itoa(i), Name: "SQL Injection"},
+					Status:      analyzer.FindingStatusAcceptedRisk,
+				})
+			}
+			_ = json.NewEncoder(writer).Encode(result)
+		case request.URL.Path == "/api/ci/findings/a b":
+			_, _ = writer.Write([]byte(`{"id": "a b", "identity": "semgrep-1", "severity": "High", "status": "Confirmed", "createdAt": "2026-01-02T03:04:05Z"}`))
+		case request.URL.Path == "/api/ci/policy":
+			_, _ = writer.Write([]byte(`{"sast": {"blockSeverities": ["Critical", "High"], "newFindingsOnly": true}, "sca": {"blockSeverities": ["Critical"]}, "licenses": {"deny": ["GPL-*"]}}`))
+		case request.URL.Path == "/api/ci/scans":
+			if query.Get("page") == "2" {
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = fmt.Fprintf(writer, `{"items": [{"scanId": "scan-2", "status": "Completed"}, {"scanId": "scan-1", "status": "Error"}], "page": 1, "pageCount": 3}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &pages
+}
+
+var queryProject = analyzer.ProjectRef{Source: "gitlab", RepoId: "42"}
+
+func TestFindingsIterator(t *testing.T) {
+	server, pages := queryServer(t)
+	client := noRetryClient(server.URL)
+	query := analyzer.FindingQuery{
+		Project:  queryProject,
+		Status:   []analyzer.FindingStatus{analyzer.FindingStatusAcceptedRisk, analyzer.FindingStatusIncorrect},
+		PageSize: 2,
+	}
+	findings, err := analyzer.Collect(client.Findings(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 5 || findings[4].ID != "4" || findings[0].Status != analyzer.FindingStatusAcceptedRisk {
+		t.Errorf("expected the 5 findings, got %+v", findings)
+	}
+	if strings.Join(*pages, ",") != "1,2,3" {
+		t.Errorf("expected 3 pages, got %v", *pages)
+	}
+	*pages = nil
+	for finding := range client.Findings(query) {
+		if finding.ID == "0" {
+			break
+		}
+	}
+	if len(*pages) != 1 {
+		t.Errorf("a stopped iteration should not fetch the next pages, got %v", *pages)
+	}
+}
+
+func TestGetFinding(t *testing.T) {
+	server, _ := queryServer(t)
+	finding, err := noRetryClient(server.URL).GetFinding("a b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finding.Identity != "semgrep-1" || finding.Severity != analyzer.SeverityHigh || finding.Status != analyzer.FindingStatusConfirmed || finding.CreatedAt.Year() != 2026 {
+		t.Errorf("unexpected finding %+v", finding)
+	}
+}
+
+func TestGetSecurityPolicy(t *testing.T) {
+	server, _ := queryServer(t)
+	policy, err := noRetryClient(server.URL).GetSecurityPolicy(queryProject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.Sast.Blocks(analyzer.SeverityHigh) || policy.Sca.Blocks(analyzer.SeverityHigh) || !policy.Sast.NewFindingsOnly {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+	if policy.Licenses == nil || policy.Licenses.Check("GPL-3.0-only") != analyzer.LicenseActionDeny {
+		t.Errorf("expected the license policy, got %+v", policy.Licenses)
+	}
+}
+
+func TestScansIteratorError(t *testing.T) {
+	server, _ := queryServer(t)
+	scans, err := analyzer.Collect(noRetryClient(server.URL).Scans(analyzer.ScanQuery{Project: queryProject}))
+	if !analyzer.IsAPIError(err, http.StatusInternalServerError) {
+		t.Errorf("expected the error of the second page, got %v", err)
+	}
+	if len(scans) != 2 || scans[0].ScanId != "scan-2" || scans[1].Status != analyzer.StatusError {
+		t.Errorf("expected the scans of the first page, got %+v", scans)
+	}
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+// failingServer answers the first failures requests with status and the next ones with body
+func failingServer(t *testing.T, failures int32, status int, header map[string]string, body string) (*httptest.Server, *atomic.Int32) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if attempts.Add(1) <= failures {
+			for key, value := range header {
+				writer.Header().Set(key, value)
+			}
+			writer.WriteHeader(status)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &attempts
+}
+
+func retryClient(url string) *analyzer.Client {
+	client := analyzer.NewClient(url, "token")
+	client.SetRetryPolicy(analyzer.RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		MaxRetryAfter:  5 * time.Second,
+	})
+	return client
+}
+
+func TestClientRetriesUpload(t *testing.T) {
+	server, attempts := failingServer(t, 2, http.StatusBadGateway, nil, `{"isBlock": true}`)
+	response, err := retryClient(server.URL).UploadFinding(analyzer.UploadFindingRequest{ScanId: "scan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.IsBlock || attempts.Load() != 3 {
+		t.Errorf("expected a successful third attempt, got %+v after %d attempts", response, attempts.Load())
+	}
+}
+
+func TestClientDoesNotRetryInitScanAfterGatewayError(t *testing.T) {
+	server, attempts := failingServer(t, 1, http.StatusBadGateway, nil, `{"scanId": "scan"}`)
+	_, err := retryClient(server.URL).InitScan(analyzer.CiScanRequest{})
+	if err == nil {
+		t.Fatal("a gateway error should fail the scan creation")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("the scan creation may have been processed and should not be retried, got %d attempts", attempts.Load())
+	}
+}
+
+func TestClientHonorsRetryAfter(t *testing.T) {
+	server, attempts := failingServer(t, 1, http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}, `{"scanId": "scan"}`)
+	start := time.Now()
+	scanInfo, err := retryClient(server.URL).InitScan(analyzer.CiScanRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanInfo.ScanId != "scan" || attempts.Load() != 2 {
+		t.Errorf("expected the scan after 2 attempts, got %+v after %d attempts", scanInfo, attempts.Load())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("the client should wait for Retry-After, waited %s", elapsed)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	server, attempts := failingServer(t, 100, http.StatusServiceUnavailable, nil, `{}`)
+	err := retryClient(server.URL).UpdateScan("scan", analyzer.UpdateCIScanRequest{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts.Load() != 4 {
+		t.Errorf("expected 1 attempt and 3 retries, got %d attempts", attempts.Load())
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	server, attempts := failingServer(t, 1, http.StatusBadRequest, nil, `{}`)
+	_, err := retryClient(server.URL).UploadDependency(analyzer.UploadDependencyRequest{ScanId: "scan"})
+	if err == nil || attempts.Load() != 1 {
+		t.Errorf("a bad request should fail without retry, got %v after %d attempts", err, attempts.Load())
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	client := retryClient(server.URL)
+	client.SetRetryPolicy(analyzer.RetryPolicy{})
+	client.SetTimeout(20 * time.Millisecond)
+	start := time.Now()
+	if err := client.UpdateScan("scan", analyzer.UpdateCIScanRequest{}); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("the request should time out, took %s", elapsed)
+	}
+}
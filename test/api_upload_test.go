@@ -0,0 +1,182 @@
+package test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+// uploadServer implements the chunked upload protocol, failingChunk answers 500 the first time it is sent
+type uploadServer struct {
+	mu           sync.Mutex
+	capabilities string
+	failingChunk string
+	requests     []string
+	chunks       map[string][]analyzer.SastFinding
+	gzipped      bool
+	committed    []analyzer.SastFinding
+	scanStatus   string
+}
+
+func (server *uploadServer) start(t *testing.T) *httptest.Server {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		server.requests = append(server.requests, request.Method+" "+request.URL.Path)
+		var reader io.Reader = request.Body
+		if request.Header.Get("Content-Encoding") == "gzip" {
+			gzipReader, err := gzip.NewReader(request.Body)
+			if err != nil {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			server.gzipped = true
+			reader = gzipReader
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		path := request.URL.Path
+		switch {
+		case path == "/api/ci/capabilities":
+			if server.capabilities == "" {
+				writer.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = writer.Write([]byte(server.capabilities))
+		case path == "/api/ci/scan/scan-1/upload":
+			server.chunks = make(map[string][]analyzer.SastFinding)
+		case strings.HasPrefix(path, "/api/ci/scan/scan-1/upload/chunks/"):
+			index := strings.TrimPrefix(path, "/api/ci/scan/scan-1/upload/chunks/")
+			if index == server.failingChunk {
+				server.failingChunk = ""
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var body struct {
+				Findings []analyzer.SastFinding `json:"findings"`
+			}
+			_ = json.NewDecoder(reader).Decode(&body)
+			server.chunks[index] = body.Findings
+		case path == "/api/ci/scan/scan-1/upload/commit":
+			for index := 0; index < len(server.chunks); index++ {
+				server.committed = append(server.committed, server.chunks[fmt.Sprint(index)]...)
+			}
+			_, _ = writer.Write([]byte(`{"findingUrl": "http://localhost/scan-1"}`))
+		case path == "/api/ci/scan":
+			_, _ = writer.Write([]byte(`{"scanId": "scan-1"}`))
+		case path == "/api/ci/scan/scan-1":
+			var body analyzer.UpdateCIScanRequest
+			_ = json.NewDecoder(reader).Decode(&body)
+			if body.Status != nil {
+				server.scanStatus = string(*body.Status)
+			}
+		case path == "/api/ci/finding":
+			var body analyzer.UploadFindingRequest
+			_ = json.NewDecoder(reader).Decode(&body)
+			server.committed = body.Findings
+			_, _ = writer.Write([]byte(`{"findingUrl": "http://localhost/finding"}`))
+		}
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func uploadRequest(count int, descriptionSize int) analyzer.UploadFindingRequest {
+	request := analyzer.UploadFindingRequest{ScanId: "scan-1"}
+	for i := 0; i < count; i++ {
+		request.Findings = append(request.Findings, analyzer.SastFinding{
+			Identity:    fmt.Sprintf("finding-%d", i),
+			Description: strings.Repeat("x", descriptionSize),
+		})
+	}
+	return request
+}
+
+func TestUploadFindingChunksResume(t *testing.T) {
+	t.Setenv("CODE_SECURE_UPLOAD_CHUNK_SIZE", "2")
+	server := &uploadServer{capabilities: `{"chunkedUpload": true, "gzip": true}`, failingChunk: "1"}
+	client := noRetryClient(server.start(t).URL)
+	request := uploadRequest(5, 10000)
+	if _, err := client.UploadFinding(request); err == nil {
+		t.Fatal("the failing chunk should fail the upload")
+	}
+	server.requests = nil
+	response, err := client.UploadFinding(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.FindingUrl != "http://localhost/scan-1" {
+		t.Errorf("expected the response of the commit, got %+v", response)
+	}
+	expected := []string{"PUT /api/ci/scan/scan-1/upload/chunks/1", "PUT /api/ci/scan/scan-1/upload/chunks/2", "POST /api/ci/scan/scan-1/upload/commit"}
+	if strings.Join(server.requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the upload to resume with the missing chunks %v, got %v", expected, server.requests)
+	}
+	if len(server.committed) != 5 || server.committed[4].Identity != "finding-4" {
+		t.Errorf("expected the 5 findings in order, got %d findings", len(server.committed))
+	}
+	if !server.gzipped {
+		t.Error("the chunks should be compressed")
+	}
+}
+
+func TestUploadFindingMaxChunkSize(t *testing.T) {
+	server := &uploadServer{capabilities: `{"chunkedUpload": true, "maxChunkSize": 2}`}
+	client := noRetryClient(server.start(t).URL)
+	if _, err := client.UploadFinding(uploadRequest(1001, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.chunks) != 501 || server.gzipped {
+		t.Errorf("expected 501 uncompressed chunks, got %d chunks", len(server.chunks))
+	}
+}
+
+func TestUploadFindingFallback(t *testing.T) {
+	t.Setenv("CODE_SECURE_UPLOAD_CHUNK_SIZE", "2")
+	server := &uploadServer{}
+	client := noRetryClient(server.start(t).URL)
+	response, err := client.UploadFinding(uploadRequest(5, 10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.FindingUrl != "http://localhost/finding" || len(server.committed) != 5 || server.gzipped {
+		t.Errorf("expected a single uncompressed upload, got %+v with requests %v", response, server.requests)
+	}
+}
+
+func TestRemoteHandlerResumesUpload(t *testing.T) {
+	server := &uploadServer{capabilities: `{"chunkedUpload": true}`, failingChunk: "1"}
+	t.Setenv("CODE_SECURE_URL", server.start(t).URL)
+	t.Setenv("CODE_SECURE_TOKEN", "token")
+	t.Setenv("CODE_SECURE_MAX_RETRIES", "0")
+	t.Setenv("CODE_SECURE_UPLOAD_CHUNK_SIZE", "2")
+	t.Setenv("FINDING_OUTPUT", filepath.Join(t.TempDir(), "finding_results.json"))
+	t.Setenv("GITLAB_CI", "true")
+	sourceManager, err := git.NewGitLab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := analyzer.NewHandlerChain("remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.DeferExit()
+	if _, err = handler.OnStart(sourceManager, "semgrep", analyzer.ScannerTypeSast); err != nil {
+		t.Fatal(err)
+	}
+	request := uploadRequest(5, 10)
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: analyzer.SastResult{Findings: request.Findings}, SourceManager: sourceManager})
+	handler.OnCompleted()
+	if len(server.committed) != 5 || server.scanStatus != string(analyzer.StatusCompleted) {
+		t.Errorf("a chunk failing past the retries should be resumed, got %d findings and status %q with requests %v", len(server.committed), server.scanStatus, server.requests)
+	}
+}
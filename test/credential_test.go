@@ -0,0 +1,155 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+// oidcServer exchanges the id token "jwt" for the tokens short-1, short-2... and only accepts the last one
+type oidcServer struct {
+	mu        sync.Mutex
+	expiresIn int
+	status    int
+	exchanges int
+	audience  string
+}
+
+func (server *oidcServer) start(t *testing.T) *httptest.Server {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		writer.Header().Set("Content-Type", "application/json")
+		switch request.URL.Path {
+		case "/api/ci/auth/token":
+			_ = request.ParseForm()
+			if request.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:token-exchange" || request.Form.Get("subject_token") != "jwt" {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if server.status != 0 {
+				writer.WriteHeader(server.status)
+				_, _ = writer.Write([]byte(`{"error": "invalid_grant", "message": "the project is not allowed"}`))
+				return
+			}
+			server.exchanges++
+			server.audience = request.Form.Get("audience")
+			_, _ = fmt.Fprintf(writer, `{"access_token": "short-%d", "token_type": "Bearer", "expires_in": %d}`, server.exchanges, server.expiresIn)
+		case "/api/ci/ping":
+			if request.Header.Get("Authorization") != fmt.Sprintf("Bearer short-%d", server.exchanges) {
+				writer.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func oidcClient(url string) *analyzer.Client {
+	client := noRetryClient(url)
+	client.SetCredentialProvider(analyzer.NewOIDCCredentials(url, "code-secure", analyzer.GitLabIDToken))
+	return client
+}
+
+func TestOIDCCredentialsCachesToken(t *testing.T) {
+	t.Setenv("CODE_SECURE_ID_TOKEN", "jwt")
+	server := &oidcServer{expiresIn: 3600}
+	client := oidcClient(server.start(t).URL)
+	if !client.TestConnection() || !client.TestConnection() {
+		t.Fatal("expected a connection with the exchanged token")
+	}
+	if server.exchanges != 1 || server.audience != "code-secure" {
+		t.Errorf("expected 1 exchange for code-secure, got %d for %q", server.exchanges, server.audience)
+	}
+}
+
+func TestOIDCCredentialsRefresh(t *testing.T) {
+	t.Setenv("CODE_SECURE_ID_TOKEN", "jwt")
+	server := &oidcServer{expiresIn: 10}
+	client := oidcClient(server.start(t).URL)
+	if !client.TestConnection() || !client.TestConnection() {
+		t.Fatal("expected a connection with the exchanged token")
+	}
+	if server.exchanges != 2 {
+		t.Errorf("a token about to expire should be renewed, got %d exchanges", server.exchanges)
+	}
+}
+
+func TestOIDCCredentialsRenewRejectedToken(t *testing.T) {
+	t.Setenv("CODE_SECURE_ID_TOKEN", "jwt")
+	server := &oidcServer{expiresIn: 3600}
+	client := oidcClient(server.start(t).URL)
+	if !client.TestConnection() {
+		t.Fatal("expected a connection with the exchanged token")
+	}
+	// the server revokes short-1
+	server.exchanges++
+	if !client.TestConnection() || server.exchanges != 3 {
+		t.Errorf("a rejected token should be renewed, got %d exchanges", server.exchanges)
+	}
+}
+
+func TestOIDCCredentialsExchangeError(t *testing.T) {
+	t.Setenv("CODE_SECURE_ID_TOKEN", "jwt")
+	server := &oidcServer{status: http.StatusForbidden}
+	url := server.start(t).URL
+	client := retryClient(url)
+	client.SetCredentialProvider(analyzer.NewOIDCCredentials(url, "code-secure", analyzer.GitLabIDToken))
+	err := client.UpdateScan("scan", analyzer.UpdateCIScanRequest{})
+	if !analyzer.IsAPIError(err, http.StatusForbidden) {
+		t.Errorf("expected the error of the token exchange, got %v", err)
+	}
+	if message := err.Error(); !strings.Contains(message, "CODE_SECURE_OIDC_AUDIENCE") || strings.Contains(message, "CODE_SECURE_TOKEN") {
+		t.Errorf("the hint should be about the id token, got %q", message)
+	}
+}
+
+func TestStaticTokenHint(t *testing.T) {
+	server := errorServer(t, http.StatusUnauthorized, nil, "")
+	err := noRetryClient(server.URL).UpdateScan("scan", analyzer.UpdateCIScanRequest{})
+	if err == nil || !strings.HasSuffix(err.Error(), "check CODE_SECURE_TOKEN") {
+		t.Errorf("the hint should be about the token, got %v", err)
+	}
+}
+
+func TestGitHubIDToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer request-token" || request.URL.Query().Get("audience") != "code-secure" {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"value": "github-jwt"}`))
+	}))
+	defer server.Close()
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", server.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	token, err := analyzer.GitHubIDToken(http.DefaultClient, "code-secure")
+	if err != nil || token != "github-jwt" {
+		t.Errorf("expected the id token of GitHub, got %q and %v", token, err)
+	}
+}
+
+func TestRemoteHandlerWithOIDC(t *testing.T) {
+	server := &oidcServer{expiresIn: 3600}
+	url := server.start(t).URL
+	t.Setenv("CODE_SECURE_URL", url)
+	t.Setenv("CODE_SECURE_TOKEN", "")
+	t.Setenv("CODE_SECURE_ID_TOKEN", "jwt")
+	t.Setenv("CODE_SECURE_MAX_RETRIES", "0")
+	if _, err := analyzer.NewHandlerChain("remote"); err != nil {
+		t.Fatal(err)
+	}
+	if server.exchanges != 1 || server.audience != url {
+		t.Errorf("the remote handler should authenticate with the id token, got %d exchanges for %q", server.exchanges, server.audience)
+	}
+	t.Setenv("CODE_SECURE_AUTH", "token")
+	if _, err := analyzer.NewHandlerChain("remote"); err == nil {
+		t.Error("token authentication without CODE_SECURE_TOKEN should fail")
+	}
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+// app -> express -> body-parser -> qs
+// app -> qs-wrapper -> qs
+// debug <-> ms is a cycle below express
+func dependencyGraphScaResult() analyzer.ScaResult {
+	return analyzer.ScaResult{
+		Packages: []analyzer.Package{
+			{PkgId: "express", Name: "express", Version: "4.17.1"},
+			{PkgId: "qs-wrapper", Name: "qs-wrapper", Version: "1.0.0"},
+			{PkgId: "body-parser", Name: "body-parser", Version: "1.19.0"},
+			{PkgId: "qs", Name: "qs", Version: "6.7.0"},
+			{PkgId: "debug", Name: "debug", Version: "2.6.9"},
+			{PkgId: "ms", Name: "ms", Version: "2.0.0"},
+		},
+		PackageDependencies: []analyzer.PackageDependency{
+			{PkgId: "express", Dependencies: []string{"body-parser", "debug"}},
+			{PkgId: "qs-wrapper", Dependencies: []string{"qs"}},
+			{PkgId: "body-parser", Dependencies: []string{"qs"}},
+			{PkgId: "debug", Dependencies: []string{"ms"}},
+			{PkgId: "ms", Dependencies: []string{"debug"}},
+		},
+		Vulnerabilities: []analyzer.Vulnerability{
+			{Identity: "CVE-2022-24999", PkgId: "qs", PkgName: "qs", FixedVersion: "6.7.3"},
+			{Identity: "CVE-2017-16137", PkgId: "debug", PkgName: "debug", FixedVersion: "2.6.9"},
+		},
+	}
+}
+
+func TestDependencyGraph(t *testing.T) {
+	graph := analyzer.NewDependencyGraph(dependencyGraphScaResult())
+	if roots := graph.Roots(); !reflect.DeepEqual(roots, []string{"express", "qs-wrapper"}) {
+		t.Errorf("unexpected roots %v", roots)
+	}
+	expectedPaths := [][]string{{"qs-wrapper", "qs"}, {"express", "body-parser", "qs"}}
+	if paths := graph.Paths("qs", 0); !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("expected paths %v, got %v", expectedPaths, paths)
+	}
+	if paths := graph.Paths("qs", 1); len(paths) != 1 {
+		t.Errorf("paths should be limited, got %v", paths)
+	}
+	if path := graph.ShortestPath("qs"); !reflect.DeepEqual(path, []string{"qs-wrapper", "qs"}) {
+		t.Errorf("unexpected shortest path %v", path)
+	}
+	if path := graph.ShortestPath("express"); path != nil {
+		t.Errorf("a root package has no path, got %v", path)
+	}
+	if direct := graph.DirectDependencies("ms"); !reflect.DeepEqual(direct, []string{"express"}) {
+		t.Errorf("unexpected direct dependencies %v", direct)
+	}
+	if names := graph.PathNames("ms"); !reflect.DeepEqual(names, [][]string{{"express@4.17.1", "debug@2.6.9", "ms@2.0.0"}}) {
+		t.Errorf("unexpected path names %v", names)
+	}
+	if cycles := graph.Cycles(); !reflect.DeepEqual(cycles, [][]string{{"debug", "ms", "debug"}}) {
+		t.Errorf("unexpected cycles %v", cycles)
+	}
+}
+
+func TestAttachDependencyPaths(t *testing.T) {
+	result := dependencyGraphScaResult()
+	analyzer.AttachDependencyPaths(&result)
+	qs := result.Vulnerabilities[0]
+	if !reflect.DeepEqual(qs.DirectDependencies, []string{"qs-wrapper", "express"}) {
+		t.Errorf("unexpected direct dependencies %v", qs.DirectDependencies)
+	}
+	if len(qs.DependencyPaths) != 2 {
+		t.Errorf("expected 2 dependency paths, got %v", qs.DependencyPaths)
+	}
+	debug := result.Vulnerabilities[1]
+	if !reflect.DeepEqual(debug.DependencyPaths, [][]string{{"express", "debug"}}) {
+		t.Errorf("the cycle should not produce paths, got %v", debug.DependencyPaths)
+	}
+}
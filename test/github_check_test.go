@@ -0,0 +1,100 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+type checkRunRecorder struct {
+	mu      sync.Mutex
+	created int
+	updates []map[string]any
+}
+
+func newCheckRunServer(t *testing.T, recorder *checkRunRecorder) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/califio/code-secure-analyzer/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		recorder.mu.Lock()
+		recorder.created++
+		recorder.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 42}`))
+	})
+	mux.HandleFunc("PATCH /repos/califio/code-secure-analyzer/check-runs/42", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		recorder.mu.Lock()
+		recorder.updates = append(recorder.updates, body)
+		recorder.mu.Unlock()
+		_, _ = w.Write([]byte(`{"id": 42}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestGitHubCheckRunAnnotationBatches(t *testing.T) {
+	recorder := &checkRunRecorder{}
+	server := newCheckRunServer(t, recorder)
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_REPOSITORY", "califio/code-secure-analyzer")
+	t.Setenv("GITHUB_SHA", "891832b2fdecb72c444af1a6676eba6eb40435ab")
+	env, err := git.NewGitHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = env.StartCheckRun("code-secure/test", ""); err != nil {
+		t.Fatal(err)
+	}
+	var annotations []git.CheckAnnotation
+	for i := 0; i < 120; i++ {
+		annotations = append(annotations, git.CheckAnnotation{
+			Path:      "src/test.java",
+			StartLine: i + 1,
+			Level:     git.AnnotationFailure,
+			Title:     fmt.Sprintf("finding %d", i),
+			Message:   "description",
+		})
+	}
+	if err = env.AddCheckRunAnnotations(annotations); err != nil {
+		t.Fatal(err)
+	}
+	if err = env.CompleteCheckRun(false, "blocked"); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.created != 1 {
+		t.Fatalf("expected 1 check run, got %d", recorder.created)
+	}
+	if len(recorder.updates) != 4 {
+		t.Fatalf("expected 3 annotation batches and 1 completion, got %d updates", len(recorder.updates))
+	}
+	for index, size := range []int{50, 50, 20} {
+		output := recorder.updates[index]["output"].(map[string]any)
+		if got := len(output["annotations"].([]any)); got != size {
+			t.Errorf("batch %d: expected %d annotations, got %d", index, size, got)
+		}
+	}
+	completion := recorder.updates[3]
+	if completion["status"] != "completed" || completion["conclusion"] != "failure" {
+		t.Errorf("unexpected completion: %v", completion)
+	}
+}
+
+func TestLocalHandlerWithoutSourceManager(t *testing.T) {
+	handler := analyzer.NewLocalHandler()
+	handler.DeferExit()
+	if _, err := handler.OnStart(nil, "semgrep", analyzer.ScannerTypeSast); err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: SastResult})
+	handler.OnError(fmt.Errorf("scanner failed"))
+	handler.OnCompleted()
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+type sarifUpload struct {
+	CommitSha string `json:"commit_sha"`
+	Ref       string `json:"ref"`
+	Sarif     string `json:"sarif"`
+	ToolName  string `json:"tool_name"`
+}
+
+func newCodeScanningServer(t *testing.T, status string, uploads *[]sarifUpload) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/califio/code-secure-analyzer/code-scanning/sarifs", func(w http.ResponseWriter, r *http.Request) {
+		var upload sarifUpload
+		if err := json.NewDecoder(r.Body).Decode(&upload); err != nil {
+			t.Error(err)
+		}
+		*uploads = append(*uploads, upload)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"id": "47177e22-5596-11eb-80a1-c1e54ef945c6", "url": ""}`))
+	})
+	mux.HandleFunc("GET /repos/califio/code-secure-analyzer/code-scanning/sarifs/47177e22-5596-11eb-80a1-c1e54ef945c6", func(w http.ResponseWriter, r *http.Request) {
+		if status == "failed" {
+			_, _ = w.Write([]byte(`{"processing_status": "failed", "errors": ["invalid sarif"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"processing_status": "complete"}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func decodeSarifUpload(t *testing.T, encoded string) analyzer.SarifLog {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log analyzer.SarifLog
+	if err = json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+func setupPullRequestEvent(t *testing.T) {
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	event := `{"pull_request": {"number": 7, "title": "test", "head": {"ref": "feature", "sha": "abc"}, "base": {"ref": "main", "sha": "def"}}}`
+	if err := os.WriteFile(eventPath, []byte(event), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+	t.Setenv("GITHUB_REPOSITORY", "califio/code-secure-analyzer")
+	t.Setenv("GITHUB_SHA", "891832b2fdecb72c444af1a6676eba6eb40435ab")
+	t.Setenv("GITHUB_REF", "refs/pull/7/merge")
+}
+
+func TestGitHubSarifUploadPullRequest(t *testing.T) {
+	var uploads []sarifUpload
+	server := newCodeScanningServer(t, "complete", &uploads)
+	defer server.Close()
+	setupPullRequestEvent(t)
+	t.Setenv("GITHUB_API_URL", server.URL)
+	env, err := git.NewGitHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.IsActive()
+	data, err := analyzer.NewSarifLog("semgrep", SastResult.Findings).JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = env.UploadSarif(data, "semgrep"); err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads) != 1 {
+		t.Fatalf("expected 1 upload, got %d", len(uploads))
+	}
+	upload := uploads[0]
+	if upload.Ref != "refs/pull/7/merge" {
+		t.Errorf("unexpected ref %s", upload.Ref)
+	}
+	if upload.CommitSha != "891832b2fdecb72c444af1a6676eba6eb40435ab" {
+		t.Errorf("unexpected commit sha %s", upload.CommitSha)
+	}
+	log := decodeSarifUpload(t, upload.Sarif)
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != len(SastResult.Findings) {
+		t.Errorf("unexpected sarif content: %+v", log)
+	}
+}
+
+func TestGitHubSarifUploadProcessingError(t *testing.T) {
+	var uploads []sarifUpload
+	server := newCodeScanningServer(t, "failed", &uploads)
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_REPOSITORY", "califio/code-secure-analyzer")
+	t.Setenv("GITHUB_SHA", "891832b2fdecb72c444af1a6676eba6eb40435ab")
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	env, err := git.NewGitHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.UploadSarif([]byte(`{}`), "semgrep")
+	if err == nil || !strings.Contains(err.Error(), "invalid sarif") {
+		t.Fatalf("expected processing error, got %v", err)
+	}
+	if uploads[0].Ref != "refs/heads/main" {
+		t.Errorf("unexpected ref %s", uploads[0].Ref)
+	}
+}
+
+func TestGitHubSarifUploadPartialPullRequestScan(t *testing.T) {
+	var uploads []sarifUpload
+	server := newCodeScanningServer(t, "complete", &uploads)
+	defer server.Close()
+	setupPullRequestEvent(t)
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_SARIF_UPLOAD", "true")
+	env, err := git.NewGitHub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.IsActive()
+	handler := analyzer.NewLocalHandler()
+	handler.DeferExit()
+	if _, err = handler.OnStart(env, "semgrep", analyzer.ScannerTypeSast); err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: SastResult, SourceManager: env, Strategy: analyzer.ChangedFileOnly})
+	if len(uploads) != 1 {
+		t.Fatalf("the partial scan of a pull request should be uploaded, got %d uploads", len(uploads))
+	}
+	log := decodeSarifUpload(t, uploads[0].Sarif)
+	if details := log.Runs[0].AutomationDetails; details == nil || details.ID != "semgrep/pull-request/" {
+		t.Errorf("the partial scan should have its own category, got %+v", details)
+	}
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+func TestGitLabCommitStatusAndApprovalGate(t *testing.T) {
+	var statuses []map[string]any
+	var createdRules []map[string]any
+	deleted := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v4/projects/50471840/statuses/72155d553d00f913d1e9b64def483724493da19e", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		statuses = append(statuses, body)
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	})
+	rules := `[]`
+	mux.HandleFunc("GET /api/v4/projects/50471840/merge_requests/3/approval_rules", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(rules))
+	})
+	mux.HandleFunc("POST /api/v4/projects/50471840/merge_requests/3/approval_rules", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		createdRules = append(createdRules, body)
+		_, _ = w.Write([]byte(`{"id": 9, "name": "Code Secure", "approvals_required": 2}`))
+	})
+	mux.HandleFunc("GET /api/v4/users", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("username") != "alice" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id": 8, "username": "alice"}]`))
+	})
+	mux.HandleFunc("GET /api/v4/groups/{group}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": 21, "full_path": "` + r.PathValue("group") + `"}`))
+	})
+	mux.HandleFunc("DELETE /api/v4/projects/50471840/merge_requests/3/approval_rules/9", func(w http.ResponseWriter, r *http.Request) {
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Setenv("CI_SERVER_URL", server.URL)
+	t.Setenv("CI_PROJECT_ID", "50471840")
+	t.Setenv("CI_COMMIT_SHA", "72155d553d00f913d1e9b64def483724493da19e")
+	t.Setenv("CI_MERGE_REQUEST_IID", "3")
+	t.Setenv("GITLAB_MR_GATE", git.MergeRequestGateApproval)
+	t.Setenv("GITLAB_MR_GATE_APPROVALS", "2")
+	t.Setenv("GITLAB_MR_GATE_APPROVERS", "7, alice")
+	t.Setenv("GITLAB_MR_GATE_GROUPS", "12,security")
+	env, err := git.NewGitLab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.SetCommitStatus(git.CommitStatusOption{
+		Name:      "code-secure/semgrep",
+		State:     git.CommitStatusFailed,
+		TargetURL: "https://codesecure.local/#/scan/1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0]["state"] != "failed" || statuses[0]["name"] != "code-secure/semgrep" {
+		t.Fatalf("unexpected commit status: %v", statuses)
+	}
+	if err = env.SetMergeRequestBlocked(true, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(createdRules) != 1 || createdRules[0]["approvals_required"] != float64(2) {
+		t.Fatalf("unexpected approval rule: %v", createdRules)
+	}
+	if fmt.Sprint(createdRules[0]["user_ids"], createdRules[0]["group_ids"]) != "[7 8] [12 21]" {
+		t.Errorf("the approval rule should require the configured approvers, got %v", createdRules[0])
+	}
+	rules = `[{"id": 9, "name": "Code Secure", "approvals_required": 2}]`
+	if err = env.SetMergeRequestBlocked(false, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !deleted {
+		t.Fatal("approval rule should be removed when findings are no longer blocking")
+	}
+}
+
+func TestGitLabInvalidGateApprovals(t *testing.T) {
+	t.Setenv("GITLAB_MR_GATE", git.MergeRequestGateApproval)
+	t.Setenv("GITLAB_MR_GATE_APPROVALS", "none")
+	if _, err := git.NewGitLab(); err != nil {
+		t.Errorf("an invalid GITLAB_MR_GATE_APPROVALS should fall back to 1 approval, got %v", err)
+	}
+}
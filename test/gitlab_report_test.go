@@ -0,0 +1,81 @@
+package test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// gitLabSchemaURL is where the published security report schemas of the version emitted by the handler live
+const gitLabSchemaURL = "https://gitlab.com/gitlab-org/security-products/security-report-schemas/-/raw/v15.0.7/dist/"
+
+func validateGitLabReport(t *testing.T, schema string, report string) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	response, err := client.Head(gitLabSchemaURL + schema)
+	if err != nil {
+		t.Skipf("the published GitLab schema is not reachable: %v", err)
+	}
+	_ = response.Body.Close()
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewReferenceLoader(gitLabSchemaURL+schema),
+		gojsonschema.NewReferenceLoader("file://"+report),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, desc := range result.Errors() {
+		t.Errorf("%s: %s", report, desc)
+	}
+}
+
+func TestGitLabSastReport(t *testing.T) {
+	outputDir := t.TempDir()
+	t.Setenv("GITLAB_REPORT_DIR", outputDir)
+	handler := analyzer.NewGitLabReportHandler(nil)
+	_, err := handler.OnStart(nil, "semgrep", analyzer.ScannerTypeSast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := SastResult
+	result.Findings = append(result.Findings, analyzer.SastFinding{
+		Name:     "SQL Injection",
+		Severity: analyzer.SeverityHigh,
+		Location: &analyzer.FindingLocation{Path: "src/db.java", StartLine: 10},
+		Metadata: &analyzer.FindingMetadata{
+			Cwes:       []string{"CWE-89"},
+			References: []string{"https://owasp.org/www-community/attacks/SQL_Injection"},
+		},
+	})
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: result})
+	handler.OnCompleted()
+	report := filepath.Join(outputDir, "gl-sast-report.json")
+	if _, err = os.Stat(report); err != nil {
+		t.Fatal(err)
+	}
+	validateGitLabReport(t, "sast-report-format.json", report)
+}
+
+func TestGitLabDependencyScanningReport(t *testing.T) {
+	outputDir := t.TempDir()
+	t.Setenv("GITLAB_REPORT_DIR", outputDir)
+	handler := analyzer.NewGitLabReportHandler(nil)
+	_, err := handler.OnStart(nil, "trivy", analyzer.ScannerTypeDependency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSCA(nil, ScaResult)
+	report := filepath.Join(outputDir, "gl-dependency-scanning-report.json")
+	vulnerabilities := analyzer.NewGitLabDependencyVulnerabilities(ScaResult)
+	if len(vulnerabilities) != 1 {
+		t.Fatalf("expected 1 vulnerability, got %d", len(vulnerabilities))
+	}
+	if vulnerabilities[0].Identifiers[0].Name != "CVE-2022-22965" {
+		t.Errorf("unexpected identifier %+v", vulnerabilities[0].Identifiers[0])
+	}
+	validateGitLabReport(t, "dependency-scanning-report-format.json", report)
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+func TestHTMLHandlerReport(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "report.html")
+	t.Setenv("HTML_OUTPUT", output)
+	handler := analyzer.NewHTMLHandler()
+	_, _ = handler.OnStart(nil, "semgrep", analyzer.ScannerTypeSast)
+	result := SastResult
+	result.Findings = append(result.Findings, analyzer.SastFinding{
+		RuleID:   "java.sqli",
+		Name:     "SQL Injection <script>alert(1)</script>",
+		Severity: analyzer.SeverityHigh,
+		Location: &analyzer.FindingLocation{Path: "src/db.java", StartLine: 10},
+		Metadata: &analyzer.FindingMetadata{Cwes: []string{"CWE-89"}},
+	})
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: result})
+	handler.HandleSCA(nil, ScaResult)
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, expected := range []string{
+		"SastFinding Test 02",
+		"https://cwe.mitre.org/data/definitions/89.html",
+		"Finding Flow (2 steps)",
+		"CVE-2022-22965",
+		"org.springframework:spring-webmvc@4.1.6.RELEASE",
+		"SQL Injection &lt;script&gt;",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("report should contain %q", expected)
+		}
+	}
+	if strings.Contains(html, "<script src") || strings.Contains(html, "<link rel=\"stylesheet\"") {
+		t.Error("report should not load external assets")
+	}
+}
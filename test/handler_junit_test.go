@@ -0,0 +1,106 @@
+package test
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+type junitReport struct {
+	Tests    int `xml:"tests,attr"`
+	Failures int `xml:"failures,attr"`
+	Suites   []struct {
+		Name      string `xml:"name,attr"`
+		TestCases []struct {
+			Name      string `xml:"name,attr"`
+			ClassName string `xml:"classname,attr"`
+			Failure   *struct {
+				Message string `xml:"message,attr"`
+			} `xml:"failure"`
+		} `xml:"testcase"`
+	} `xml:"testsuite"`
+}
+
+func readJUnitReport(t *testing.T, path string) junitReport {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitReport
+	if err = xml.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestJUnitHandlerThresholdAndFixedFindings(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "junit.xml")
+	t.Setenv("JUNIT_OUTPUT", output)
+	t.Setenv("JUNIT_SEVERITY_THRESHOLD", "high")
+	t.Setenv("JUNIT_FIXED_FINDINGS", "true")
+	handler, err := analyzer.NewJUnitHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = handler.OnStart(nil, "semgrep", analyzer.ScannerTypeSast)
+	result := SastResult
+	result.Findings = append(result.Findings, analyzer.SastFinding{
+		RuleID:   "rule-low",
+		Name:     "Weak hash",
+		Severity: analyzer.SeverityLow,
+		Location: &analyzer.FindingLocation{Path: "src/hash.java", StartLine: 3},
+	})
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{
+		Result: result,
+		FindingResult: &analyzer.UploadFindingResponse{
+			FixedFindings: []analyzer.SastFinding{{RuleID: "rule-fixed", Name: "Fixed finding"}},
+		},
+	})
+	report := readJUnitReport(t, output)
+	if len(report.Suites) != 1 || report.Suites[0].Name != "semgrep" {
+		t.Fatalf("expected one suite per scanner: %+v", report)
+	}
+	if report.Tests != 3 || report.Failures != 1 {
+		t.Errorf("expected 3 tests and 1 failure, got %d tests and %d failures", report.Tests, report.Failures)
+	}
+	for _, testCase := range report.Suites[0].TestCases {
+		failed := testCase.Failure != nil
+		if failed != (testCase.ClassName == "rule-test-02") {
+			t.Errorf("unexpected result for %s", testCase.ClassName)
+		}
+	}
+}
+
+func TestJUnitHandlerUnknownSeverity(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "junit.xml")
+	t.Setenv("JUNIT_OUTPUT", output)
+	t.Setenv("JUNIT_SEVERITY_THRESHOLD", "critical")
+	handler, err := analyzer.NewJUnitHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = handler.OnStart(nil, "semgrep", analyzer.ScannerTypeSast)
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: analyzer.SastResult{Findings: []analyzer.SastFinding{
+		{RuleID: "rule-unknown", Name: "Unranked finding"},
+		{RuleID: "rule-high", Name: "Command injection", Severity: analyzer.SeverityHigh},
+	}}})
+	report := readJUnitReport(t, output)
+	if report.Tests != 2 || report.Failures != 1 {
+		t.Fatalf("expected 2 tests and 1 failure, got %d tests and %d failures", report.Tests, report.Failures)
+	}
+	for _, testCase := range report.Suites[0].TestCases {
+		if failed := testCase.Failure != nil; failed != (testCase.ClassName == "rule-unknown") {
+			t.Errorf("a finding without severity should fail the suite, unexpected result for %s", testCase.ClassName)
+		}
+	}
+}
+
+func TestJUnitHandlerInvalidThreshold(t *testing.T) {
+	t.Setenv("JUNIT_SEVERITY_THRESHOLD", "urgent")
+	if _, err := analyzer.NewJUnitHandler(); err == nil {
+		t.Fatal("invalid threshold should be rejected")
+	}
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+type recordHandler struct {
+	scanInfo  *analyzer.CiScanInfo
+	startErr  error
+	required  bool
+	panics    bool
+	block     bool
+	deferExit bool
+	findings  int
+	completed bool
+}
+
+func (h *recordHandler) OnStart(source git.GitEnv, scannerName string, scannerType analyzer.ScannerType) (*analyzer.CiScanInfo, error) {
+	return h.scanInfo, h.startErr
+}
+
+func (h *recordHandler) OnCompleted() {
+	h.completed = true
+}
+
+func (h *recordHandler) OnError(err error) {}
+
+func (h *recordHandler) HandleSastFindings(input analyzer.HandleSastFindingPros) {
+	if h.panics {
+		panic("sink is broken")
+	}
+	h.findings += len(input.Result.Findings)
+}
+
+func (h *recordHandler) HandleSCA(sourceManager git.GitEnv, result analyzer.ScaResult) {}
+
+func (h *recordHandler) IsBlock() bool {
+	return h.block
+}
+
+func (h *recordHandler) DeferExit() {
+	h.deferExit = true
+}
+
+func (h *recordHandler) Required() bool {
+	return h.required
+}
+
+func TestMultiHandlerFanOut(t *testing.T) {
+	remote := &recordHandler{scanInfo: &analyzer.CiScanInfo{ScanId: "scan-1", ScanUrl: "https://codesecure.local/#/scan/1"}, block: true}
+	broken := &recordHandler{startErr: errors.New("unreachable"), panics: true}
+	local := &recordHandler{scanInfo: &analyzer.CiScanInfo{LastCommitSha: "891832b"}}
+	handler := analyzer.NewMultiHandler(remote, broken, local)
+	handler.DeferExit()
+
+	scanInfo, err := handler.OnStart(nil, "semgrep", analyzer.ScannerTypeSast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanInfo.ScanId != "scan-1" || scanInfo.LastCommitSha != "891832b" {
+		t.Errorf("scan info is not merged: %+v", scanInfo)
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: SastResult})
+	if remote.findings != 1 || local.findings != 1 {
+		t.Errorf("findings are not delivered to every sink")
+	}
+	handler.OnCompleted()
+	if !remote.deferExit {
+		t.Error("blocking sink should leave exit to multi handler")
+	}
+	if !local.completed || !handler.IsBlock() {
+		t.Error("block decision is not aggregated")
+	}
+	if broken.completed {
+		t.Error("a sink which failed to start should not receive the next events")
+	}
+}
+
+func TestMultiHandlerRequiredSink(t *testing.T) {
+	required := &recordHandler{startErr: errors.New("unreachable"), required: true}
+	local := &recordHandler{scanInfo: &analyzer.CiScanInfo{}}
+	if _, err := analyzer.NewMultiHandler(local, required).OnStart(nil, "semgrep", analyzer.ScannerTypeSast); err == nil {
+		t.Error("a required sink which failed to start should fail the chain")
+	}
+}
+
+func TestMultiHandlerReportsOnce(t *testing.T) {
+	var states []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		states = append(states, fmt.Sprint(body["state"]))
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+	t.Setenv("CI_SERVER_URL", server.URL)
+	t.Setenv("CI_PROJECT_ID", "50471840")
+	t.Setenv("CI_COMMIT_SHA", "72155d553d00f913d1e9b64def483724493da19e")
+	t.Setenv("GITLAB_COMMIT_STATUS", "true")
+	sourceManager, err := git.NewGitLab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := analyzer.NewMultiHandler(analyzer.NewLocalHandler(), &recordHandler{scanInfo: &analyzer.CiScanInfo{}, block: true}, analyzer.NewLocalHandler())
+	handler.DeferExit()
+	if _, err = handler.OnStart(sourceManager, "semgrep", analyzer.ScannerTypeSast); err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: analyzer.SastResult{}, SourceManager: sourceManager})
+	handler.OnCompleted()
+	if strings.Join(states, ",") != "running,failed" {
+		t.Errorf("the chain should post one commit status with the aggregated decision, got %v", states)
+	}
+}
+
+func TestHandlerChainFromConfig(t *testing.T) {
+	if _, err := analyzer.NewHandlerChain("local,sarif,gitlab-report"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := analyzer.NewHandlerChain("local,unknown"); err == nil {
+		t.Fatal("unknown handler should be rejected")
+	}
+	analyzer.RegisterHandlerFactory("record", func() (analyzer.Handler, error) {
+		return &recordHandler{}, nil
+	})
+	if _, err := analyzer.NewHandlerChain("record"); err != nil {
+		t.Fatal(err)
+	}
+}
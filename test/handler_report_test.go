@@ -0,0 +1,62 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+func TestMarkdownAndCodeClimateReports(t *testing.T) {
+	outputDir := t.TempDir()
+	markdownOutput := filepath.Join(outputDir, "report.md")
+	codeClimateOutput := filepath.Join(outputDir, "codeclimate.json")
+	t.Setenv("MARKDOWN_OUTPUT", markdownOutput)
+	t.Setenv("CODECLIMATE_OUTPUT", codeClimateOutput)
+	handler, err := analyzer.NewHandlerChain("markdown,codeclimate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = handler.OnStart(nil, "semgrep", analyzer.ScannerTypeSast)
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: SastResult})
+
+	markdown, err := os.ReadFile(markdownOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"| Critical | 1 |", "### 1. [Critical] rule-test-02", "**Location:** `input` @ src/test.java:4:4", "<summary>SastFinding Flow</summary>"} {
+		if !strings.Contains(string(markdown), expected) {
+			t.Errorf("markdown report should contain %q", expected)
+		}
+	}
+
+	data, err := os.ReadFile(codeClimateOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []analyzer.CodeClimateIssue
+	if err = json.Unmarshal(data, &issues); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d", len(issues))
+	}
+	issue := issues[0]
+	if issue.Severity != "blocker" || issue.Categories[0] != "Security" || issue.Fingerprint == "" {
+		t.Errorf("unexpected issue: %+v", issue)
+	}
+	again := analyzer.NewCodeClimateSastIssues(SastResult.Findings)
+	if again[0].Fingerprint != issue.Fingerprint {
+		t.Error("fingerprint should be stable")
+	}
+	unlocated := analyzer.NewCodeClimateSastIssues([]analyzer.SastFinding{
+		{RuleID: "hardcoded-secret", Name: "Hardcoded secret", Description: "AWS key"},
+		{RuleID: "hardcoded-secret", Name: "Hardcoded secret", Description: "AWS key"},
+	})
+	if unlocated[0].Fingerprint == unlocated[1].Fingerprint {
+		t.Error("findings without identity nor location should have distinct fingerprints")
+	}
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := map[string]string{
+		"mit":                         "MIT",
+		"Apache License, Version 2.0": "Apache-2.0",
+		"MIT/Apache-2.0":              "MIT OR Apache-2.0",
+		"(mit or apache-2.0)":         "(MIT OR Apache-2.0)",
+		"gpl-2.0-only with classpath-exception-2.0": "GPL-2.0-only WITH Classpath-exception-2.0",
+		"Custom Proprietary":                        "Custom Proprietary",
+	}
+	for license, expected := range tests {
+		if normalized := analyzer.NormalizeLicense(license); normalized != expected {
+			t.Errorf("%s: expected %s, got %s", license, expected, normalized)
+		}
+	}
+}
+
+func TestLicensePolicyCheck(t *testing.T) {
+	policy := &analyzer.LicensePolicy{
+		Allow:  []string{"MIT", "Apache-2.0", "BSD-*", "GPL-2.0-only WITH Classpath-exception-2.0"},
+		Deny:   []string{"GPL-*", "AGPL-*"},
+		Review: []string{"MPL-2.0"},
+	}
+	tests := map[string]analyzer.LicenseAction{
+		"MIT":                               analyzer.LicenseActionAllow,
+		"bsd-3-clause":                      analyzer.LicenseActionAllow,
+		"GPL-3.0-only":                      analyzer.LicenseActionDeny,
+		"MPL-2.0":                           analyzer.LicenseActionReview,
+		"ISC":                               analyzer.LicenseActionReview,
+		"MIT OR GPL-3.0-only":               analyzer.LicenseActionAllow,
+		"MIT AND GPL-3.0-only":              analyzer.LicenseActionDeny,
+		"(MIT OR GPL-3.0-only) AND MPL-2.0": analyzer.LicenseActionReview,
+		"GPL-2.0-only WITH Classpath-exception-2.0": analyzer.LicenseActionAllow,
+		"GPL-2.0-only WITH Autoconf-exception-2.0":  analyzer.LicenseActionDeny,
+		"GPLv3":     analyzer.LicenseActionDeny,
+		"MIT AND (": analyzer.LicenseActionReview,
+	}
+	for license, expected := range tests {
+		if action := policy.Check(license); action != expected {
+			t.Errorf("%s: expected %s, got %s", license, expected, action)
+		}
+	}
+}
+
+func TestLicensePolicyEvaluate(t *testing.T) {
+	t.Setenv("LICENSE_ALLOW", "")
+	t.Setenv("LICENSE_REVIEW", "")
+	t.Setenv("LICENSE_DENY", "copyleft")
+	policy := analyzer.NewLicensePolicy()
+	if policy == nil {
+		t.Fatal("policy should be configured")
+	}
+	result := analyzer.ScaResult{
+		Packages: []analyzer.Package{
+			{PkgId: "app", Name: "app", Version: "1.0.0", License: "MIT"},
+			{PkgId: "lib", Name: "lib", Version: "2.0.0", License: "MIT"},
+			{PkgId: "readline", Name: "readline", Version: "8.2", License: "GPLv3+"},
+			{PkgId: "unknown", Name: "unknown", Version: "0.1.0"},
+		},
+		PackageDependencies: []analyzer.PackageDependency{
+			{PkgId: "app", Dependencies: []string{"lib"}},
+			{PkgId: "lib", Dependencies: []string{"readline", "unknown"}},
+		},
+	}
+	violations := policy.Evaluate(result)
+	if len(violations) != 1 {
+		t.Fatalf("expected 1 violation, got %+v", violations)
+	}
+	violation := violations[0]
+	if violation.Package.PkgId != "readline" || violation.License != "GPL-3.0-or-later" || violation.Action != analyzer.LicenseActionDeny {
+		t.Errorf("unexpected violation %+v", violation)
+	}
+	expectedPaths := [][]string{{"app@1.0.0", "lib@2.0.0", "readline@8.2"}}
+	if !reflect.DeepEqual(violation.Paths, expectedPaths) {
+		t.Errorf("expected paths %v, got %v", expectedPaths, violation.Paths)
+	}
+
+	result.LicenseViolations = violations
+	handler := analyzer.NewLocalHandler()
+	handler.DeferExit()
+	handler.HandleSCA(nil, result)
+	if !handler.IsBlock() {
+		t.Error("a denied license should block the pipeline")
+	}
+	result.LicenseViolations[0].Action = analyzer.LicenseActionReview
+	handler.HandleSCA(nil, result)
+	if handler.IsBlock() {
+		t.Error("a license to review should not block the pipeline")
+	}
+}
+
+func TestLicensePolicyNotConfigured(t *testing.T) {
+	t.Setenv("LICENSE_ALLOW", "")
+	t.Setenv("LICENSE_DENY", "")
+	t.Setenv("LICENSE_REVIEW", "")
+	if policy := analyzer.NewLicensePolicy(); policy != nil {
+		t.Errorf("expected no policy, got %+v", policy)
+	}
+	var policy *analyzer.LicensePolicy
+	if violations := policy.Evaluate(cycloneDXScaResult()); violations != nil {
+		t.Errorf("a nil policy should not report violations, got %+v", violations)
+	}
+}
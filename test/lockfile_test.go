@@ -0,0 +1,211 @@
+package test
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/lockfile"
+)
+
+// lockfileSummary lists the package urls of a result and the dependencies by package url
+func lockfileSummary(result *analyzer.ScaResult) ([]string, map[string][]string) {
+	purls := make(map[string]string)
+	var packages []string
+	for _, pkg := range result.Packages {
+		purls[pkg.PkgId] = analyzer.PackageURL(pkg)
+		packages = append(packages, analyzer.PackageURL(pkg))
+	}
+	sort.Strings(packages)
+	dependencies := make(map[string][]string)
+	for _, dependency := range result.PackageDependencies {
+		for _, child := range dependency.Dependencies {
+			dependencies[purls[dependency.PkgId]] = append(dependencies[purls[dependency.PkgId]], purls[child])
+		}
+		sort.Strings(dependencies[purls[dependency.PkgId]])
+	}
+	return packages, dependencies
+}
+
+func TestLockfileParsers(t *testing.T) {
+	tests := []struct {
+		parser       lockfile.Parser
+		path         string
+		packages     []string
+		dependencies map[string][]string
+	}{
+		{
+			parser: lockfile.ParseGoMod,
+			path:   "go/go.mod",
+			packages: []string{
+				"pkg:golang/github.com/sirupsen/logrus@v1.9.3",
+				"pkg:golang/github.com/stretchr/testify@v1.8.0",
+				"pkg:golang/golang.org/x/sys@v0.0.0-20220715151400-c0bba94af5f8",
+				"pkg:golang/gopkg.in/yaml.v3@v3.0.1",
+			},
+			dependencies: map[string][]string{},
+		},
+		{
+			parser: lockfile.ParsePackageLock,
+			path:   "npm/package-lock.json",
+			packages: []string{
+				"pkg:npm/%40babel/core@7.12.3",
+				"pkg:npm/debug@2.6.9",
+				"pkg:npm/debug@4.3.4",
+				"pkg:npm/express@4.17.1",
+				"pkg:npm/ms@2.0.0",
+				"pkg:npm/ms@2.1.2",
+				"pkg:npm/qs@6.11.0",
+				"pkg:npm/qs@6.7.0",
+			},
+			dependencies: map[string][]string{
+				"pkg:npm/%40babel/core@7.12.3": {"pkg:npm/debug@4.3.4"},
+				"pkg:npm/debug@2.6.9":          {"pkg:npm/ms@2.0.0"},
+				"pkg:npm/debug@4.3.4":          {"pkg:npm/ms@2.1.2"},
+				"pkg:npm/express@4.17.1":       {"pkg:npm/debug@2.6.9", "pkg:npm/qs@6.7.0"},
+			},
+		},
+		{
+			parser:   lockfile.ParsePackageLock,
+			path:     "npmv1/package-lock.json",
+			packages: []string{"pkg:npm/debug@2.6.9", "pkg:npm/express@4.17.1", "pkg:npm/ms@2.0.0", "pkg:npm/qs@6.11.0", "pkg:npm/qs@6.7.0"},
+			dependencies: map[string][]string{
+				"pkg:npm/debug@2.6.9":    {"pkg:npm/ms@2.0.0"},
+				"pkg:npm/express@4.17.1": {"pkg:npm/debug@2.6.9", "pkg:npm/qs@6.7.0"},
+			},
+		},
+		{
+			parser: lockfile.ParseYarnLock,
+			path:   "yarn/yarn.lock",
+			packages: []string{
+				"pkg:npm/%40babel/core@7.12.3",
+				"pkg:npm/debug@2.6.9",
+				"pkg:npm/debug@4.3.4",
+				"pkg:npm/express@4.17.1",
+				"pkg:npm/ms@2.0.0",
+				"pkg:npm/ms@2.1.2",
+				"pkg:npm/qs@6.7.0",
+			},
+			dependencies: map[string][]string{
+				"pkg:npm/%40babel/core@7.12.3": {"pkg:npm/debug@4.3.4"},
+				"pkg:npm/debug@2.6.9":          {"pkg:npm/ms@2.0.0"},
+				"pkg:npm/debug@4.3.4":          {"pkg:npm/ms@2.1.2"},
+				"pkg:npm/express@4.17.1":       {"pkg:npm/debug@2.6.9", "pkg:npm/qs@6.7.0"},
+			},
+		},
+		{
+			parser:       lockfile.ParseYarnLock,
+			path:         "yarnberry/yarn.lock",
+			packages:     []string{"pkg:npm/express@4.17.1", "pkg:npm/qs@6.7.0"},
+			dependencies: map[string][]string{"pkg:npm/express@4.17.1": {"pkg:npm/qs@6.7.0"}},
+		},
+		{
+			parser:   lockfile.ParsePnpmLock,
+			path:     "pnpm/pnpm-lock.yaml",
+			packages: []string{"pkg:npm/express@4.17.1", "pkg:npm/qs@6.7.0", "pkg:npm/react-dom@18.2.0", "pkg:npm/react@18.2.0"},
+			dependencies: map[string][]string{
+				"pkg:npm/express@4.17.1":   {"pkg:npm/qs@6.7.0"},
+				"pkg:npm/react-dom@18.2.0": {"pkg:npm/react@18.2.0"},
+			},
+		},
+		{
+			parser:       lockfile.ParseRequirements,
+			path:         "python/requirements.txt",
+			packages:     []string{"pkg:pypi/django@3.2.12", "pkg:pypi/pyyaml@5.3.1", "pkg:pypi/requests@2.25.1"},
+			dependencies: map[string][]string{},
+		},
+		{
+			parser:       lockfile.ParsePoetryLock,
+			path:         "poetry/poetry.lock",
+			packages:     []string{"pkg:pypi/certifi@2022.12.7", "pkg:pypi/requests@2.25.1", "pkg:pypi/urllib3@1.26.5"},
+			dependencies: map[string][]string{"pkg:pypi/requests@2.25.1": {"pkg:pypi/certifi@2022.12.7", "pkg:pypi/urllib3@1.26.5"}},
+		},
+		{
+			parser: lockfile.ParsePom,
+			path:   "maven/pom.xml",
+			packages: []string{
+				"pkg:maven/com.fasterxml.jackson.core/jackson-databind@2.9.8",
+				"pkg:maven/com.scalesec/vulnado-common@0.0.1-SNAPSHOT",
+				"pkg:maven/org.springframework/spring-webmvc@4.1.6.RELEASE",
+			},
+			dependencies: map[string][]string{},
+		},
+		{
+			parser: lockfile.ParseGemfileLock,
+			path:   "ruby/Gemfile.lock",
+			packages: []string{
+				"pkg:gem/actionpack@6.1.4",
+				"pkg:gem/nokogiri@1.13.10-x86_64-linux",
+				"pkg:gem/racc@1.6.2",
+				"pkg:gem/rack-test@1.1.0",
+				"pkg:gem/rack@2.2.3",
+			},
+			dependencies: map[string][]string{
+				"pkg:gem/actionpack@6.1.4":              {"pkg:gem/rack-test@1.1.0", "pkg:gem/rack@2.2.3"},
+				"pkg:gem/nokogiri@1.13.10-x86_64-linux": {"pkg:gem/racc@1.6.2"},
+				"pkg:gem/rack-test@1.1.0":               {"pkg:gem/rack@2.2.3"},
+			},
+		},
+		{
+			parser:       lockfile.ParseCargoLock,
+			path:         "cargo/Cargo.lock",
+			packages:     []string{"pkg:cargo/libc@0.2.139", "pkg:cargo/smallvec@0.6.9", "pkg:cargo/time@0.1.45", "pkg:cargo/time@0.3.20"},
+			dependencies: map[string][]string{"pkg:cargo/time@0.1.45": {"pkg:cargo/libc@0.2.139"}},
+		},
+		{
+			parser:       lockfile.ParseComposerLock,
+			path:         "composer/composer.lock",
+			packages:     []string{"pkg:composer/guzzlehttp/guzzle@7.4.1", "pkg:composer/guzzlehttp/psr7@2.1.0", "pkg:composer/phpunit/phpunit@9.5.10"},
+			dependencies: map[string][]string{"pkg:composer/guzzlehttp/guzzle@7.4.1": {"pkg:composer/guzzlehttp/psr7@2.1.0"}},
+		},
+	}
+	for _, test := range tests {
+		result, err := test.parser(filepath.Join("testdata", "lockfile", test.path))
+		if err != nil {
+			t.Errorf("%s: %s", test.path, err)
+			continue
+		}
+		packages, dependencies := lockfileSummary(result)
+		if !reflect.DeepEqual(packages, test.packages) {
+			t.Errorf("%s: expected packages %v, got %v", test.path, test.packages, packages)
+		}
+		if !reflect.DeepEqual(dependencies, test.dependencies) {
+			t.Errorf("%s: expected dependencies %v, got %v", test.path, test.dependencies, dependencies)
+		}
+	}
+}
+
+func TestLockfileScanner(t *testing.T) {
+	scanner := lockfile.NewScanner(filepath.Join("testdata", "lockfile"))
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	locations := make(map[string]int)
+	for _, pkg := range result.Packages {
+		locations[*pkg.Location]++
+		if pkg.PkgId != *pkg.Location+":"+analyzer.PackageURL(pkg) {
+			t.Errorf("unexpected PkgId %s", pkg.PkgId)
+		}
+	}
+	if locations["npm/package-lock.json"] != 8 || locations["cargo/Cargo.lock"] != 4 || locations["python/requirements.txt"] != 3 {
+		t.Errorf("unexpected locations %v", locations)
+	}
+	// lockfiles of installed dependencies are skipped
+	if locations["npm/node_modules/qs/package-lock.json"] != 0 {
+		t.Error("node_modules should be skipped")
+	}
+	ids := make(map[string]bool)
+	for _, pkg := range result.Packages {
+		ids[pkg.PkgId] = true
+	}
+	for _, dependency := range result.PackageDependencies {
+		for _, child := range append([]string{dependency.PkgId}, dependency.Dependencies...) {
+			if !ids[child] {
+				t.Errorf("dependency references unknown package %s", child)
+			}
+		}
+	}
+}
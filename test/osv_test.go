@@ -0,0 +1,119 @@
+package test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/osv"
+)
+
+// loadOsvFixture packs each ecosystem directory of testdata/osv into <ecosystem>/all.zip like the OSV export
+func loadOsvFixture(t *testing.T) *osv.Database {
+	output := t.TempDir()
+	ecosystems, err := os.ReadDir(filepath.Join("testdata", "osv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ecosystem := range ecosystems {
+		files, err := filepath.Glob(filepath.Join("testdata", "osv", ecosystem.Name(), "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.MkdirAll(filepath.Join(output, ecosystem.Name()), 0755); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := os.Create(filepath.Join(output, ecosystem.Name(), "all.zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer := zip.NewWriter(archive)
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry, err := writer.Create(filepath.Base(file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = entry.Write(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		_ = archive.Close()
+	}
+	database, err := osv.LoadDatabase(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestOsvMatch(t *testing.T) {
+	database := loadOsvFixture(t)
+	if database.Size() != 7 {
+		t.Errorf("expected 7 advisories, got %d", database.Size())
+	}
+	tests := []struct {
+		pkg          analyzer.Package
+		identities   []string
+		fixedVersion string
+		severity     analyzer.Severity
+	}{
+		{analyzer.Package{PkgId: "1", Name: "qs", Version: "6.7.0", Type: "npm"}, []string{"CVE-2022-24999"}, "6.7.3", analyzer.SeverityHigh},
+		{analyzer.Package{PkgId: "2", Name: "qs", Version: "6.7.3", Type: "npm"}, nil, "", ""},
+		{analyzer.Package{PkgId: "3", Name: "qs", Version: "6.5.0", Type: "npm"}, nil, "", ""},
+		{analyzer.Package{PkgId: "4", Group: "@babel", Name: "core", Version: "7.12.3", Type: "npm"}, []string{"CVE-2022-3517"}, "", analyzer.SeverityLow},
+		{analyzer.Package{PkgId: "5", Name: "@babel/core", Version: "7.12.4", Type: "yarn"}, nil, "", ""},
+		{analyzer.Package{PkgId: "6", Name: "PyYAML", Version: "5.3.1", Type: "pip"}, []string{"CVE-2020-14343"}, "5.4", ""},
+		{analyzer.Package{PkgId: "7", Group: "org.springframework", Name: "spring-webmvc", Version: "4.1.6.RELEASE", Type: "pom"}, []string{"CVE-2022-22965"}, "5.2.20.RELEASE", analyzer.SeverityCritical},
+		{analyzer.Package{PkgId: "8", Group: "org.springframework", Name: "spring-webmvc", Version: "5.3.17", Type: "pom"}, []string{"CVE-2022-22965"}, "5.3.18", analyzer.SeverityCritical},
+		{analyzer.Package{PkgId: "9", Group: "org.springframework", Name: "spring-webmvc", Version: "5.2.20.RELEASE", Type: "pom"}, nil, "", ""},
+		{analyzer.Package{PkgId: "10", Name: "golang.org/x/sys", Version: "v0.0.0-20210615035016-665e8c7367d1", Type: "gomod"}, []string{"CVE-2022-29526"}, "0.0.0-20220412211240-33da011f77ad", analyzer.SeverityUnknown},
+		{analyzer.Package{PkgId: "11", Name: "smallvec", Version: "0.6.9", Type: "cargo"}, []string{"CVE-2019-15551"}, "0.6.10", analyzer.SeverityHigh},
+		{analyzer.Package{PkgId: "12", Name: "smallvec", Version: "0.6.4", Type: "cargo"}, nil, "", ""},
+		{analyzer.Package{PkgId: "13", Name: "smallvec", Version: "3f9a2c6", Type: "cargo"}, []string{"CVE-2019-15551"}, "", analyzer.SeverityHigh},
+	}
+	for _, test := range tests {
+		vulnerabilities := database.Match(test.pkg)
+		if len(vulnerabilities) != len(test.identities) {
+			t.Errorf("%s@%s: expected %v, got %+v", test.pkg.Name, test.pkg.Version, test.identities, vulnerabilities)
+			continue
+		}
+		for index, vulnerability := range vulnerabilities {
+			if vulnerability.Identity != test.identities[index] || vulnerability.FixedVersion != test.fixedVersion || vulnerability.PkgId != test.pkg.PkgId {
+				t.Errorf("%s@%s: unexpected vulnerability %+v", test.pkg.Name, test.pkg.Version, vulnerability)
+			}
+			if test.severity != "" && vulnerability.Severity != test.severity {
+				t.Errorf("%s@%s: expected severity %s, got %s", test.pkg.Name, test.pkg.Version, test.severity, vulnerability.Severity)
+			}
+		}
+	}
+}
+
+func TestOsvScanner(t *testing.T) {
+	database := loadOsvFixture(t)
+	inventory := analyzer.NewCycloneDXScanner("cdxgen", filepath.Join("testdata", "cyclonedx", "cdxgen.cdx.json"))
+	result, err := osv.NewScanner(inventory, database).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// qs@6.7.0 is already reported by the bom, only @babel/core is added
+	if len(result.Vulnerabilities) != 2 {
+		t.Fatalf("expected 2 vulnerabilities, got %+v", result.Vulnerabilities)
+	}
+	added := result.Vulnerabilities[1]
+	if added.Identity != "CVE-2022-3517" || added.PkgId != "pkg:npm/%40babel/core@7.12.3" || len(added.Metadata.Cwes) != 2 {
+		t.Errorf("unexpected vulnerability %+v", added)
+	}
+	spring := database.Match(ScaResult.Packages[0])
+	if len(spring) != 1 || *spring[0].Metadata.CvssScore != "9.8" {
+		t.Errorf("unexpected vulnerability %+v", spring)
+	}
+}
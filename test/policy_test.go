@@ -0,0 +1,203 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+const policyFile = "testdata/policy/policy.yml"
+
+func sastFinding(ruleId string, severity analyzer.Severity, path string, cwes ...string) analyzer.SastFinding {
+	return analyzer.SastFinding{
+		RuleID:   ruleId,
+		Identity: ruleId + ":" + path,
+		Severity: severity,
+		Location: &analyzer.FindingLocation{Path: path, StartLine: 10},
+		Metadata: &analyzer.FindingMetadata{Cwes: cwes},
+	}
+}
+
+func violatedRules(decision analyzer.PolicyDecision) string {
+	var rules []string
+	for _, violation := range decision.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	return strings.Join(rules, ",")
+}
+
+func TestPolicySast(t *testing.T) {
+	policy, err := analyzer.LoadPolicy(policyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changedFiles := []analyzer.ChangedFile{{To: "src/api.py", Status: analyzer.Modify}, {To: "src/db.py", Status: analyzer.Add}}
+	findings := []analyzer.SastFinding{
+		sastFinding("python.flask.xss", analyzer.SeverityHigh, "src/api.py"),
+		sastFinding("python.flask.ssrf", analyzer.SeverityHigh, "src/api.py"),
+		sastFinding("python.flask.debug", analyzer.SeverityHigh, "src/app.py"),
+		sastFinding("python.sqlalchemy.security.sqli", analyzer.SeverityMedium, "src/legacy/orders.py", "CWE-89: Improper Neutralization of Special Elements used in an SQL Command"),
+		sastFinding("python.jwt.none", analyzer.SeverityCritical, "tests/test/fixtures.py"),
+	}
+	tests := []struct {
+		name     string
+		findings []analyzer.SastFinding
+		isNew    func(analyzer.SastFinding) bool
+		expected string
+	}{
+		{"new critical and high in changed files", findings, nil, "new-critical,high-in-changed-files,test-code"},
+		{"critical already reported", findings, func(finding analyzer.SastFinding) bool { return finding.Severity != analyzer.SeverityCritical }, "high-in-changed-files,test-code"},
+		{"threshold not reached", findings[1:4], nil, ""},
+		{"sql injection without suppression", []analyzer.SastFinding{sastFinding("python.sqlalchemy.security.sqli", analyzer.SeverityMedium, "src/db.py", "CWE-89")}, nil, "sql-injection"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := policy.EvaluateSast(test.findings, changedFiles, test.isNew)
+			if rules := violatedRules(decision); rules != test.expected {
+				t.Errorf("expected violations %q, got %q", test.expected, rules)
+			}
+			if decision.Block != (test.expected != "") {
+				t.Errorf("expected block %v, got %v", test.expected != "", decision.Block)
+			}
+		})
+	}
+	decision := policy.EvaluateSast(findings, changedFiles, nil)
+	if decision.Allowed["allow-suppressed-with-ticket"] != 1 {
+		t.Errorf("the suppressed sql injection should be allowed, got %v", decision.Allowed)
+	}
+	explanation := decision.Violations[1].String()
+	if !strings.Contains(explanation, `rule "high-in-changed-files" (block): 2 results match, 1 tolerated`) || !strings.Contains(explanation, "High python.flask.xss at src/api.py:10") {
+		t.Errorf("unexpected explanation %q", explanation)
+	}
+}
+
+func TestPolicySca(t *testing.T) {
+	policy, err := analyzer.LoadPolicy(policyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := analyzer.ScaResult{
+		Packages: []analyzer.Package{{PkgId: "lodash@4.17.20", Name: "lodash", Version: "4.17.20"}},
+		Vulnerabilities: []analyzer.Vulnerability{
+			{Identity: "CVE-2023-0001", Severity: analyzer.SeverityCritical, PkgId: "lodash@4.17.20"},
+			{Identity: "CVE-2020-0002", Severity: analyzer.SeverityHigh, PkgId: "lodash@4.17.20", Metadata: &analyzer.FindingMetadata{Cwes: []string{"CWE-89"}}},
+			{Identity: "CVE-2024-0003", Severity: analyzer.SeverityCritical, PkgId: "lodash@4.17.20", Vex: &analyzer.VexAssessment{Status: analyzer.VexStatusNotAffected}},
+		},
+	}
+	decision := policy.EvaluateSca(result)
+	if rules := violatedRules(decision); rules != "sql-injection" || !decision.Block {
+		t.Errorf("the expired suppression should not allow the sql injection, got %q", rules)
+	}
+	if !strings.Contains(decision.Violations[0].String(), "High CVE-2020-0002 in lodash@4.17.20") {
+		t.Errorf("unexpected explanation %q", decision.Violations[0].String())
+	}
+}
+
+func TestPolicyScaManifestPath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.yml")
+	_ = os.WriteFile(file, []byte("rules:\n  - name: frontend\n    action: block\n    when: {kind: sca, path: [\"web/**\"]}\n"), 0600)
+	policy, err := analyzer.LoadPolicy(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := analyzer.ScaResult{
+		Packages: []analyzer.Package{
+			{PkgId: "lodash@4.17.20", Name: "lodash", Location: analyzer.Ptr("web/package-lock.json")},
+			{PkgId: "requests@2.0.0", Name: "requests", Location: analyzer.Ptr("api/requirements.txt")},
+		},
+		Vulnerabilities: []analyzer.Vulnerability{
+			{Identity: "CVE-2023-0001", Severity: analyzer.SeverityHigh, PkgId: "lodash@4.17.20"},
+			{Identity: "CVE-2023-0002", Severity: analyzer.SeverityHigh, PkgId: "requests@2.0.0"},
+		},
+	}
+	decision := policy.EvaluateSca(result)
+	if len(decision.Violations) != 1 || decision.Violations[0].Count != 1 {
+		t.Errorf("the path should match the manifest of the vulnerable package, got %+v", decision.Violations)
+	}
+}
+
+func TestPolicyCombine(t *testing.T) {
+	block := analyzer.PolicyDecision{Block: true}
+	pass := analyzer.PolicyDecision{}
+	tests := []struct {
+		combine     analyzer.PolicyCombine
+		serverBlock bool
+		decision    analyzer.PolicyDecision
+		expected    bool
+	}{
+		{analyzer.PolicyCombineAny, true, pass, true},
+		{analyzer.PolicyCombineAny, false, block, true},
+		{analyzer.PolicyCombineAny, false, pass, false},
+		{analyzer.PolicyCombineLocal, true, pass, false},
+		{analyzer.PolicyCombineLocal, false, block, true},
+		{analyzer.PolicyCombineServer, false, block, false},
+		{analyzer.PolicyCombineServer, true, pass, true},
+	}
+	for _, test := range tests {
+		policy := &analyzer.Policy{Combine: test.combine}
+		if isBlock := policy.IsBlock(test.serverBlock, test.decision); isBlock != test.expected {
+			t.Errorf("%s with server %v and local %v: expected %v, got %v", test.combine, test.serverBlock, test.decision.Block, test.expected, isBlock)
+		}
+	}
+	var policy *analyzer.Policy
+	if !policy.IsBlock(true, pass) || policy.IsBlock(false, policy.EvaluateSast(SastResult.Findings, nil, nil)) {
+		t.Error("without policy the server should decide")
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	policies := map[string]string{
+		"unknown field":        "rules:\n  - name: a\n    action: block\n    when: {severity: [High], cvss: 9}\n",
+		"invalid action":       "rules:\n  - name: a\n    action: deny\n",
+		"invalid severity":     "rules:\n  - name: a\n    action: block\n    when: {severity: [Urgent]}\n",
+		"invalid cwe":          "rules:\n  - name: a\n    action: block\n    when: {cwe: [sqli]}\n",
+		"invalid combine":      "combine: all\n",
+		"invalid expires":      "suppressions:\n  - id: CVE-1\n    expires: tomorrow\n",
+		"sca in changed files": "rules:\n  - name: a\n    action: block\n    when: {kind: sca, changedFiles: true}\n",
+		"sca not new":          "rules:\n  - name: a\n    action: block\n    when: {kind: sca, new: false}\n",
+	}
+	dir := t.TempDir()
+	for name, content := range policies {
+		file := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".yml")
+		_ = os.WriteFile(file, []byte(content), 0600)
+		if _, err := analyzer.LoadPolicy(file); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLocalHandlerPolicy(t *testing.T) {
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("CODE_SECURE_POLICY", policyFile)
+	sourceManager, err := git.NewGitLab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := analyzer.NewLocalHandler()
+	handler.DeferExit()
+	if _, err = handler.OnStart(sourceManager, "semgrep", analyzer.ScannerTypeSast); err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{
+		Result: analyzer.SastResult{Findings: []analyzer.SastFinding{sastFinding("python.jwt.none", analyzer.SeverityCritical, "src/auth.py")}},
+	})
+	if !handler.IsBlock() {
+		t.Error("a new critical finding should block the pipeline")
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{
+		Result:        analyzer.SastResult{Findings: []analyzer.SastFinding{sastFinding("python.jwt.none", analyzer.SeverityCritical, "src/auth.py")}},
+		FindingResult: &analyzer.UploadFindingResponse{},
+	})
+	if handler.IsBlock() {
+		t.Error("a critical finding of a previous scan should not block the pipeline")
+	}
+
+	t.Setenv("CODE_SECURE_POLICY", "testdata/policy/missing.yml")
+	if _, err = analyzer.NewLocalHandler().OnStart(sourceManager, "semgrep", analyzer.ScannerTypeSast); err == nil {
+		t.Error("a missing policy file should fail the scan")
+	}
+}
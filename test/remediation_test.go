@@ -0,0 +1,82 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+func TestRemediationPlans(t *testing.T) {
+	packageJson := "package-lock.json"
+	subProject := "web/package-lock.json"
+	result := analyzer.ScaResult{
+		Packages: []analyzer.Package{
+			{PkgId: "app", Name: "app", Version: "1.0.0", Type: "npm", Location: &packageJson},
+			{PkgId: "lodash", Name: "lodash", Version: "4.17.15", Type: "npm", Location: &packageJson},
+			{PkgId: "qs", Name: "qs", Version: "5.2.0", Type: "npm", Location: &packageJson},
+			{PkgId: "minimist", Name: "minimist", Version: "1.2.0", Type: "npm", Location: &subProject},
+			{PkgId: "tar", Name: "tar", Version: "1.4.0", Type: "npm", Location: &subProject},
+		},
+		PackageDependencies: []analyzer.PackageDependency{
+			{PkgId: "app", Dependencies: []string{"lodash", "qs"}},
+		},
+		Vulnerabilities: []analyzer.Vulnerability{
+			{Identity: "CVE-2020-8203", PkgId: "lodash", FixedVersion: "4.17.19"},
+			{Identity: "CVE-2021-23337", PkgId: "lodash", FixedVersion: "4.17.21"},
+			{Identity: "CVE-2017-1000048", PkgId: "qs", FixedVersion: "6.0.4"},
+			{Identity: "CVE-2020-7598", PkgId: "minimist", FixedVersion: "0.2.1, 1.2.3"},
+			{Identity: "CVE-2021-44906", PkgId: "minimist", FixedVersion: "1.2.6"},
+			{Identity: "GHSA-minimist", PkgId: "minimist"},
+			{Identity: "CVE-2021-37701", PkgId: "tar", FixedVersion: "1.5.0 || 2.1.0"},
+			{Identity: "CVE-2021-37712", PkgId: "tar", FixedVersion: "2.0.0"},
+		},
+	}
+	plans := analyzer.NewRemediationPlans(result)
+	expected := []analyzer.RemediationPlan{
+		{
+			Location: packageJson,
+			Remediations: []analyzer.Remediation{
+				{PkgId: "lodash", Package: "lodash", CurrentVersion: "4.17.15", TargetVersion: "4.17.21",
+					Fixes: []string{"CVE-2020-8203", "CVE-2021-23337"}, DirectDependencies: []string{"app@1.0.0"}},
+				{PkgId: "qs", Package: "qs", CurrentVersion: "5.2.0", TargetVersion: "6.0.4", Breaking: true,
+					Fixes: []string{"CVE-2017-1000048"}, DirectDependencies: []string{"app@1.0.0"}},
+			},
+		},
+		{
+			Location: subProject,
+			Remediations: []analyzer.Remediation{
+				{PkgId: "minimist", Package: "minimist", CurrentVersion: "1.2.0", TargetVersion: "1.2.6",
+					Fixes: []string{"CVE-2020-7598", "CVE-2021-44906"}, Unfixed: []string{"GHSA-minimist"}},
+				{PkgId: "tar", Package: "tar", CurrentVersion: "1.4.0", TargetVersion: "2.1.0", Breaking: true,
+					Fixes: []string{"CVE-2021-37701", "CVE-2021-37712"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(plans, expected) {
+		t.Errorf("expected %+v, got %+v", expected, plans)
+	}
+}
+
+func TestRemediationPrefersSameMajor(t *testing.T) {
+	result := analyzer.ScaResult{
+		Packages: []analyzer.Package{
+			{PkgId: "jackson", Group: "com.fasterxml.jackson.core", Name: "jackson-databind", Version: "2.9.8", Type: "maven"},
+		},
+		Vulnerabilities: []analyzer.Vulnerability{
+			{Identity: "CVE-2019-12384", PkgId: "jackson", FixedVersion: "2.9.9.1"},
+			{Identity: "CVE-2020-36518", PkgId: "jackson", FixedVersion: "2.12.6.1, 2.13.2.1"},
+		},
+	}
+	plans := analyzer.NewRemediationPlans(result)
+	if len(plans) != 1 || len(plans[0].Remediations) != 1 {
+		t.Fatalf("expected a single remediation, got %+v", plans)
+	}
+	remediation := plans[0].Remediations[0]
+	if remediation.TargetVersion != "2.12.6.1" || remediation.Breaking {
+		t.Errorf("expected a non breaking upgrade to 2.12.6.1, got %+v", remediation)
+	}
+	if remediation.Package != "com.fasterxml.jackson.core:jackson-databind" {
+		t.Errorf("unexpected package name %s", remediation.Package)
+	}
+}
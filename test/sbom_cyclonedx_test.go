@@ -0,0 +1,162 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func cycloneDXScaResult() analyzer.ScaResult {
+	result := ScaResult
+	result.Packages = append([]analyzer.Package{}, ScaResult.Packages...)
+	result.Packages[0].License = "Apache-2.0"
+	result.Packages[1].License = "apache-2.0 OR MIT"
+	result.Packages[2].License = "Custom Proprietary"
+	return result
+}
+
+func validateCycloneDXJSON(t *testing.T, data []byte) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "cyclonedx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := gojsonschema.NewSchemaLoader()
+	// the bom schema references spdx and jsf schemas by their http id, register them to stay offline
+	for _, name := range []string{"spdx.schema.json", "jsf-0.82.schema.json"} {
+		err = loader.AddSchema("http://cyclonedx.org/schema/"+name, gojsonschema.NewReferenceLoader("file://"+filepath.Join(dir, name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema, err := loader.Compile(gojsonschema.NewReferenceLoader("file://" + filepath.Join(dir, "bom-1.5.schema.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, desc := range result.Errors() {
+		t.Error(desc)
+	}
+}
+
+func TestCycloneDXJSON(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "bom.cdx.json")
+	t.Setenv("CYCLONEDX_OUTPUT", output)
+	handler, err := analyzer.NewHandlerChain("cyclonedx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = handler.OnStart(nil, "trivy", analyzer.ScannerTypeDependency)
+	handler.HandleSCA(nil, cycloneDXScaResult())
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateCycloneDXJSON(t, data)
+
+	var bom analyzer.CycloneDXBom
+	if err = json.Unmarshal(data, &bom); err != nil {
+		t.Fatal(err)
+	}
+	if len(bom.Components) != 3 {
+		t.Fatalf("expected 3 components, got %d", len(bom.Components))
+	}
+	if bom.Components[0].Purl != "pkg:maven/org.springframework/spring-webmvc@4.1.6.RELEASE" {
+		t.Errorf("unexpected purl %s", bom.Components[0].Purl)
+	}
+	if bom.Components[0].Licenses[0].License.ID != "Apache-2.0" || bom.Components[1].Licenses[0].Expression == "" || bom.Components[2].Licenses[0].License.Name != "Custom Proprietary" {
+		t.Errorf("unexpected licenses %+v", bom.Components)
+	}
+	if len(bom.Dependencies) != 1 || len(bom.Dependencies[0].DependsOn) != 2 {
+		t.Errorf("unexpected dependencies %+v", bom.Dependencies)
+	}
+	vulnerability := bom.Vulnerabilities[0]
+	if vulnerability.Source.Name != "NVD" || vulnerability.Ratings[0].Method != "CVSSv31" || vulnerability.Affects[0].Ref != bom.Components[0].BomRef {
+		t.Errorf("unexpected vulnerability %+v", vulnerability)
+	}
+}
+
+func TestCycloneDXXML(t *testing.T) {
+	data, err := analyzer.NewCycloneDXBom(cycloneDXScaResult(), "vulnado").XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed")
+	}
+	output := filepath.Join(t.TempDir(), "bom.cdx.xml")
+	if err = os.WriteFile(output, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(xmllint, "--noout", "--schema", filepath.Join("testdata", "cyclonedx", "bom-1.5.xsd"), output).CombinedOutput()
+	if err != nil {
+		t.Errorf("%s: %s", err, out)
+	}
+}
+
+func TestCycloneDXScanner(t *testing.T) {
+	scanner := analyzer.NewCycloneDXScanner("cdxgen", filepath.Join("testdata", "cyclonedx", "cdxgen.cdx.json"))
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Packages) != 3 {
+		t.Fatalf("expected 3 packages, got %+v", result.Packages)
+	}
+	express, qs := result.Packages[0], result.Packages[1]
+	if express.PkgId != "pkg:npm/express@4.17.1" || express.Type != "npm" || express.License != "MIT" || *express.Location != "package-lock.json" {
+		t.Errorf("unexpected package %+v", express)
+	}
+	if result.Packages[2].Name != "@babel/core" {
+		t.Errorf("unexpected package %+v", result.Packages[2])
+	}
+	if len(result.PackageDependencies) != 1 || result.PackageDependencies[0].Dependencies[0] != qs.PkgId {
+		t.Errorf("unexpected dependencies %+v", result.PackageDependencies)
+	}
+	if len(result.Vulnerabilities) != 1 {
+		t.Fatalf("expected 1 vulnerability, got %d", len(result.Vulnerabilities))
+	}
+	vulnerability := result.Vulnerabilities[0]
+	if vulnerability.PkgId != qs.PkgId || vulnerability.Severity != analyzer.SeverityHigh || vulnerability.FixedVersion != "6.7.3" || *vulnerability.Metadata.CvssScore != "7.5" || vulnerability.Metadata.Cwes[0] != "CWE-1321" {
+		t.Errorf("unexpected vulnerability %+v", vulnerability)
+	}
+}
+
+func TestCycloneDXRoundTrip(t *testing.T) {
+	source := cycloneDXScaResult()
+	bom := analyzer.NewCycloneDXBom(source, "vulnado")
+	jsonData, err := bom.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmlData, err := bom.XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"json": jsonData, "xml": xmlData} {
+		parsed, err := analyzer.ParseCycloneDXBom(data)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		result := parsed.ScaResult()
+		if len(result.Packages) != 3 || result.Packages[0].PkgId != source.Packages[0].PkgId || *result.Packages[0].Location != "pom.xml" {
+			t.Errorf("%s: unexpected packages %+v", name, result.Packages)
+		}
+		if len(result.PackageDependencies) != 1 || len(result.PackageDependencies[0].Dependencies) != 2 {
+			t.Errorf("%s: unexpected dependencies %+v", name, result.PackageDependencies)
+		}
+		vulnerability := result.Vulnerabilities[0]
+		expected := source.Vulnerabilities[0]
+		if vulnerability.Identity != expected.Identity || vulnerability.FixedVersion != expected.FixedVersion || vulnerability.Severity != expected.Severity || *vulnerability.Metadata.Cvss != *expected.Metadata.Cvss {
+			t.Errorf("%s: unexpected vulnerability %+v", name, vulnerability)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+func TestSpdxRoundTrip(t *testing.T) {
+	source := cycloneDXScaResult()
+	document := analyzer.NewSpdxDocument(source, "vulnado")
+	jsonData, err := document.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagValue := document.TagValue()
+	if !strings.Contains(string(tagValue), "ExternalRef: PACKAGE-MANAGER purl pkg:maven/org.springframework/spring-webmvc@4.1.6.RELEASE") {
+		t.Errorf("tag-value should contain the purl:\n%s", tagValue)
+	}
+	for name, data := range map[string][]byte{"json": jsonData, "tag-value": tagValue} {
+		parsed, err := analyzer.ParseSpdxDocument(data)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		result := parsed.ScaResult()
+		if len(result.Packages) != len(source.Packages) {
+			t.Fatalf("%s: expected %d packages, got %d", name, len(source.Packages), len(result.Packages))
+		}
+		for index, pkg := range result.Packages {
+			expected := source.Packages[index]
+			expected.Type = "maven"
+			if index == 1 {
+				expected.License = "Apache-2.0 OR MIT"
+			}
+			if !reflect.DeepEqual(pkg, expected) {
+				t.Errorf("%s: expected %+v, got %+v", name, expected, pkg)
+			}
+		}
+		if !reflect.DeepEqual(result.PackageDependencies, source.PackageDependencies) {
+			t.Errorf("%s: unexpected dependencies %+v", name, result.PackageDependencies)
+		}
+	}
+	if document.Packages[1].LicenseDeclared != "Apache-2.0 OR MIT" {
+		t.Errorf("license expression should be normalized, got %s", document.Packages[1].LicenseDeclared)
+	}
+}
+
+func TestSpdxScanner(t *testing.T) {
+	scanner := analyzer.NewSpdxScanner("syft", filepath.Join("testdata", "spdx", "npm.spdx.json"))
+	result, err := scanner.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Packages) != 3 {
+		t.Fatalf("expected 3 packages, got %d", len(result.Packages))
+	}
+	babel := result.Packages[1]
+	if babel.Name != "@babel/core" || babel.Type != "npm" || babel.License != "Babel License" {
+		t.Errorf("unexpected package %+v", babel)
+	}
+	expected := []analyzer.PackageDependency{{PkgId: "npm-express-9a1b", Dependencies: []string{"npm-qs-1f0e"}}}
+	if !reflect.DeepEqual(result.PackageDependencies, expected) {
+		t.Errorf("unexpected dependencies %+v", result.PackageDependencies)
+	}
+}
@@ -0,0 +1,215 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/git"
+)
+
+func spoolScan(t *testing.T, dir string) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	t.Setenv("CODE_SECURE_URL", closed.URL)
+	t.Setenv("CODE_SECURE_TOKEN", "token")
+	t.Setenv("CODE_SECURE_MAX_RETRIES", "0")
+	t.Setenv("CODE_SECURE_OFFLINE_MODE", "spool")
+	t.Setenv("CODE_SECURE_SPOOL_DIR", dir)
+	t.Setenv("GITLAB_CI", "true")
+	sourceManager, err := git.NewGitLab()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := analyzer.NewHandlerChain("remote")
+	if err != nil {
+		t.Fatalf("an unreachable server should fall back to the spool, got %v", err)
+	}
+	if _, err = handler.OnStart(sourceManager, "semgrep", analyzer.ScannerTypeSast); err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: SastResult, SourceManager: sourceManager, Strategy: analyzer.AllFiles})
+	handler.OnCompleted()
+}
+
+func TestSpoolAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	spoolScan(t, dir)
+	spoolScan(t, dir)
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 spooled scans, got %v", paths)
+	}
+	record, err := analyzer.ReadSpoolRecord(paths[0], "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Scan.Scanner != "semgrep" || record.Findings == nil || len(record.Findings.Findings) != len(SastResult.Findings) {
+		t.Errorf("the spool should keep the scan request and the findings, got %+v", record)
+	}
+
+	var mu sync.Mutex
+	var requests []string
+	var uploaded int
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request.Method+" "+request.URL.Path)
+		writer.Header().Set("Content-Type", "application/json")
+		switch request.URL.Path {
+		case "/api/ci/scan":
+			_, _ = writer.Write([]byte(`{"scanId": "scan-1"}`))
+		case "/api/ci/finding":
+			var body analyzer.UploadFindingRequest
+			_ = json.NewDecoder(request.Body).Decode(&body)
+			if body.ScanId == "scan-1" {
+				uploaded += len(body.Findings)
+			}
+			_, _ = writer.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	replayed, err := analyzer.ReplaySpool(noRetryClient(server.URL), dir, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 2 || uploaded != 2*len(SastResult.Findings) {
+		t.Errorf("expected 2 replayed scans, got %d with %d findings", replayed, uploaded)
+	}
+	expected := strings.Repeat("POST /api/ci/scan,POST /api/ci/finding,PUT /api/ci/scan/scan-1,", 2)
+	if strings.Join(requests, ",")+"," != expected {
+		t.Errorf("unexpected requests %v", requests)
+	}
+	if paths, _ = filepath.Glob(filepath.Join(dir, "*")); len(paths) != 0 {
+		t.Errorf("the replayed scans should be removed, got %v", paths)
+	}
+}
+
+func TestReplaySpoolRejectsTamperedFile(t *testing.T) {
+	dir := t.TempDir()
+	spoolScan(t, dir)
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	content, _ := os.ReadFile(paths[0])
+	tampered := strings.Replace(string(content), "semgrep", "semgrap", 1)
+	if err := os.WriteFile(paths[0], []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := errorServer(t, http.StatusOK, nil, `{"scanId": "scan-1"}`)
+	replayed, err := analyzer.ReplaySpool(noRetryClient(server.URL), dir, "token")
+	if err != nil || replayed != 0 {
+		t.Errorf("the tampered file should be skipped, got %d replayed scans and %v", replayed, err)
+	}
+	if _, err = os.Stat(paths[0] + ".rejected"); err != nil {
+		t.Errorf("the tampered file should be rejected: %v", err)
+	}
+}
+
+func TestReplaySpoolStopsAtFirstFailure(t *testing.T) {
+	dir := t.TempDir()
+	spoolScan(t, dir)
+	spoolScan(t, dir)
+	server := errorServer(t, http.StatusInternalServerError, nil, "")
+	if replayed, err := analyzer.ReplaySpool(noRetryClient(server.URL), dir, "token"); err == nil || replayed != 0 {
+		t.Errorf("expected the replay to fail, got %d replayed scans", replayed)
+	}
+	if paths, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(paths) != 2 {
+		t.Errorf("the scans should stay in the spool, got %v", paths)
+	}
+}
+
+// spoolServer is reachable but fails the requests of the scan from failingPath
+func spoolServer(t *testing.T, failingPath string) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var statuses []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writer.Header().Set("Content-Type", "application/json")
+		switch {
+		case request.URL.Path == failingPath:
+			writer.WriteHeader(http.StatusInternalServerError)
+		case request.URL.Path == "/api/ci/scan":
+			_, _ = writer.Write([]byte(`{"scanId": "scan-1"}`))
+		case request.URL.Path == "/api/ci/scan/scan-1":
+			var body analyzer.UpdateCIScanRequest
+			_ = json.NewDecoder(request.Body).Decode(&body)
+			statuses = append(statuses, string(*body.Status))
+			_, _ = writer.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &statuses
+}
+
+func TestRemoteHandlerSpoolsOnScanFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		failingPath string
+		statuses    string
+	}{
+		{"init scan", "/api/ci/scan", ""},
+		{"upload", "/api/ci/finding", string(analyzer.StatusError)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, statuses := spoolServer(t, test.failingPath)
+			dir := t.TempDir()
+			t.Setenv("CODE_SECURE_URL", server.URL)
+			t.Setenv("CODE_SECURE_TOKEN", "token")
+			t.Setenv("CODE_SECURE_MAX_RETRIES", "0")
+			t.Setenv("CODE_SECURE_OFFLINE_MODE", "spool")
+			t.Setenv("CODE_SECURE_SPOOL_DIR", dir)
+			t.Setenv("FINDING_OUTPUT", filepath.Join(t.TempDir(), "finding_results.json"))
+			t.Setenv("GITLAB_CI", "true")
+			sourceManager, err := git.NewGitLab()
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler, err := analyzer.NewHandlerChain("remote")
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler.DeferExit()
+			if _, err = handler.OnStart(sourceManager, "semgrep", analyzer.ScannerTypeSast); err != nil {
+				t.Fatalf("a failing scan should be spooled, got %v", err)
+			}
+			handler.HandleSastFindings(analyzer.HandleSastFindingPros{Result: SastResult, SourceManager: sourceManager, Strategy: analyzer.AllFiles})
+			handler.OnCompleted()
+			paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+			if len(paths) != 1 {
+				t.Fatalf("expected 1 spooled scan, got %v", paths)
+			}
+			record, err := analyzer.ReadSpoolRecord(paths[0], "token")
+			if err != nil || record.Findings == nil || len(record.Findings.Findings) != len(SastResult.Findings) {
+				t.Errorf("the spool should keep the findings, got %+v and %v", record, err)
+			}
+			if strings.Join(*statuses, ",") != test.statuses {
+				t.Errorf("expected the scan statuses %q, got %v", test.statuses, *statuses)
+			}
+		})
+	}
+}
+
+func TestSpoolRequiresKeyWithoutToken(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	t.Setenv("CODE_SECURE_URL", closed.URL)
+	t.Setenv("CODE_SECURE_TOKEN", "")
+	t.Setenv("CODE_SECURE_SPOOL_KEY", "")
+	t.Setenv("CODE_SECURE_ID_TOKEN", "jwt")
+	t.Setenv("CODE_SECURE_OFFLINE_MODE", "spool")
+	if _, err := analyzer.NewHandlerChain("remote"); err == nil || !strings.Contains(err.Error(), "CODE_SECURE_SPOOL_KEY") {
+		t.Errorf("spooling without key should fail before scanning, got %v", err)
+	}
+	t.Setenv("CODE_SECURE_SPOOL_KEY", "key")
+	t.Setenv("CODE_SECURE_MAX_RETRIES", "0")
+	if _, err := analyzer.NewHandlerChain("remote"); err != nil {
+		t.Errorf("spooling with CODE_SECURE_SPOOL_KEY should be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+module example.com/app
+
+go 1.21
+
+require (
+	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	example.com/local v1.0.0
+)
+
+require gopkg.in/yaml.v3 v3.0.1
+
+replace example.com/local => ../local
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+	"github.com/califio/code-secure-analyzer/transport"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certFile    string
+	keyFile     string
+}
+
+// newTestCertificate creates a certificate signed by parent, a self signed CA when parent is nil
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, usage x509.ExtKeyUsage) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.certificate, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	dir := t.TempDir()
+	result := &testCertificate{
+		certificate: certificate,
+		key:         key,
+		certFile:    filepath.Join(dir, name+".pem"),
+		keyFile:     filepath.Join(dir, name+".key"),
+	}
+	_ = os.WriteFile(result.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = os.WriteFile(result.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return result
+}
+
+func mutualTLSServer(t *testing.T) (*httptest.Server, *testCertificate) {
+	ca := newTestCertificate(t, "ca", nil, x509.ExtKeyUsageAny)
+	serverCert := newTestCertificate(t, "server", ca, x509.ExtKeyUsageServerAuth)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	certificate, err := tls.LoadX509KeyPair(serverCert.certFile, serverCert.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MaxVersion:   tls.VersionTLS12,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, ca
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	server, ca := mutualTLSServer(t)
+	clientCert := newTestCertificate(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	tests := []struct {
+		name      string
+		config    transport.Config
+		connected bool
+	}{
+		{"system roots", transport.Config{}, false},
+		{"without client certificate", transport.Config{CABundle: ca.certFile}, false},
+		{"mutual tls", transport.Config{CABundle: ca.certFile, ClientCert: clientCert.certFile, ClientKey: clientCert.keyFile}, true},
+		{"tls 1.3 required", transport.Config{CABundle: ca.certFile, ClientCert: clientCert.certFile, ClientKey: clientCert.keyFile, MinTLSVersion: "1.3"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := noRetryClient(server.URL)
+			if err := client.SetTransportConfig(test.config); err != nil {
+				t.Fatal(err)
+			}
+			if connected := client.TestConnection(); connected != test.connected {
+				t.Errorf("expected connected %v, got %v", test.connected, connected)
+			}
+		})
+	}
+}
+
+func TestClientProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		proxiedHost = request.URL.Host
+	}))
+	defer proxy.Close()
+	client := noRetryClient("http://code-secure.invalid")
+	if err := client.SetTransportConfig(transport.Config{Proxy: proxy.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if !client.TestConnection() || proxiedHost != "code-secure.invalid" {
+		t.Errorf("the request should go through the proxy, got host %q", proxiedHost)
+	}
+	proxiedHost = ""
+	if err := client.SetTransportConfig(transport.Config{Proxy: proxy.URL, NoProxy: ".invalid"}); err != nil {
+		t.Fatal(err)
+	}
+	if client.TestConnection() || proxiedHost != "" {
+		t.Errorf("NO_PROXY hosts should be reached directly, got host %q", proxiedHost)
+	}
+}
+
+func TestTransportConfigErrors(t *testing.T) {
+	configs := []transport.Config{
+		{ClientCert: "client.pem"},
+		{CABundle: "missing.pem"},
+		{Proxy: "ftp://proxy:21"},
+		{MinTLSVersion: "1.4"},
+	}
+	for _, config := range configs {
+		if _, err := config.NewTransport(); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+	if err := analyzer.NewClient("http://localhost", "token").SetTransportConfig(transport.Config{MinTLSVersion: "tls1.3"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRemoteHandlerInvalidTransportConfig(t *testing.T) {
+	t.Setenv("CODE_SECURE_URL", "https://code-secure.invalid")
+	t.Setenv("CODE_SECURE_TOKEN", "token")
+	t.Setenv("CODE_SECURE_CA_BUNDLE", filepath.Join(t.TempDir(), "missing.pem"))
+	t.Setenv("CODE_SECURE_OFFLINE_MODE", "spool")
+	if _, err := analyzer.NewHandlerChain("remote"); err == nil || !strings.Contains(err.Error(), "ca bundle") {
+		t.Errorf("an invalid ca bundle should fail the remote handler even in spool mode, got %v", err)
+	}
+}
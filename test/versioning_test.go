@@ -0,0 +1,45 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/califio/code-secure-analyzer/versioning"
+)
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		ecosystem string
+		a         string
+		b         string
+		expected  int
+	}{
+		{versioning.Npm, "1.2.3", "1.2.10", -1},
+		{versioning.Npm, "1.0.0-alpha", "1.0.0", -1},
+		{versioning.Npm, "1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{versioning.Npm, "1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{versioning.Npm, "1.0.0+build.5", "1.0.0", 0},
+		{versioning.Go, "v0.0.0-20210615035016-665e8c7367d1", "0.0.0-20220412211240-33da011f77ad", -1},
+		{versioning.PyPI, "5.3.1", "5.4", -1},
+		{versioning.PyPI, "1.0.dev1", "1.0a1", -1},
+		{versioning.PyPI, "1.0rc1", "1.0", -1},
+		{versioning.PyPI, "1.0.post1", "1.0", 1},
+		{versioning.PyPI, "1!0.1", "2.0", 1},
+		{versioning.PyPI, "2.0.0", "2", 0},
+		{versioning.Maven, "4.1.6.RELEASE", "5.2.20.RELEASE", -1},
+		{versioning.Maven, "5.3.18", "5.3.18.RELEASE", 0},
+		{versioning.Maven, "1.0-rc1", "1.0", -1},
+		{versioning.Maven, "1.0-SNAPSHOT", "1.0-rc1", 1},
+		{versioning.Maven, "1.0-sp1", "1.0", 1},
+		{versioning.Maven, "1.0.1", "1.0-rc1", 1},
+		{versioning.RubyGems, "1.0.a", "1.0", -1},
+		{versioning.RubyGems, "1.10", "1.9.9", 1},
+	}
+	for _, test := range tests {
+		if actual := versioning.Compare(test.ecosystem, test.a, test.b); actual != test.expected {
+			t.Errorf("%s: compare(%s, %s) = %d, expected %d", test.ecosystem, test.a, test.b, actual, test.expected)
+		}
+		if actual := versioning.Compare(test.ecosystem, test.b, test.a); actual != -test.expected {
+			t.Errorf("%s: compare(%s, %s) = %d, expected %d", test.ecosystem, test.b, test.a, actual, -test.expected)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	analyzer "github.com/califio/code-secure-analyzer"
+)
+
+func vexScaResult() analyzer.ScaResult {
+	return analyzer.ScaResult{
+		Packages: []analyzer.Package{
+			{PkgId: "lodash", Name: "lodash", Version: "4.17.15", Type: "npm"},
+			{PkgId: "qs", Name: "qs", Version: "6.7.0", Type: "npm"},
+			{PkgId: "minimist", Name: "minimist", Version: "1.2.0", Type: "npm"},
+		},
+		Vulnerabilities: []analyzer.Vulnerability{
+			{Identity: "GHSA-35jh-r3h4-6jhm", PkgId: "lodash", FixedVersion: "4.17.21", Severity: analyzer.SeverityHigh},
+			{Identity: "CVE-2020-8203", PkgId: "lodash", FixedVersion: "4.17.19", Severity: analyzer.SeverityHigh},
+			{Identity: "CVE-2022-24999", PkgId: "qs", FixedVersion: "6.7.3", Severity: analyzer.SeverityHigh},
+			{Identity: "GHSA-hrpp-h998-j3pp", PkgId: "qs", FixedVersion: "6.7.3", Severity: analyzer.SeverityHigh},
+			{Identity: "CVE-2021-44906", PkgId: "minimist", FixedVersion: "1.2.6", Severity: analyzer.SeverityCritical},
+			{Identity: "CVE-2020-7598", PkgId: "minimist", FixedVersion: "1.2.3", Severity: analyzer.SeverityMedium},
+		},
+	}
+}
+
+func TestParseOpenVex(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "vex", "app.openvex.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements, err := analyzer.ParseVexDocument(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := analyzer.VexStatement{
+		Vulnerability: "CVE-2021-23337",
+		Aliases:       []string{"GHSA-35jh-r3h4-6jhm"},
+		Products:      []string{"pkg:npm/lodash@4.17.15"},
+		Status:        analyzer.VexStatusNotAffected,
+		Justification: "vulnerable_code_not_in_execute_path",
+		Statement:     "template() is never called with user input",
+	}
+	if len(statements) != 2 || !reflect.DeepEqual(statements[0], expected) {
+		t.Errorf("expected %+v, got %+v", expected, statements)
+	}
+}
+
+func TestApplyVex(t *testing.T) {
+	t.Setenv("VEX_FILES", filepath.Join("testdata", "vex", "*.json"))
+	statements := analyzer.LoadVexStatements()
+	if len(statements) != 5 {
+		t.Fatalf("expected 5 statements, got %d", len(statements))
+	}
+	result := vexScaResult()
+	analyzer.ApplyVex(&result, statements)
+	expected := map[string]analyzer.VexStatus{
+		"GHSA-35jh-r3h4-6jhm": analyzer.VexStatusNotAffected,
+		"CVE-2022-24999":      analyzer.VexStatusFixed,
+		"GHSA-hrpp-h998-j3pp": analyzer.VexStatusUnderInvestigation,
+		"CVE-2021-44906":      analyzer.VexStatusNotAffected,
+		"CVE-2020-7598":       analyzer.VexStatusAffected,
+	}
+	for _, vulnerability := range result.Vulnerabilities {
+		status, ok := expected[vulnerability.Identity]
+		if !ok {
+			if vulnerability.Vex != nil {
+				t.Errorf("%s should not match a statement, got %+v", vulnerability.Identity, vulnerability.Vex)
+			}
+			continue
+		}
+		if vulnerability.Vex == nil || vulnerability.Vex.Status != status {
+			t.Errorf("%s: expected %s, got %+v", vulnerability.Identity, status, vulnerability.Vex)
+		}
+	}
+	minimist := result.Vulnerabilities[4]
+	if minimist.Vex.Justification != "code_not_reachable" || !strings.HasSuffix(minimist.Vex.Source, "app.cdx.vex.json") {
+		t.Errorf("unexpected assessment %+v", minimist.Vex)
+	}
+	if !minimist.IsSuppressed() || result.Vulnerabilities[3].IsSuppressed() {
+		t.Error("only not affected and fixed vulnerabilities are suppressed")
+	}
+
+	// suppressed vulnerabilities need no upgrade
+	plans := analyzer.NewRemediationPlans(result)
+	var upgrades []string
+	for _, remediation := range plans[0].Remediations {
+		upgrades = append(upgrades, remediation.Package+"@"+remediation.TargetVersion)
+	}
+	if !reflect.DeepEqual(upgrades, []string{"lodash@4.17.19", "qs@6.7.3", "minimist@1.2.3"}) {
+		t.Errorf("unexpected upgrades %v", upgrades)
+	}
+}
+
+func TestJUnitHandlerSkipsSuppressedVulnerabilities(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "junit.xml")
+	t.Setenv("JUNIT_OUTPUT", output)
+	result := vexScaResult()
+	result.Vulnerabilities[4].Vex = &analyzer.VexAssessment{Status: analyzer.VexStatusNotAffected, Justification: "code_not_reachable"}
+	handler, err := analyzer.NewJUnitHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleSCA(nil, result)
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	if !strings.Contains(report, `failures="5" skipped="1"`) || !strings.Contains(report, `<skipped message="VEX: not_affected (code_not_reachable)"></skipped>`) {
+		t.Errorf("the suppressed vulnerability should be skipped:\n%s", report)
+	}
+}
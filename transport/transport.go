@@ -0,0 +1,151 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Config is the network configuration shared by the Code Secure, GitLab and GitHub clients
+type Config struct {
+	// CABundle is a pem file of certificate authorities trusted in addition to the system ones
+	CABundle string
+	// ClientCert and ClientKey are the pem files of the client certificate for mutual TLS
+	ClientCert string
+	ClientKey  string
+	// Proxy is a http, https or socks5 proxy url, HTTPS_PROXY/HTTP_PROXY/ALL_PROXY when empty
+	Proxy string
+	// NoProxy is a comma separated list of hosts, domains and CIDR which are reached without proxy, NO_PROXY when empty
+	NoProxy string
+	// MinTLSVersion is 1.0, 1.1, 1.2 or 1.3, 1.2 when empty
+	MinTLSVersion string
+}
+
+// FromEnv reads CODE_SECURE_CA_BUNDLE, CODE_SECURE_CLIENT_CERT, CODE_SECURE_CLIENT_KEY, CODE_SECURE_PROXY,
+// CODE_SECURE_NO_PROXY and CODE_SECURE_TLS_MIN_VERSION
+func FromEnv() Config {
+	return Config{
+		CABundle:      os.Getenv("CODE_SECURE_CA_BUNDLE"),
+		ClientCert:    os.Getenv("CODE_SECURE_CLIENT_CERT"),
+		ClientKey:     os.Getenv("CODE_SECURE_CLIENT_KEY"),
+		Proxy:         os.Getenv("CODE_SECURE_PROXY"),
+		NoProxy:       os.Getenv("CODE_SECURE_NO_PROXY"),
+		MinTLSVersion: os.Getenv("CODE_SECURE_TLS_MIN_VERSION"),
+	}
+}
+
+// NewTransport creates a http transport with the tls and proxy settings of the config
+func (config Config) NewTransport() (*http.Transport, error) {
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := config.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	return transport, nil
+}
+
+// NewHTTPClient creates a http client with the transport of the config
+func (config Config) NewHTTPClient() (*http.Client, error) {
+	transport, err := config.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+func (config Config) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.MinTLSVersion != "" {
+		version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(config.MinTLSVersion), "tls")]
+		if !ok {
+			return nil, errors.New("invalid minimum tls version: " + config.MinTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if config.CABundle != "" {
+		pem, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("ca bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("ca bundle: there is no certificate in " + config.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, errors.New("client certificate and client key are both required for mutual tls")
+		}
+		certificate, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// proxyFunc resolves the proxy of a request. http.ProxyFromEnvironment is not used as it ignores ALL_PROXY and caches
+// the environment of the first call
+func (config Config) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxyConfig := httpproxy.FromEnvironment()
+	if proxyConfig.HTTPProxy == "" {
+		proxyConfig.HTTPProxy = firstEnv("ALL_PROXY", "all_proxy")
+	}
+	if proxyConfig.HTTPSProxy == "" {
+		proxyConfig.HTTPSProxy = firstEnv("ALL_PROXY", "all_proxy")
+	}
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, errors.New("invalid proxy: " + config.Proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, errors.New("unsupported proxy scheme: " + proxyURL.Scheme)
+		}
+		proxyConfig.HTTPProxy = config.Proxy
+		proxyConfig.HTTPSProxy = config.Proxy
+	}
+	if config.NoProxy != "" {
+		proxyConfig.NoProxy = config.NoProxy
+	}
+	proxy := proxyConfig.ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		return proxy(request.URL)
+	}, nil
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -31,4 +31,10 @@ type Vulnerability struct {
 	PkgName      string
 	PublishedAt  *string
 	Metadata     *FindingMetadata
+	// DependencyPaths are chains of package ids from a root package down to the vulnerable package
+	DependencyPaths [][]string
+	// DirectDependencies are the root packages which pull the vulnerable package in
+	DirectDependencies []string
+	// Vex is the VEX statement applied to the vulnerability
+	Vex *VexAssessment
 }
@@ -1,5 +1,7 @@
 package analyzer
 
+import "strings"
+
 type GitAction string
 
 const (
@@ -16,4 +18,34 @@ const (
 	SeverityMedium   Severity = "Medium"
 	SeverityLow      Severity = "Low"
 	SeverityInfo     Severity = "Info"
+	// SeverityUnknown is the severity of results which are not rated
+	SeverityUnknown Severity = "Unknown"
 )
+
+// Rank orders severities from Info (1) to Critical (5). Unknown severities rank 0
+func (severity Severity) Rank() int {
+	switch severity {
+	case SeverityCritical:
+		return 5
+	case SeverityHigh:
+		return 4
+	case SeverityMedium:
+		return 3
+	case SeverityLow:
+		return 2
+	case SeverityInfo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity matches a severity name case-insensitively
+func ParseSeverity(value string) (Severity, bool) {
+	for _, severity := range []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo, SeverityUnknown} {
+		if strings.EqualFold(value, string(severity)) {
+			return severity, true
+		}
+	}
+	return "", false
+}
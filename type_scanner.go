@@ -25,6 +25,10 @@ type ScaResult struct {
 	Packages            []Package
 	PackageDependencies []PackageDependency
 	Vulnerabilities     []Vulnerability
+	// LicenseViolations are set by the license policy configured with LICENSE_ALLOW, LICENSE_DENY and LICENSE_REVIEW
+	LicenseViolations []LicenseViolation
+	// Remediations are the upgrades fixing the vulnerabilities, grouped by manifest
+	Remediations []RemediationPlan
 }
 
 type ScaScanner interface {
@@ -0,0 +1,87 @@
+package versioning
+
+import "strings"
+
+// maven qualifiers in ascending order, the empty qualifier is the release
+var mavenQualifiers = map[string]int{
+	"alpha":     0,
+	"a":         0,
+	"beta":      1,
+	"b":         1,
+	"milestone": 2,
+	"m":         2,
+	"rc":        3,
+	"cr":        3,
+	"snapshot":  4,
+	"":          5,
+	"ga":        5,
+	"final":     5,
+	"release":   5,
+	"sp":        6,
+}
+
+const mavenReleaseRank = 5
+
+// CompareMaven compares versions like maven's ComparableVersion: numbers numerically,
+// known qualifiers by their order (alpha < beta < milestone < rc < snapshot < release < sp) and other qualifiers lexically
+func CompareMaven(a, b string) int {
+	tokensA := mavenTokens(a)
+	tokensB := mavenTokens(b)
+	for index := 0; index < len(tokensA) || index < len(tokensB); index++ {
+		tokenA, tokenB := "", ""
+		if index < len(tokensA) {
+			tokenA = tokensA[index]
+		}
+		if index < len(tokensB) {
+			tokenB = tokensB[index]
+		}
+		if result := compareMavenToken(tokenA, tokenB); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// mavenTokens splits a version and drops trailing tokens that equal a release (0, ga, final, release)
+func mavenTokens(version string) []string {
+	tokens := tokenize(strings.ToLower(strings.TrimSpace(version)))
+	for len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+		if (isNumber(last) && strings.Trim(last, "0") == "") || (!isNumber(last) && mavenQualifiers[last] == mavenReleaseRank && last != "") {
+			tokens = tokens[:len(tokens)-1]
+			continue
+		}
+		break
+	}
+	return tokens
+}
+
+// compareMavenToken compares two tokens, an empty token is a missing one
+func compareMavenToken(a, b string) int {
+	switch {
+	case isNumber(a) && isNumber(b):
+		return compareNumber(a, b)
+	case isNumber(a):
+		if b == "" {
+			return compareNumber(a, "0")
+		}
+		return 1
+	case isNumber(b):
+		if a == "" {
+			return compareNumber("0", b)
+		}
+		return -1
+	}
+	rankA, knownA := mavenQualifiers[a]
+	rankB, knownB := mavenQualifiers[b]
+	switch {
+	case knownA && knownB:
+		return compareInt(rankA, rankB)
+	case knownA:
+		return -1
+	case knownB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,98 @@
+package versioning
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// https://peps.python.org/pep-0440/#appendix-b-parsing-version-strings-with-regular-expressions
+var pep440Regex = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|alpha|b|beta|c|rc|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?` +
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+type pep440Version struct {
+	epoch   int
+	release []int
+	pre     [2]int
+	post    int
+	dev     int
+	local   string
+}
+
+// ComparePep440 compares python versions. Invalid versions fall back to the natural ordering
+func ComparePep440(a, b string) int {
+	versionA, okA := parsePep440(a)
+	versionB, okB := parsePep440(b)
+	if !okA || !okB {
+		return CompareNatural(a, b)
+	}
+	if result := compareInt(versionA.epoch, versionB.epoch); result != 0 {
+		return result
+	}
+	for index := 0; index < len(versionA.release) || index < len(versionB.release); index++ {
+		partA, partB := 0, 0
+		if index < len(versionA.release) {
+			partA = versionA.release[index]
+		}
+		if index < len(versionB.release) {
+			partB = versionB.release[index]
+		}
+		if result := compareInt(partA, partB); result != 0 {
+			return result
+		}
+	}
+	for _, pair := range [][2]int{
+		{versionA.pre[0], versionB.pre[0]},
+		{versionA.pre[1], versionB.pre[1]},
+		{versionA.post, versionB.post},
+		{versionA.dev, versionB.dev},
+	} {
+		if result := compareInt(pair[0], pair[1]); result != 0 {
+			return result
+		}
+	}
+	return strings.Compare(versionA.local, versionB.local)
+}
+
+func parsePep440(version string) (pep440Version, bool) {
+	match := pep440Regex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(version)))
+	if match == nil {
+		return pep440Version{}, false
+	}
+	result := pep440Version{
+		epoch: atoi(match[1]),
+		pre:   [2]int{math.MaxInt, math.MaxInt},
+		post:  math.MinInt,
+		dev:   math.MaxInt,
+		local: match[10],
+	}
+	for _, part := range strings.Split(match[2], ".") {
+		result.release = append(result.release, atoi(part))
+	}
+	if match[3] != "" {
+		phases := map[string]int{"a": 0, "alpha": 0, "b": 1, "beta": 1, "c": 2, "rc": 2, "pre": 2, "preview": 2}
+		result.pre = [2]int{phases[match[3]], atoi(match[4])}
+	}
+	if match[5] != "" {
+		result.post = atoi(match[5])
+	} else if match[6] != "" {
+		result.post = atoi(match[7])
+	}
+	if match[8] != "" {
+		result.dev = atoi(match[9])
+		// a dev release of a final version sorts before its pre-releases
+		if match[3] == "" && match[5] == "" && match[6] == "" {
+			result.pre = [2]int{math.MinInt, 0}
+		}
+	}
+	return result, true
+}
+
+func atoi(value string) int {
+	number, _ := strconv.Atoi(value)
+	return number
+}
@@ -0,0 +1,239 @@
+// Package versioning compares package versions with the ordering rules of each ecosystem
+package versioning
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ecosystems use the OSV ecosystem names (https://ossf.github.io/osv-schema/#affectedpackage-field)
+const (
+	Npm       = "npm"
+	PyPI      = "PyPI"
+	Maven     = "Maven"
+	Go        = "Go"
+	CratesIO  = "crates.io"
+	RubyGems  = "RubyGems"
+	NuGet     = "NuGet"
+	Packagist = "Packagist"
+	Pub       = "Pub"
+	Hex       = "Hex"
+	SwiftURL  = "SwiftURL"
+)
+
+// purlEcosystems maps the package url types to their ecosystem
+var purlEcosystems = map[string]string{
+	"npm":      Npm,
+	"pypi":     PyPI,
+	"maven":    Maven,
+	"golang":   Go,
+	"cargo":    CratesIO,
+	"gem":      RubyGems,
+	"nuget":    NuGet,
+	"composer": Packagist,
+	"pub":      Pub,
+	"hex":      Hex,
+	"swift":    SwiftURL,
+}
+
+// EcosystemOf returns the ecosystem of a package url type, or "" when the type is not supported
+func EcosystemOf(purlType string) string {
+	return purlEcosystems[purlType]
+}
+
+// Major returns the part of a version whose change breaks compatibility: the major version, or major.minor for the
+// 0.x versions of the semver ecosystems
+func Major(ecosystem, version string) string {
+	tokens := tokenize(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(version), "v")))
+	if len(tokens) == 0 || !isNumber(tokens[0]) {
+		return ""
+	}
+	major := strings.TrimLeft(tokens[0], "0")
+	if major != "" {
+		return major
+	}
+	switch ecosystem {
+	case Npm, CratesIO, Pub, Hex:
+		if len(tokens) > 1 && isNumber(tokens[1]) {
+			return "0." + tokens[1]
+		}
+	}
+	return "0"
+}
+
+// Compare returns -1, 0 or 1 when version a is lower, equal or greater than version b in the ecosystem.
+// Unknown ecosystems fall back to a natural ordering of the numeric and text parts
+func Compare(ecosystem, a, b string) int {
+	switch ecosystem {
+	case Npm, Go, CratesIO, NuGet, Packagist, Pub, Hex, SwiftURL:
+		return CompareSemver(a, b)
+	case PyPI:
+		return ComparePep440(a, b)
+	case Maven:
+		return CompareMaven(a, b)
+	default:
+		return CompareNatural(a, b)
+	}
+}
+
+// CompareSemver compares semantic versions. A leading "v" is ignored and missing parts count as zero
+func CompareSemver(a, b string) int {
+	coreA, preA := splitSemver(a)
+	coreB, preB := splitSemver(b)
+	partsA := strings.Split(coreA, ".")
+	partsB := strings.Split(coreB, ".")
+	for index := 0; index < len(partsA) || index < len(partsB); index++ {
+		partA, partB := "0", "0"
+		if index < len(partsA) {
+			partA = partsA[index]
+		}
+		if index < len(partsB) {
+			partB = partsB[index]
+		}
+		if result := compareIdentifier(partA, partB); result != 0 {
+			return result
+		}
+	}
+	// a version without pre-release has a higher precedence
+	switch {
+	case preA == "" && preB == "":
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	identifiersA := strings.Split(preA, ".")
+	identifiersB := strings.Split(preB, ".")
+	for index := 0; index < len(identifiersA) && index < len(identifiersB); index++ {
+		if result := compareIdentifier(identifiersA[index], identifiersB[index]); result != 0 {
+			return result
+		}
+	}
+	return compareInt(len(identifiersA), len(identifiersB))
+}
+
+func splitSemver(version string) (string, string) {
+	version = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(version), "="), "v")
+	version, _, _ = strings.Cut(version, "+")
+	core, pre, _ := strings.Cut(version, "-")
+	return core, pre
+}
+
+// compareIdentifier compares numeric identifiers numerically, which have a lower precedence than text identifiers
+func compareIdentifier(a, b string) int {
+	numberA, errA := strconv.ParseUint(a, 10, 64)
+	numberB, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareUint(numberA, numberB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// CompareNatural compares versions part by part, numbers numerically and text lexically.
+// Text after a common prefix is a pre-release, e.g. 1.0.rc1 < 1.0 < 1.0.1
+func CompareNatural(a, b string) int {
+	tokensA := tokenize(strings.ToLower(a))
+	tokensB := tokenize(strings.ToLower(b))
+	for index := 0; index < len(tokensA) || index < len(tokensB); index++ {
+		switch {
+		case index >= len(tokensA):
+			if isNumber(tokensB[index]) {
+				return -1
+			}
+			return 1
+		case index >= len(tokensB):
+			if isNumber(tokensA[index]) {
+				return 1
+			}
+			return -1
+		}
+		tokenA, tokenB := tokensA[index], tokensB[index]
+		switch {
+		case isNumber(tokenA) && isNumber(tokenB):
+			if result := compareNumber(tokenA, tokenB); result != 0 {
+				return result
+			}
+		case isNumber(tokenA):
+			return 1
+		case isNumber(tokenB):
+			return -1
+		default:
+			if result := strings.Compare(tokenA, tokenB); result != 0 {
+				return result
+			}
+		}
+	}
+	return 0
+}
+
+// tokenize splits a version on separators and on transitions between digits and letters
+func tokenize(version string) []string {
+	var tokens []string
+	current := ""
+	for _, char := range version {
+		isSeparator := !(char >= '0' && char <= '9') && !(char >= 'a' && char <= 'z')
+		if isSeparator {
+			if current != "" {
+				tokens = append(tokens, current)
+			}
+			current = ""
+			continue
+		}
+		if current != "" && isDigit(rune(current[len(current)-1])) != isDigit(char) {
+			tokens = append(tokens, current)
+			current = ""
+		}
+		current += string(char)
+	}
+	if current != "" {
+		tokens = append(tokens, current)
+	}
+	return tokens
+}
+
+func isDigit(char rune) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isNumber(token string) bool {
+	return token != "" && isDigit(rune(token[0]))
+}
+
+// compareNumber compares digit strings of any length
+func compareNumber(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return compareInt(len(a), len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,333 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/califio/code-secure-analyzer/logger"
+)
+
+type VexStatus string
+
+const (
+	VexStatusNotAffected        VexStatus = "not_affected"
+	VexStatusAffected           VexStatus = "affected"
+	VexStatusFixed              VexStatus = "fixed"
+	VexStatusUnderInvestigation VexStatus = "under_investigation"
+)
+
+// defaultVexFiles are the VEX documents looked up in the repository when VEX_FILES is not set
+var defaultVexFiles = []string{".vex/*.json", "vex/*.json", "*.openvex.json", "*.vex.json"}
+
+// cycloneDXVexStates maps the impact analysis states of CycloneDX to the VEX status
+var cycloneDXVexStates = map[string]VexStatus{
+	"not_affected":           VexStatusNotAffected,
+	"false_positive":         VexStatusNotAffected,
+	"resolved":               VexStatusFixed,
+	"resolved_with_pedigree": VexStatusFixed,
+	"exploitable":            VexStatusAffected,
+	"in_triage":              VexStatusUnderInvestigation,
+}
+
+// VexStatement is the status of a vulnerability in a set of products. Products are package urls or package ids,
+// a package url without version applies to every version. A statement without product applies to every package
+type VexStatement struct {
+	Vulnerability string
+	Aliases       []string
+	Products      []string
+	Status        VexStatus
+	Justification string
+	Statement     string
+	Source        string
+}
+
+// VexAssessment is the VEX statement applied to a vulnerability
+type VexAssessment struct {
+	Status        VexStatus `json:"status"`
+	Justification string    `json:"justification,omitempty"`
+	Statement     string    `json:"statement,omitempty"`
+	Source        string    `json:"source,omitempty"`
+}
+
+// IsSuppressed reports whether a VEX statement declares the product not affected by the vulnerability or fixed.
+// Suppressed vulnerabilities are still reported but do not block the pipeline
+func (vulnerability Vulnerability) IsSuppressed() bool {
+	return vulnerability.Vex != nil && (vulnerability.Vex.Status == VexStatusNotAffected || vulnerability.Vex.Status == VexStatusFixed)
+}
+
+func vexDescription(vex VexAssessment) string {
+	description := string(vex.Status)
+	if vex.Justification != "" {
+		description += " (" + vex.Justification + ")"
+	}
+	if vex.Statement != "" {
+		description += ": " + vex.Statement
+	}
+	return description
+}
+
+type openVexDocument struct {
+	Context    string             `json:"@context"`
+	Statements []openVexStatement `json:"statements"`
+}
+
+type openVexStatement struct {
+	Vulnerability   json.RawMessage   `json:"vulnerability"`
+	Products        []json.RawMessage `json:"products"`
+	Subcomponents   []json.RawMessage `json:"subcomponents"`
+	Status          string            `json:"status"`
+	Justification   string            `json:"justification"`
+	ImpactStatement string            `json:"impact_statement"`
+	ActionStatement string            `json:"action_statement"`
+}
+
+type openVexVulnerability struct {
+	Name    string   `json:"name"`
+	ID      string   `json:"@id"`
+	Aliases []string `json:"aliases"`
+}
+
+type openVexComponent struct {
+	ID            string            `json:"@id"`
+	Identifiers   map[string]string `json:"identifiers"`
+	Subcomponents []json.RawMessage `json:"subcomponents"`
+}
+
+// ParseVexDocument reads the statements of an OpenVEX document or of the vulnerabilities of a CycloneDX BOM
+func ParseVexDocument(data []byte) ([]VexStatement, error) {
+	content := strings.TrimSpace(string(data))
+	if strings.HasPrefix(content, "{") {
+		var document openVexDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+		if strings.Contains(document.Context, "openvex") {
+			return document.vexStatements()
+		}
+	}
+	bom, err := ParseCycloneDXBom(data)
+	if err != nil {
+		return nil, err
+	}
+	if bom.BomFormat != "CycloneDX" && !strings.HasPrefix(content, "<") {
+		return nil, errors.New("unknown VEX document format")
+	}
+	return bom.vexStatements(), nil
+}
+
+func (document openVexDocument) vexStatements() ([]VexStatement, error) {
+	var statements []VexStatement
+	for _, item := range document.Statements {
+		statement := VexStatement{
+			Status:        VexStatus(item.Status),
+			Justification: item.Justification,
+			Statement:     item.ImpactStatement,
+		}
+		if statement.Statement == "" {
+			statement.Statement = item.ActionStatement
+		}
+		// v0.0.1 uses a string, v0.2.0 an object
+		var vulnerability openVexVulnerability
+		if err := json.Unmarshal(item.Vulnerability, &statement.Vulnerability); err != nil {
+			if err = json.Unmarshal(item.Vulnerability, &vulnerability); err != nil {
+				return nil, fmt.Errorf("invalid vulnerability: %w", err)
+			}
+			statement.Vulnerability = vulnerability.Name
+			if statement.Vulnerability == "" {
+				statement.Vulnerability = vulnerability.ID
+			}
+			statement.Aliases = vulnerability.Aliases
+		}
+		// the vulnerable packages are the subcomponents of the products, or the products themselves
+		var products []string
+		var subcomponents []string
+		for _, raw := range item.Products {
+			product, children := openVexComponentIDs(raw)
+			products = append(products, product...)
+			subcomponents = append(subcomponents, children...)
+		}
+		for _, raw := range item.Subcomponents {
+			component, _ := openVexComponentIDs(raw)
+			subcomponents = append(subcomponents, component...)
+		}
+		statement.Products = subcomponents
+		if len(subcomponents) == 0 {
+			statement.Products = products
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+func openVexComponentIDs(raw json.RawMessage) ([]string, []string) {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return []string{id}, nil
+	}
+	var component openVexComponent
+	if err := json.Unmarshal(raw, &component); err != nil {
+		return nil, nil
+	}
+	var ids []string
+	if component.ID != "" {
+		ids = append(ids, component.ID)
+	}
+	if purl := component.Identifiers["purl"]; purl != "" {
+		ids = append(ids, purl)
+	}
+	var subcomponents []string
+	for _, child := range component.Subcomponents {
+		childIds, _ := openVexComponentIDs(child)
+		subcomponents = append(subcomponents, childIds...)
+	}
+	return ids, subcomponents
+}
+
+func (bom CycloneDXBom) vexStatements() []VexStatement {
+	// affects reference the bom-ref of the components
+	purls := make(map[string]string)
+	var walk func(components []CycloneDXComponent)
+	walk = func(components []CycloneDXComponent) {
+		for _, component := range components {
+			if component.BomRef != "" && component.Purl != "" {
+				purls[component.BomRef] = component.Purl
+			}
+			walk(component.Components)
+		}
+	}
+	walk(bom.Components)
+	var statements []VexStatement
+	for _, vulnerability := range bom.Vulnerabilities {
+		if vulnerability.Analysis == nil || vulnerability.ID == "" {
+			continue
+		}
+		status, ok := cycloneDXVexStates[vulnerability.Analysis.State]
+		if !ok {
+			continue
+		}
+		statement := VexStatement{
+			Vulnerability: vulnerability.ID,
+			Status:        status,
+			Justification: vulnerability.Analysis.Justification,
+			Statement:     vulnerability.Analysis.Detail,
+		}
+		for _, affect := range vulnerability.Affects {
+			statement.Products = append(statement.Products, affect.Ref)
+			if purl, ok := purls[affect.Ref]; ok {
+				statement.Products = append(statement.Products, purl)
+			}
+		}
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// LoadVexStatements reads the VEX documents listed in VEX_FILES (comma separated paths or glob patterns), or the
+// documents found at the default locations of the repository
+func LoadVexStatements() []VexStatement {
+	patterns := defaultVexFiles
+	if value := os.Getenv("VEX_FILES"); value != "" {
+		patterns = strings.Split(value, ",")
+	}
+	var statements []VexStatement
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(strings.TrimSpace(pattern))
+		if err != nil {
+			logger.Error(fmt.Sprintf("invalid VEX_FILES pattern %s: %s", pattern, err))
+			continue
+		}
+		for _, file := range files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			data, err := os.ReadFile(file)
+			if err != nil {
+				logger.Error(err.Error())
+				continue
+			}
+			documentStatements, err := ParseVexDocument(data)
+			if err != nil {
+				logger.Warn(fmt.Sprintf("skip VEX document %s: %s", file, err))
+				continue
+			}
+			for index := range documentStatements {
+				documentStatements[index].Source = file
+			}
+			logger.Info(fmt.Sprintf("load %d VEX statements from %s", len(documentStatements), file))
+			statements = append(statements, documentStatements...)
+		}
+	}
+	return statements
+}
+
+// ApplyVex sets the VEX assessment of the vulnerabilities matching a statement. The last matching statement wins,
+// so that a later document updates an earlier one
+func ApplyVex(result *ScaResult, statements []VexStatement) {
+	if len(statements) == 0 {
+		return
+	}
+	packages := make(map[string]Package)
+	for _, pkg := range result.Packages {
+		packages[pkg.PkgId] = pkg
+	}
+	for index := range result.Vulnerabilities {
+		vulnerability := &result.Vulnerabilities[index]
+		pkg, ok := packages[vulnerability.PkgId]
+		if !ok {
+			pkg = Package{PkgId: vulnerability.PkgId, Name: vulnerability.PkgName}
+		}
+		for _, statement := range statements {
+			if statement.matchVulnerability(*vulnerability) && statement.matchPackage(pkg) {
+				vulnerability.Vex = &VexAssessment{
+					Status:        statement.Status,
+					Justification: statement.Justification,
+					Statement:     statement.Statement,
+					Source:        statement.Source,
+				}
+			}
+		}
+	}
+}
+
+func (statement VexStatement) matchVulnerability(vulnerability Vulnerability) bool {
+	for _, id := range append([]string{statement.Vulnerability}, statement.Aliases...) {
+		if id != "" && (strings.EqualFold(id, vulnerability.Identity) || strings.EqualFold(id, vulnerability.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (statement VexStatement) matchPackage(pkg Package) bool {
+	if len(statement.Products) == 0 {
+		return true
+	}
+	unversioned := pkg
+	unversioned.Version = ""
+	for _, product := range statement.Products {
+		if product == pkg.PkgId {
+			return true
+		}
+		if !strings.HasPrefix(product, "pkg:") || pkg.Name == "" {
+			continue
+		}
+		target, err := ParsePackageURL(product)
+		if err != nil {
+			continue
+		}
+		if target.Version != "" && target.Version != pkg.Version {
+			continue
+		}
+		target.Version = ""
+		if PackageURL(target) == PackageURL(unversioned) {
+			return true
+		}
+	}
+	return false
+}